@@ -0,0 +1,190 @@
+// Package apikey выпускает ключи API и аутентифицирует по ним запросы.
+//
+// Ключ передается как bearer-токен вида wk_<id>_<secret>. Хранится только SHA-256
+// секрета: секрет случайный (256 бит), поэтому медленный хеш паролей не нужен.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"witness/auth"
+	"witness/models"
+	"witness/storage"
+)
+
+// Prefix отличает ключи API от JWT в заголовке Authorization.
+const Prefix = "wk_"
+
+// touchInterval - не чаще этого записываем время использования ключа,
+// чтобы частые запросы SIEM не превращались в поток записей в хранилище.
+const touchInterval = time.Minute
+
+// Scopes - допустимые области действия ключей.
+var Scopes = []string{models.ScopeEventsRead, models.ScopeAggregatesRead, models.ScopeEventsWrite, models.ScopeAdmin}
+
+var (
+	ErrInvalidKey     = errors.New("invalid api key")
+	ErrAlreadyRevoked = errors.New("api key already revoked")
+)
+
+// Service управляет ключами API и реализует auth.Authenticator.
+type Service struct {
+	store storage.APIKeyStore
+	now   func() time.Time
+
+	mu      sync.Mutex
+	touched map[string]time.Time
+}
+
+var _ auth.Authenticator = (*Service)(nil)
+
+// NewService создает сервис ключей API.
+func NewService(store storage.APIKeyStore) *Service {
+	return &Service{store: store, now: time.Now, touched: make(map[string]time.Time)}
+}
+
+// Create выпускает ключ. Возвращает сохраненный ключ и токен - он показывается
+// только один раз и восстановить его нельзя. ttl 0 означает бессрочный ключ.
+// tenant ограничивает ключ данными арендатора.
+func (s *Service) Create(ctx context.Context, name string, scopes []string, ttl time.Duration, tenant, createdBy string) (*models.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("%w: name is required", ErrInvalidKey)
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one scope is required", ErrInvalidKey)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return nil, "", fmt.Errorf("%w: unknown scope %q, expected one of %s", ErrInvalidKey, scope, strings.Join(Scopes, ", "))
+		}
+	}
+	if ttl < 0 {
+		return nil, "", fmt.Errorf("%w: ttl must not be negative", ErrInvalidKey)
+	}
+
+	id, err := random(8)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := random(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := s.now().UTC()
+	key := &models.APIKey{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Hash:      hash(secret),
+		Scopes:    slices.Clone(scopes),
+		Tenant:    strings.TrimSpace(tenant),
+		CreatedBy: createdBy,
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		expires := now.Add(ttl)
+		key.ExpiresAt = &expires
+	}
+	if err := s.store.SaveAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, Prefix + key.ID + "_" + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// List возвращает все ключи, включая отозванные и просроченные.
+func (s *Service) List(ctx context.Context) ([]*models.APIKey, error) {
+	return s.store.ListAPIKeys(ctx)
+}
+
+// Revoke отзывает ключ. Отозванный ключ остается в списке для истории.
+func (s *Service) Revoke(ctx context.Context, id string) (*models.APIKey, error) {
+	key, err := s.store.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrAlreadyRevoked
+	}
+	now := s.now().UTC()
+	key.RevokedAt = &now
+	if err := s.store.SaveAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Authenticate проверяет токен ключа API. Роли субъекта - области действия ключа.
+func (s *Service) Authenticate(ctx context.Context, token string) (*auth.Principal, error) {
+	rest, ok := strings.CutPrefix(token, Prefix)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	id, encoded, ok := strings.Cut(rest, "_")
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	secret, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	key, err := s.store.GetAPIKey(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown api key", auth.ErrUnauthenticated)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hash(secret)), []byte(key.Hash)) != 1 {
+		return nil, fmt.Errorf("%w: api key secret does not match", auth.ErrUnauthenticated)
+	}
+	now := s.now()
+	if !key.IsActive(now) {
+		return nil, fmt.Errorf("%w: api key %s is revoked or expired", auth.ErrUnauthenticated, key.ID)
+	}
+
+	s.touch(ctx, key.ID, now)
+	return &auth.Principal{Subject: "apikey:" + key.ID, Name: key.Name, Roles: key.Scopes, Tenant: key.Tenant}, nil
+}
+
+// touch записывает время использования ключа не чаще touchInterval.
+// Ошибка записи не мешает запросу: это статистика, а не условие доступа.
+func (s *Service) touch(ctx context.Context, id string, now time.Time) {
+	s.mu.Lock()
+	last, ok := s.touched[id]
+	if ok && now.Sub(last) < touchInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.touched[id] = now
+	s.mu.Unlock()
+
+	if err := s.store.TouchAPIKey(ctx, id, now.UTC()); err != nil {
+		slog.Warn("failed to record api key usage", "id", id, "error", err)
+	}
+}
+
+func random(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	return b, nil
+}
+
+func hash(secret []byte) string {
+	sum := sha256.Sum256(secret)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,322 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+	"witness/models"
+	"witness/opensearch"
+)
+
+const (
+	// Format - формат файлов архива: NDJSON, сжатый gzip.
+	Format = "ndjson.gz"
+	// maxPartRecords ограничивает размер одного файла архива.
+	maxPartRecords = 100000
+	dayLayout      = "2006-01-02"
+)
+
+// Manifest описывает все файлы архива за один день.
+type Manifest struct {
+	Date      string         `json:"date"`
+	Format    string         `json:"format"`
+	Records   int            `json:"records"`
+	Files     []ManifestFile `json:"files"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// ManifestFile - один файл архива с контрольной суммой.
+type ManifestFile struct {
+	Key       string    `json:"key"`
+	Records   int       `json:"records"`
+	Bytes     int64     `json:"bytes"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Archiver выгружает события старше заданного возраста в хранилище архива
+// и удаляет их из OpenSearch. События под активными удержаниями не трогаются:
+// они остаются в горячем индексе и будут выгружены после снятия удержания.
+type Archiver struct {
+	osClient *opensearch.Client
+	target   Target
+	maxAge   time.Duration
+	interval time.Duration
+}
+
+// NewArchiver создает архиватор. maxAge <= 0 отключает периодическую архивацию.
+func NewArchiver(osClient *opensearch.Client, target Target, maxAge, interval time.Duration) *Archiver {
+	return &Archiver{
+		osClient: osClient,
+		target:   target,
+		maxAge:   maxAge,
+		interval: interval,
+	}
+}
+
+// Run выполняет архивацию по таймеру до отмены контекста.
+func (a *Archiver) Run(ctx context.Context) {
+	if a.maxAge <= 0 {
+		slog.Info("archiving disabled")
+		return
+	}
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := a.RunOnce(ctx); err != nil {
+			slog.Error("archive run failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce архивирует все полные дни старше maxAge и возвращает число выгруженных событий.
+func (a *Archiver) RunOnce(ctx context.Context) (int, error) {
+	// Архивируем только целые дни, чтобы файлы за день не дописывались постоянно.
+	cutoff := startOfDay(time.Now().Add(-a.maxAge))
+	days, err := a.osClient.EventDays(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, day := range days {
+		n, err := a.ArchiveDay(ctx, day)
+		if err != nil {
+			return total, fmt.Errorf("failed to archive %s: %w", day.Format(dayLayout), err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// ArchiveDay выгружает события за день в хранилище, обновляет манифест
+// и удаляет выгруженные события из OpenSearch.
+func (a *Archiver) ArchiveDay(ctx context.Context, day time.Time) (int, error) {
+	day = startOfDay(day)
+	manifest, err := a.loadManifest(ctx, day)
+	if err != nil {
+		return 0, err
+	}
+
+	// Список удержаний читаем до выгрузки, чтобы не удалить событие под удержанием.
+	holds, err := a.osClient.ListLegalHolds(ctx, true)
+	if err != nil {
+		return 0, err
+	}
+
+	runID := time.Now().UTC().Format("20060102T150405")
+	var (
+		part     *partWriter
+		archived []string
+		partNo   int
+	)
+	flush := func() error {
+		if part == nil || part.records == 0 {
+			return nil
+		}
+		key := fmt.Sprintf("%s/part-%s-%04d.%s", dayPrefix(day), runID, partNo, Format)
+		file, err := part.upload(ctx, a.target, key)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, file)
+		manifest.Records += file.Records
+		partNo++
+		part = nil
+		return nil
+	}
+
+	err = a.osClient.ScanEvents(ctx, day, day.AddDate(0, 0, 1), func(event *models.AuditEvent) error {
+		if models.IsHeld(event, holds) {
+			return nil
+		}
+		if part == nil {
+			part = newPartWriter()
+		}
+		if err := part.write(event); err != nil {
+			return err
+		}
+		archived = append(archived, event.EventID)
+		if part.records >= maxPartRecords {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	if len(archived) == 0 {
+		return 0, nil
+	}
+
+	// Манифест пишем до удаления: если удаление не удастся, события останутся
+	// в OpenSearch и будут повторно выгружены, но ничего не потеряется.
+	manifest.UpdatedAt = time.Now().UTC()
+	if err := a.saveManifest(ctx, day, manifest); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for start := 0; start < len(archived); start += 1000 {
+		end := min(start+1000, len(archived))
+		n, err := a.osClient.DeleteEventsByID(ctx, archived[start:end], holds)
+		if err != nil {
+			return 0, err
+		}
+		deleted += n
+	}
+
+	slog.Info("archived events", "day", day.Format(dayLayout), "archived", len(archived), "deleted", deleted)
+	return len(archived), nil
+}
+
+// Manifests возвращает манифесты за дни из интервала [from, to].
+func (a *Archiver) Manifests(ctx context.Context, from, to time.Time) ([]*Manifest, error) {
+	var manifests []*Manifest
+	for day := startOfDay(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		m, err := a.loadManifest(ctx, day)
+		if err != nil {
+			return nil, err
+		}
+		if len(m.Files) > 0 {
+			manifests = append(manifests, m)
+		}
+	}
+	return manifests, nil
+}
+
+func (a *Archiver) loadManifest(ctx context.Context, day time.Time) (*Manifest, error) {
+	r, err := a.target.Get(ctx, manifestKey(day))
+	if errors.Is(err, ErrNotExist) {
+		return &Manifest{Date: day.Format(dayLayout), Format: Format}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest for %s: %w", day.Format(dayLayout), err)
+	}
+	return &m, nil
+}
+
+func (a *Archiver) saveManifest(ctx context.Context, day time.Time, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	return a.target.Put(ctx, manifestKey(day), bytes.NewReader(data), int64(len(data)))
+}
+
+// partWriter накапливает сжатый файл архива в памяти и считает его контрольную сумму.
+type partWriter struct {
+	buf     bytes.Buffer
+	gz      *gzip.Writer
+	enc     *json.Encoder
+	records int
+}
+
+func newPartWriter() *partWriter {
+	p := &partWriter{}
+	p.gz = gzip.NewWriter(&p.buf)
+	p.enc = json.NewEncoder(p.gz)
+	return p
+}
+
+func (p *partWriter) write(event *models.AuditEvent) error {
+	if err := p.enc.Encode(event); err != nil {
+		return fmt.Errorf("failed to encode event %s: %w", event.EventID, err)
+	}
+	p.records++
+	return nil
+}
+
+func (p *partWriter) upload(ctx context.Context, target Target, key string) (ManifestFile, error) {
+	if err := p.gz.Close(); err != nil {
+		return ManifestFile{}, fmt.Errorf("failed to compress %s: %w", key, err)
+	}
+	sum := sha256.Sum256(p.buf.Bytes())
+	size := int64(p.buf.Len())
+	if err := target.Put(ctx, key, &p.buf, size); err != nil {
+		return ManifestFile{}, err
+	}
+	return ManifestFile{
+		Key:       key,
+		Records:   p.records,
+		Bytes:     size,
+		SHA256:    hex.EncodeToString(sum[:]),
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// readFile скачивает файл архива, проверяет контрольную сумму и передает события в fn.
+func readFile(ctx context.Context, target Target, file ManifestFile, fn func(*models.AuditEvent) error) error {
+	r, err := target.Get(ctx, file.Key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file.Key, err)
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != file.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s", file.Key)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decompress %s: %w", file.Key, err)
+	}
+	defer gz.Close()
+
+	dec := json.NewDecoder(gz)
+	for {
+		var event models.AuditEvent
+		if err := dec.Decode(&event); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to decode event in %s: %w", file.Key, err)
+		}
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// dayPrefix - партиция дня в стиле Hive, удобная для внешних инструментов.
+func dayPrefix(day time.Time) string {
+	return "events/dt=" + day.Format(dayLayout)
+}
+
+func manifestKey(day time.Time) string {
+	return dayPrefix(day) + "/manifest.json"
+}
@@ -0,0 +1,77 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"witness/models"
+)
+
+// restoreBatchSize - размер пачки при массовой вставке восстановленных событий.
+const restoreBatchSize = 1000
+
+// RestoreIndexPrefix - префикс временных индексов с восстановленными событиями.
+const RestoreIndexPrefix = "audit-events-restore-"
+
+// Restore загружает события за интервал [from, to] из архива во временный индекс.
+// Если index пуст, имя индекса генерируется. Возвращает имя индекса и число событий.
+func (a *Archiver) Restore(ctx context.Context, from, to time.Time, index string) (string, int, error) {
+	if to.Before(from) {
+		return "", 0, fmt.Errorf("'to' is before 'from'")
+	}
+	if index == "" {
+		index = RestoreIndexPrefix + strings.ToLower(time.Now().UTC().Format("20060102t150405"))
+	}
+
+	manifests, err := a.Manifests(ctx, from, to)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(manifests) == 0 {
+		return "", 0, fmt.Errorf("no archived events between %s and %s", from.Format(dayLayout), to.Format(dayLayout))
+	}
+
+	if err := a.osClient.CreateEventsIndex(ctx, index); err != nil {
+		return "", 0, err
+	}
+
+	restored := 0
+	batch := make([]*models.AuditEvent, 0, restoreBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := a.osClient.IndexEventsBulkTo(ctx, index, batch); err != nil {
+			return err
+		}
+		restored += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for _, m := range manifests {
+		for _, file := range m.Files {
+			err := readFile(ctx, a.target, file, func(event *models.AuditEvent) error {
+				if event.Timestamp.Before(from) || event.Timestamp.After(to) {
+					return nil
+				}
+				batch = append(batch, event)
+				if len(batch) >= restoreBatchSize {
+					return flush()
+				}
+				return nil
+			})
+			if err != nil {
+				return "", restored, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return "", restored, err
+	}
+
+	slog.Info("restored archived events", "index", index, "count", restored)
+	return index, restored, nil
+}
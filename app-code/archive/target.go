@@ -0,0 +1,217 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// ErrNotExist возвращается, когда объекта с указанным ключом нет в хранилище.
+var ErrNotExist = errors.New("object does not exist")
+
+// Target - хранилище архивных файлов. Ключи разделяются символом '/'.
+type Target interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Delete удаляет объект; отсутствие объекта не считается ошибкой.
+	Delete(ctx context.Context, key string) error
+}
+
+// S3Config - параметры подключения к S3-совместимому хранилищу (AWS S3, MinIO).
+type S3Config struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Region    string
+	UseSSL    bool
+}
+
+// NewTarget создает хранилище по URL: file:///path или s3://bucket/prefix.
+func NewTarget(ctx context.Context, rawURL string, s3cfg S3Config) (Target, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive target %q: %w", rawURL, err)
+	}
+
+	switch u.Scheme {
+	case "file", "":
+		dir := u.Path
+		if u.Scheme == "" {
+			dir = rawURL
+		}
+		return NewFileTarget(dir)
+	case "s3":
+		return NewS3Target(ctx, u.Host, strings.Trim(u.Path, "/"), s3cfg)
+	default:
+		return nil, fmt.Errorf("unsupported archive target scheme %q", u.Scheme)
+	}
+}
+
+// FileTarget хранит архив в локальном каталоге.
+type FileTarget struct {
+	dir string
+}
+
+// NewFileTarget создает файловое хранилище, при необходимости создавая каталог.
+func NewFileTarget(dir string) (*FileTarget, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	return &FileTarget{dir: dir}, nil
+}
+
+// Put записывает объект атомарно: сначала во временный файл, затем переименовывает.
+func (t *FileTarget) Put(_ context.Context, key string, r io.Reader, _ int64) error {
+	name := filepath.Join(t.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (t *FileTarget) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(t.dir, filepath.FromSlash(key)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotExist
+	}
+	return f, err
+}
+
+func (t *FileTarget) List(_ context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.WalkDir(t.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+		rel, err := filepath.Rel(t.dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive directory: %w", err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (t *FileTarget) Delete(_ context.Context, key string) error {
+	err := os.Remove(filepath.Join(t.dir, filepath.FromSlash(key)))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// S3Target хранит архив в бакете S3-совместимого хранилища.
+type S3Target struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Target подключается к хранилищу и создает бакет, если его нет.
+func NewS3Target(ctx context.Context, bucket, prefix string, cfg S3Config) (*S3Target, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %w", err)
+	}
+
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket %q: %w", bucket, err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+			return nil, fmt.Errorf("failed to create bucket %q: %w", bucket, err)
+		}
+	}
+	return &S3Target{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+func (t *S3Target) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	_, err := t.client.PutObject(ctx, t.bucket, path.Join(t.prefix, key), r, size, minio.PutObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return nil
+}
+
+func (t *S3Target) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := t.client.GetObject(ctx, t.bucket, path.Join(t.prefix, key), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	// GetObject ленив: отсутствие объекта обнаруживается только при первом обращении.
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotExist
+		}
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	return obj, nil
+}
+
+func (t *S3Target) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	full := path.Join(t.prefix, prefix)
+	if strings.HasSuffix(prefix, "/") {
+		full += "/"
+	}
+	for obj := range t.client.ListObjects(ctx, t.bucket, minio.ListObjectsOptions{Prefix: full, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", obj.Err)
+		}
+		key := strings.TrimPrefix(obj.Key, t.prefix)
+		keys = append(keys, strings.TrimPrefix(key, "/"))
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (t *S3Target) Delete(ctx context.Context, key string) error {
+	// RemoveObject не возвращает ошибку для отсутствующего объекта.
+	if err := t.client.RemoveObject(ctx, t.bucket, path.Join(t.prefix, key), minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
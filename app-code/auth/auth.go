@@ -0,0 +1,68 @@
+// Package auth определяет, кто выполняет запрос к API, и какие роли у него есть.
+package auth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Роли, которые проверяет сам Witness.
+const (
+	// RoleAdmin дает все остальные роли.
+	RoleAdmin = "admin"
+	// RolePIIReader разрешает видеть персональные данные в ответах API без маскирования.
+	RolePIIReader = "pii_reader"
+)
+
+// Principal - субъект запроса.
+type Principal struct {
+	Subject string
+	// Name - отображаемое имя субъекта (например, имя ключа API). Оно не обязано быть
+	// уникальным, поэтому в проверках доступа участвует только Subject.
+	Name  string
+	Roles []string
+	// Tenant - арендатор, данными которого ограничены запросы субъекта (см. пакет tenant).
+	Tenant string
+}
+
+// HasRole сообщает, есть ли у субъекта роль (напрямую или через RoleAdmin).
+func (p *Principal) HasRole(role string) bool {
+	if p == nil {
+		return false
+	}
+	return slices.Contains(p.Roles, role) || slices.Contains(p.Roles, RoleAdmin)
+}
+
+// IsGlobalAdmin сообщает, что субъект - администратор, не ограниченный арендатором.
+// Операции над всем хранилищем (удержания, проверка целостности, забвение) доступны только ему.
+func (p *Principal) IsGlobalAdmin() bool {
+	return p.HasRole(RoleAdmin) && p.Tenant == ""
+}
+
+type principalKey struct{}
+
+// WithPrincipal возвращает контекст с субъектом запроса.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает субъекта запроса или nil, если он не определен.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Anonymous - middleware, которое назначает каждому запросу анонимного субъекта
+// с ролями roles и арендатором tenant.
+func Anonymous(roles []string, tenant string) echo.MiddlewareFunc {
+	principal := &Principal{Subject: "anonymous", Roles: roles, Tenant: tenant}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			c.SetRequest(req.WithContext(WithPrincipal(req.Context(), principal)))
+			return next(c)
+		}
+	}
+}
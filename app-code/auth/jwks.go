@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksTTL - как долго ключи, загруженные по URL, считаются актуальными.
+	jwksTTL = 10 * time.Minute
+	// jwksMinRefresh - не чаще этого перезагружаем ключи из-за неизвестного kid,
+	// чтобы токены с поддельным kid не превращались в поток запросов к провайдеру.
+	jwksMinRefresh = time.Minute
+)
+
+// jwk - открытый ключ из JWKS (RFC 7517).
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey - разобранный ключ JWKS.
+type publicKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// KeySet - набор ключей проверки JWT из файла или по URL (например, jwks_uri провайдера OIDC).
+// Ключи по URL перечитываются по истечении jwksTTL и при появлении неизвестного kid.
+type KeySet struct {
+	source string
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    []publicKey
+	fetched time.Time
+}
+
+// LoadKeySet загружает JWKS из файла или по http(s) URL.
+func LoadKeySet(ctx context.Context, source string) (*KeySet, error) {
+	ks := &KeySet{source: source, client: &http.Client{Timeout: 10 * time.Second}}
+	keys, err := ks.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ks.keys, ks.fetched = keys, time.Now()
+	return ks, nil
+}
+
+func (ks *KeySet) remote() bool {
+	return strings.HasPrefix(ks.source, "http://") || strings.HasPrefix(ks.source, "https://")
+}
+
+// lookup возвращает ключи для токена с заданными kid и alg.
+func (ks *KeySet) lookup(ctx context.Context, kid, alg string) ([]publicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	found := ks.match(kid, alg)
+	if !ks.remote() {
+		return found, nil
+	}
+	age := time.Since(ks.fetched)
+	if age > jwksTTL || (len(found) == 0 && age > jwksMinRefresh) {
+		keys, err := ks.load(ctx)
+		if err != nil {
+			// Продолжаем работать с прежними ключами: провайдер может быть временно недоступен.
+			slog.Warn("failed to refresh jwks", "source", ks.source, "error", err)
+			return found, nil
+		}
+		ks.keys, ks.fetched = keys, time.Now()
+		found = ks.match(kid, alg)
+	}
+	return found, nil
+}
+
+func (ks *KeySet) match(kid, alg string) []publicKey {
+	var found []publicKey
+	for _, k := range ks.keys {
+		if kid != "" && k.kid != kid {
+			continue
+		}
+		if k.alg != "" && k.alg != alg {
+			continue
+		}
+		found = append(found, k)
+	}
+	return found
+}
+
+func (ks *KeySet) load(ctx context.Context) ([]publicKey, error) {
+	var data []byte
+	if ks.remote() {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.source, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwks url: %w", err)
+		}
+		res, err := ks.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch jwks: %s", res.Status)
+		}
+		data, err = io.ReadAll(io.LimitReader(res.Body, 1<<20))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read jwks: %w", err)
+		}
+	} else {
+		var err error
+		data, err = os.ReadFile(ks.source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read jwks: %w", err)
+		}
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse jwks: %w", err)
+	}
+
+	var keys []publicKey
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			// Ключ неподдерживаемого типа не должен мешать остальным.
+			slog.Warn("skipping jwks key", "kid", k.Kid, "error", err)
+			continue
+		}
+		keys = append(keys, publicKey{kid: k.Kid, alg: k.Alg, key: pub})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks has no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid n: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid e: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid rsa exponent")
+		}
+		if n.BitLen() < 2048 {
+			return nil, errors.New("rsa key is shorter than 2048 bits")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y: %w", err)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid ec point size")
+		}
+		// Несжатая точка SEC 1: 0x04 || X || Y; ParseUncompressedPublicKey проверяет, что она на кривой.
+		point := append(append([]byte{4}, x...), y...)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
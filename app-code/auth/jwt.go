@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrUnauthenticated - токен отсутствует, не подходит ни одному способу аутентификации или недействителен.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// leeway - допустимое расхождение часов с провайдером при проверке exp и nbf.
+const leeway = time.Minute
+
+// JWTConfig - параметры проверки JWT.
+type JWTConfig struct {
+	// Issuer - ожидаемое значение iss; пустое значение не проверяется.
+	Issuer string
+	// Audience - значение, которое должно входить в aud; пустое значение не проверяется.
+	Audience string
+	// RolesClaim - путь к claim с ролями через точку (например, realm_access.roles).
+	// Значение - массив строк или строка с ролями через пробел.
+	RolesClaim string
+	// RoleMap сопоставляет значения claim ролям Witness. Если пуст, значения claim
+	// используются как роли напрямую; иначе значения без сопоставления отбрасываются.
+	RoleMap map[string]string
+	// TenantClaim - claim с арендатором субъекта; пустое значение - арендатор не определяется.
+	TenantClaim string
+}
+
+// JWTVerifier проверяет bearer JWT по ключам JWKS.
+type JWTVerifier struct {
+	keys *KeySet
+	cfg  JWTConfig
+	now  func() time.Time
+}
+
+// NewJWTVerifier создает проверку JWT.
+func NewJWTVerifier(keys *KeySet, cfg JWTConfig) *JWTVerifier {
+	if cfg.RolesClaim == "" {
+		cfg.RolesClaim = "roles"
+	}
+	return &JWTVerifier{keys: keys, cfg: cfg, now: time.Now}
+}
+
+// Authenticate проверяет подпись и claims токена и возвращает субъекта с ролями из claims.
+// Токен, не похожий на JWT, отклоняется с ErrUnauthenticated без разбора.
+func (v *JWTVerifier) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrUnauthenticated
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: invalid jwt header: %v", ErrUnauthenticated, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid jwt signature encoding", ErrUnauthenticated)
+	}
+
+	keys, err := v.keys.lookup(ctx, header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, k := range keys {
+		if verifySignature(header.Alg, k.key, signed, sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("%w: jwt signature is not valid for any known key", ErrUnauthenticated)
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: invalid jwt claims: %v", ErrUnauthenticated, err)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+
+	subject, _ := claims["sub"].(string)
+	principal := &Principal{Subject: subject, Roles: v.roles(claims)}
+	if v.cfg.TenantClaim != "" {
+		principal.Tenant, _ = claims[v.cfg.TenantClaim].(string)
+	}
+	return principal, nil
+}
+
+func (v *JWTVerifier) checkClaims(claims map[string]any) error {
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("jwt has no exp")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(leeway)) {
+		return errors.New("jwt expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("jwt is not valid yet")
+	}
+	if v.cfg.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.cfg.Issuer {
+			return fmt.Errorf("unexpected issuer %q", iss)
+		}
+	}
+	if v.cfg.Audience != "" && !slices.Contains(stringList(claims["aud"]), v.cfg.Audience) {
+		return errors.New("jwt is not issued for this audience")
+	}
+	return nil
+}
+
+// roles извлекает роли из claim RolesClaim и сопоставляет их через RoleMap.
+func (v *JWTVerifier) roles(claims map[string]any) []string {
+	var value any = claims
+	for _, key := range strings.Split(v.cfg.RolesClaim, ".") {
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = obj[key]
+	}
+
+	var roles []string
+	for _, r := range stringList(value) {
+		if len(v.cfg.RoleMap) > 0 {
+			mapped, ok := v.cfg.RoleMap[r]
+			if !ok {
+				continue
+			}
+			r = mapped
+		}
+		if !slices.Contains(roles, r) {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// stringList приводит значение claim к списку строк: массив строк или строка через пробел.
+func stringList(v any) []string {
+	switch val := v.(type) {
+	case string:
+		return strings.Fields(val)
+	case []any:
+		var out []string
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// verifySignature проверяет подпись JWS. Алгоритм из заголовка должен соответствовать типу ключа,
+// поэтому "none" и HMAC с открытым ключом в качестве секрета не принимаются.
+func verifySignature(alg string, key crypto.PublicKey, signed, sig []byte) bool {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "PS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "PS512", "ES512":
+		hash = crypto.SHA512
+	case "EdDSA":
+		pub, ok := key.(ed25519.PublicKey)
+		return ok && ed25519.Verify(pub, signed, sig)
+	default:
+		return false
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(pub, hash, digest, sig) == nil
+		case "PS":
+			return rsa.VerifyPSS(pub, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+	case *ecdsa.PublicKey:
+		bits := pub.Curve.Params().BitSize
+		if alg[:2] != "ES" || alg[2:] != map[int]string{256: "256", 384: "384", 521: "512"}[bits] {
+			return false
+		}
+		// Подпись ES* - r || s фиксированной длины (RFC 7518, 3.4), а не DER.
+		size := (bits + 7) / 8
+		if len(sig) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		return ecdsa.Verify(pub, digest, r, s)
+	}
+	return false
+}
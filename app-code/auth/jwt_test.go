@@ -0,0 +1,329 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"witness/auth"
+)
+
+// signer подписывает JWT одним ключом из тестового JWKS.
+type signer struct {
+	kid  string
+	alg  string
+	sign func(data []byte) []byte
+	jwk  map[string]string
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func rsaSigner(t *testing.T, kid string) *signer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &signer{
+		kid: kid,
+		alg: "RS256",
+		sign: func(data []byte) []byte {
+			digest := sha256.Sum256(data)
+			sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return sig
+		},
+		jwk: map[string]string{
+			"kty": "RSA", "kid": kid, "alg": "RS256", "use": "sig",
+			"n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes()),
+		},
+	}
+}
+
+func ecSigner(t *testing.T, kid string) *signer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	point, err := key.PublicKey.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &signer{
+		kid: kid,
+		alg: "ES256",
+		sign: func(data []byte) []byte {
+			digest := sha256.Sum256(data)
+			r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			sig := make([]byte, 64)
+			r.FillBytes(sig[:32])
+			s.FillBytes(sig[32:])
+			return sig
+		},
+		jwk: map[string]string{
+			"kty": "EC", "kid": kid, "crv": "P-256",
+			"x": b64(point[1:33]), "y": b64(point[33:]),
+		},
+	}
+}
+
+func edSigner(t *testing.T, kid string) *signer {
+	t.Helper()
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &signer{
+		kid:  kid,
+		alg:  "EdDSA",
+		sign: func(data []byte) []byte { return ed25519.Sign(key, data) },
+		jwk:  map[string]string{"kty": "OKP", "kid": kid, "crv": "Ed25519", "x": b64(pub)},
+	}
+}
+
+// token собирает JWT с заголовком header (alg и kid подписанта, если не заданы) и claims.
+func (s *signer) token(t *testing.T, header map[string]string, claims map[string]any) string {
+	t.Helper()
+	h := map[string]string{"typ": "JWT", "alg": s.alg, "kid": s.kid}
+	for k, v := range header {
+		h[k] = v
+	}
+	hdr, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := b64(hdr) + "." + b64(payload)
+	return signed + "." + b64(s.sign([]byte(signed)))
+}
+
+// writeJWKS сохраняет открытые ключи во временный файл и загружает их.
+func writeJWKS(t *testing.T, signers ...*signer) *auth.KeySet {
+	t.Helper()
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for _, s := range signers {
+		set.Keys = append(set.Keys, s.jwk)
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.LoadKeySet(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func validClaims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"sub":   "alice",
+		"iss":   "https://idp.example.com",
+		"aud":   []string{"other", "witness"},
+		"exp":   now.Add(time.Hour).Unix(),
+		"iat":   now.Unix(),
+		"roles": []string{"events:read", "pii_reader"},
+		"org":   "billing",
+	}
+}
+
+func with(claims map[string]any, key string, value any) map[string]any {
+	claims[key] = value
+	if value == nil {
+		delete(claims, key)
+	}
+	return claims
+}
+
+var testConfig = auth.JWTConfig{
+	Issuer:      "https://idp.example.com",
+	Audience:    "witness",
+	TenantClaim: "org",
+}
+
+func TestJWTValidTokens(t *testing.T) {
+	signers := []*signer{rsaSigner(t, "rsa-1"), ecSigner(t, "ec-1"), edSigner(t, "ed-1")}
+	verifier := auth.NewJWTVerifier(writeJWKS(t, signers...), testConfig)
+
+	for _, s := range signers {
+		t.Run(s.alg, func(t *testing.T) {
+			p, err := verifier.Authenticate(context.Background(), s.token(t, nil, validClaims()))
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if p.Subject != "alice" || p.Tenant != "billing" || !slices.Equal(p.Roles, []string{"events:read", "pii_reader"}) {
+				t.Errorf("got principal %+v", p)
+			}
+		})
+	}
+
+	// Без kid токен проверяется всеми ключами с подходящим alg.
+	t.Run("no kid", func(t *testing.T) {
+		token := signers[0].token(t, map[string]string{"kid": ""}, validClaims())
+		if _, err := verifier.Authenticate(context.Background(), token); err != nil {
+			t.Fatalf("Authenticate: %v", err)
+		}
+	})
+}
+
+func TestJWTRejectedTokens(t *testing.T) {
+	rs := rsaSigner(t, "rsa-1")
+	ec := ecSigner(t, "ec-1")
+	verifier := auth.NewJWTVerifier(writeJWKS(t, rs, ec), testConfig)
+	// Чужой ключ с тем же kid, что у настоящего.
+	forged := rsaSigner(t, "rsa-1")
+	past := time.Now().Add(-2 * time.Hour)
+
+	tests := map[string]string{
+		"expired":             rs.token(t, nil, with(validClaims(), "exp", past.Unix())),
+		"no exp":              rs.token(t, nil, with(validClaims(), "exp", nil)),
+		"not valid yet":       rs.token(t, nil, with(validClaims(), "nbf", time.Now().Add(time.Hour).Unix())),
+		"wrong issuer":        rs.token(t, nil, with(validClaims(), "iss", "https://evil.example.com")),
+		"wrong audience":      rs.token(t, nil, with(validClaims(), "aud", "other")),
+		"no audience":         rs.token(t, nil, with(validClaims(), "aud", nil)),
+		"unknown kid":         rs.token(t, map[string]string{"kid": "rsa-2"}, validClaims()),
+		"alg of another key":  rs.token(t, map[string]string{"alg": "ES256"}, validClaims()),
+		"alg not of the key":  rs.token(t, map[string]string{"alg": "RS384"}, validClaims()),
+		"alg none":            rs.token(t, map[string]string{"alg": "none"}, validClaims()),
+		"hmac":                rs.token(t, map[string]string{"alg": "HS256"}, validClaims()),
+		"ec key for rsa kid":  ec.token(t, map[string]string{"kid": "rsa-1"}, validClaims()),
+		"forged key same kid": forged.token(t, nil, validClaims()),
+		"not a jwt":           "wk_0123_secret",
+		"garbage":             "a.b.c",
+	}
+	// Поднятые роли с подписью исходного токена.
+	good := rs.token(t, nil, validClaims())
+	evil := rs.token(t, nil, with(validClaims(), "roles", []string{"admin"}))
+	tests["tampered claims"] = evil[:strings.LastIndex(evil, ".")] + good[strings.LastIndex(good, "."):]
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := verifier.Authenticate(context.Background(), token)
+			if !errors.Is(err, auth.ErrUnauthenticated) {
+				t.Fatalf("got principal %+v, error %v; want ErrUnauthenticated", p, err)
+			}
+		})
+	}
+}
+
+func TestJWTExpiryLeeway(t *testing.T) {
+	rs := rsaSigner(t, "rsa-1")
+	verifier := auth.NewJWTVerifier(writeJWKS(t, rs), auth.JWTConfig{})
+
+	// Расхождение часов с провайдером до минуты допускается.
+	token := rs.token(t, nil, with(validClaims(), "exp", time.Now().Add(-30*time.Second).Unix()))
+	if _, err := verifier.Authenticate(context.Background(), token); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+}
+
+func TestJWTRolesAndTenant(t *testing.T) {
+	rs := rsaSigner(t, "rsa-1")
+	keys := writeJWKS(t, rs)
+
+	tests := []struct {
+		name   string
+		cfg    auth.JWTConfig
+		claims map[string]any
+		roles  []string
+		tenant string
+	}{
+		{
+			name:   "nested claim with role map",
+			cfg:    auth.JWTConfig{RolesClaim: "realm_access.roles", RoleMap: map[string]string{"auditor": "events:read", "dpo": "pii_reader", "root": "admin"}},
+			claims: with(validClaims(), "realm_access", map[string]any{"roles": []string{"auditor", "offline_access", "dpo", "auditor"}}),
+			roles:  []string{"events:read", "pii_reader"},
+		},
+		{
+			name:   "space separated scope",
+			cfg:    auth.JWTConfig{RolesClaim: "scope"},
+			claims: with(validClaims(), "scope", "events:read aggregates:read"),
+			roles:  []string{"events:read", "aggregates:read"},
+		},
+		{
+			name:   "missing roles claim",
+			cfg:    auth.JWTConfig{RolesClaim: "groups"},
+			claims: validClaims(),
+		},
+		{
+			name:   "tenant claim",
+			cfg:    auth.JWTConfig{TenantClaim: "org"},
+			claims: validClaims(),
+			roles:  []string{"events:read", "pii_reader"},
+			tenant: "billing",
+		},
+		{
+			name:   "tenant claim is not a string",
+			cfg:    auth.JWTConfig{TenantClaim: "org"},
+			claims: with(validClaims(), "org", []string{"billing"}),
+			roles:  []string{"events:read", "pii_reader"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := auth.NewJWTVerifier(keys, tt.cfg).Authenticate(context.Background(), rs.token(t, nil, tt.claims))
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if !slices.Equal(p.Roles, tt.roles) || p.Tenant != tt.tenant {
+				t.Errorf("got roles %v, tenant %q; want %v, %q", p.Roles, p.Tenant, tt.roles, tt.tenant)
+			}
+		})
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	for name, path := range map[string]string{
+		"missing file":   filepath.Join(dir, "missing.json"),
+		"invalid json":   write("invalid.json", "{"),
+		"no usable keys": write("enc.json", `{"keys": [{"kty": "oct", "k": "c2VjcmV0"}, {"kty": "RSA", "use": "enc"}]}`),
+		"short rsa key":  write("short.json", `{"keys": [{"kty": "RSA", "n": "AQAB", "e": "AQAB"}]}`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := auth.LoadKeySet(context.Background(), path); err == nil {
+				t.Error("LoadKeySet must fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Authenticator определяет субъекта по bearer-токену. Если токен ему не подходит
+// или недействителен, возвращает ошибку, обернутую в ErrUnauthenticated.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+// Chain проверяет токен способами аутентификации по очереди и принимает его,
+// если его подтвердил хотя бы один.
+type Chain []Authenticator
+
+// Authenticate реализует Authenticator. Ошибка, отличная от ErrUnauthenticated
+// (например, недоступно хранилище ключей), прерывает проверку.
+func (ch Chain) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	for _, a := range ch {
+		principal, err := a.Authenticate(ctx, token)
+		if err == nil {
+			return principal, nil
+		}
+		if !errors.Is(err, ErrUnauthenticated) {
+			return nil, err
+		}
+		slog.Debug("token rejected", "error", err)
+	}
+	return nil, ErrUnauthenticated
+}
+
+// Middleware требует заголовок Authorization: Bearer <token> и принимает токен,
+// который подтвердил хотя бы один из authenticators.
+func Middleware(authenticators ...Authenticator) echo.MiddlewareFunc {
+	return middleware(Chain(authenticators), false)
+}
+
+// Deferred - как Middleware, но запрос без заголовка Authorization пропускается без
+// субъекта: браузер не может передать заголовок при открытии WebSocket, и токен
+// проверяется позже, из сообщения connection_init (см. graphql.WebsocketInit).
+func Deferred(authenticators ...Authenticator) echo.MiddlewareFunc {
+	return middleware(Chain(authenticators), true)
+}
+
+func middleware(chain Chain, optional bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			token, ok := bearerToken(req)
+			if !ok {
+				if optional && req.Header.Get(echo.HeaderAuthorization) == "" {
+					return next(c)
+				}
+				return unauthorized(c, "missing bearer token")
+			}
+
+			principal, err := chain.Authenticate(req.Context(), token)
+			if errors.Is(err, ErrUnauthenticated) {
+				return unauthorized(c, "invalid token")
+			}
+			if err != nil {
+				slog.Error("authentication failed", "error", err)
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "authentication is temporarily unavailable")
+			}
+			c.SetRequest(req.WithContext(WithPrincipal(req.Context(), principal)))
+			return next(c)
+		}
+	}
+}
+
+// BearerToken извлекает токен из значения заголовка Authorization ("Bearer <token>").
+func BearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func bearerToken(req *http.Request) (string, bool) {
+	return BearerToken(req.Header.Get(echo.HeaderAuthorization))
+}
+
+func unauthorized(c echo.Context, message string) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="witness"`)
+	return echo.NewHTTPError(http.StatusUnauthorized, message)
+}
@@ -0,0 +1,113 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"witness/auth"
+
+	"github.com/labstack/echo/v4"
+)
+
+// authFunc - Authenticator из функции.
+type authFunc func(ctx context.Context, token string) (*auth.Principal, error)
+
+func (f authFunc) Authenticate(ctx context.Context, token string) (*auth.Principal, error) {
+	return f(ctx, token)
+}
+
+// serve пропускает запрос через mw и возвращает ответ и субъекта, которого увидел обработчик.
+func serve(t *testing.T, mw echo.MiddlewareFunc, authorization string) (*httptest.ResponseRecorder, *auth.Principal) {
+	t.Helper()
+	e := echo.New()
+	var seen *auth.Principal
+	e.GET("/", func(c echo.Context) error {
+		seen = auth.FromContext(c.Request().Context())
+		return c.NoContent(http.StatusOK)
+	}, mw)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if authorization != "" {
+		req.Header.Set(echo.HeaderAuthorization, authorization)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec, seen
+}
+
+func TestMiddleware(t *testing.T) {
+	rs := rsaSigner(t, "rsa-1")
+	verifier := auth.NewJWTVerifier(writeJWKS(t, rs), testConfig)
+	valid := rs.token(t, nil, validClaims())
+
+	tests := []struct {
+		name          string
+		mw            echo.MiddlewareFunc
+		authorization string
+		status        int
+		subject       string
+	}{
+		{name: "valid token", mw: auth.Middleware(verifier), authorization: "Bearer " + valid, status: http.StatusOK, subject: "alice"},
+		{name: "scheme is case insensitive", mw: auth.Middleware(verifier), authorization: "bearer " + valid, status: http.StatusOK, subject: "alice"},
+		{name: "no header", mw: auth.Middleware(verifier), status: http.StatusUnauthorized},
+		{name: "basic auth", mw: auth.Middleware(verifier), authorization: "Basic YWxpY2U6c2VjcmV0", status: http.StatusUnauthorized},
+		{name: "invalid token", mw: auth.Middleware(verifier), authorization: "Bearer " + valid + "x", status: http.StatusUnauthorized},
+		{name: "deferred without header", mw: auth.Deferred(verifier), status: http.StatusOK},
+		{name: "deferred with valid token", mw: auth.Deferred(verifier), authorization: "Bearer " + valid, status: http.StatusOK, subject: "alice"},
+		{name: "deferred with basic auth", mw: auth.Deferred(verifier), authorization: "Basic YWxpY2U6c2VjcmV0", status: http.StatusUnauthorized},
+		{name: "deferred with invalid token", mw: auth.Deferred(verifier), authorization: "Bearer garbage", status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, p := serve(t, tt.mw, tt.authorization)
+			if rec.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status == http.StatusUnauthorized && rec.Header().Get(echo.HeaderWWWAuthenticate) != `Bearer realm="witness"` {
+				t.Errorf("got WWW-Authenticate %q", rec.Header().Get(echo.HeaderWWWAuthenticate))
+			}
+			var subject string
+			if p != nil {
+				subject = p.Subject
+			}
+			if subject != tt.subject {
+				t.Errorf("got subject %q, want %q", subject, tt.subject)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	reject := authFunc(func(context.Context, string) (*auth.Principal, error) {
+		return nil, auth.ErrUnauthenticated
+	})
+	accept := authFunc(func(_ context.Context, token string) (*auth.Principal, error) {
+		if token != "key" {
+			return nil, auth.ErrUnauthenticated
+		}
+		return &auth.Principal{Subject: "apikey:1", Roles: []string{auth.RoleAdmin}}, nil
+	})
+	broken := authFunc(func(context.Context, string) (*auth.Principal, error) {
+		return nil, errors.New("jwks unavailable")
+	})
+
+	// Токен, который не подошел первому способу, проверяется следующим.
+	rec, p := serve(t, auth.Middleware(reject, accept), "Bearer key")
+	if rec.Code != http.StatusOK || p == nil || p.Subject != "apikey:1" {
+		t.Errorf("got status %d, principal %+v", rec.Code, p)
+	}
+	rec, _ = serve(t, auth.Middleware(reject, accept), "Bearer other")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("no authenticator accepted the token: got status %d", rec.Code)
+	}
+	// Сбой проверки - не повод отвечать 401: клиенту незачем менять токен.
+	rec, _ = serve(t, auth.Middleware(reject, broken, accept), "Bearer key")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("authenticator failure: got status %d, want 503", rec.Code)
+	}
+	if _, err := (auth.Chain{}).Authenticate(context.Background(), "key"); !errors.Is(err, auth.ErrUnauthenticated) {
+		t.Errorf("empty chain: got %v, want ErrUnauthenticated", err)
+	}
+}
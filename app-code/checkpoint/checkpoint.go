@@ -0,0 +1,256 @@
+// Package checkpoint периодически подписывает корни деревьев Меркла над событиями,
+// принятыми за интервал, и строит доказательства включения отдельных событий.
+//
+// Листья дерева - хеши событий (integrity.Hash) в порядке (stream, seq). Листья сохраняются
+// вместе с точкой, поэтому доказательства строятся и после удаления части событий интервала.
+// Контрольная точка вместе с доказательством позволяет проверить событие без доступа к Witness.
+package checkpoint
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+	"witness/integrity"
+	"witness/models"
+	"witness/storage"
+)
+
+// settleDelay - запас после конца интервала: события, принятые в его конце,
+// должны успеть попасть в хранилище до построения дерева.
+const settleDelay = time.Minute
+
+// ErrTreeChanged возвращается, когда события интервала уже не совпадают с подписанным деревом.
+var ErrTreeChanged = errors.New("events of the checkpoint interval changed since it was signed")
+
+// Store - то, что нужно контрольным точкам от хранилища.
+type Store interface {
+	storage.EventStore
+	storage.ChainStore
+	storage.CheckpointStore
+}
+
+// Service строит контрольные точки и доказательства включения.
+type Service struct {
+	store    Store
+	key      ed25519.PrivateKey
+	interval time.Duration
+	file     string
+	now      func() time.Time
+}
+
+// NewService создает сервис контрольных точек. key нужен только для построения
+// точек (Run, RunOnce); file, если задан, дополнительно получает каждую точку строкой JSON.
+func NewService(store Store, key ed25519.PrivateKey, interval time.Duration, file string) *Service {
+	return &Service{
+		store:    store,
+		key:      key,
+		interval: interval,
+		file:     file,
+		now:      time.Now,
+	}
+}
+
+// Run строит контрольные точки по таймеру до отмены контекста.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunOnce(ctx); err != nil {
+			slog.Error("checkpoint run failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce строит контрольные точки для всех завершившихся интервалов после последней
+// сохраненной. Без сохраненных точек начинает с последнего завершившегося интервала.
+func (s *Service) RunOnce(ctx context.Context) (int, error) {
+	if s.key == nil {
+		return 0, errors.New("checkpoint signing key is not configured")
+	}
+
+	until := s.now().Add(-settleDelay)
+	var from time.Time
+	latest, err := s.store.LatestCheckpoint(ctx)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		from = until.Truncate(s.interval).Add(-s.interval)
+	case err != nil:
+		return 0, err
+	default:
+		from = latest.To
+	}
+
+	built := 0
+	for ; !from.Add(s.interval).After(until); from = from.Add(s.interval) {
+		cp, err := s.Build(ctx, from, from.Add(s.interval))
+		if err != nil {
+			return built, err
+		}
+		slog.Info("checkpoint built", "id", cp.ID, "from", cp.From, "to", cp.To, "tree_size", cp.TreeSize)
+		built++
+	}
+	return built, nil
+}
+
+// Build строит, подписывает и сохраняет контрольную точку для интервала [from, to).
+// Идентификатор зависит только от интервала, поэтому повторное построение перезаписывает точку.
+func (s *Service) Build(ctx context.Context, from, to time.Time) (*models.Checkpoint, error) {
+	if s.key == nil {
+		return nil, errors.New("checkpoint signing key is not configured")
+	}
+
+	leaves, stored, err := s.leaves(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to = from.UTC(), to.UTC()
+	cp := &models.Checkpoint{
+		ID:        "cp-" + from.Format("20060102T150405Z"),
+		From:      from,
+		To:        to,
+		TreeSize:  int64(len(leaves)),
+		RootHash:  hex.EncodeToString(RootHash(leaves)),
+		CreatedAt: s.now().UTC(),
+	}
+	sign(cp, s.key)
+
+	// Листья сохраняются раньше точки: сохраненная точка всегда имеет свои листья.
+	if err := s.store.SaveCheckpointLeaves(ctx, cp.ID, stored); err != nil {
+		return nil, err
+	}
+	if err := s.store.SaveCheckpoint(ctx, cp); err != nil {
+		return nil, err
+	}
+	if s.file != "" {
+		if err := appendFile(s.file, cp); err != nil {
+			return nil, err
+		}
+	}
+	return cp, nil
+}
+
+// Prove строит доказательство включения события в его контрольную точку.
+// Возвращает nil, если контрольной точки для события еще нет.
+func (s *Service) Prove(ctx context.Context, eventID string) (*models.InclusionProof, error) {
+	event, err := s.store.Get(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Chain == nil {
+		return nil, fmt.Errorf("event %s was not chained at ingest", eventID)
+	}
+
+	cp, err := s.store.CheckpointAt(ctx, event.Chain.IngestedAt)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.store.CheckpointLeaves(ctx, cp.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(stored) == 0 && cp.TreeSize > 0 {
+		// Точка построена до того, как листья стали сохраняться: пересобираем их по событиям.
+		if _, stored, err = s.leaves(ctx, cp.From, cp.To); err != nil {
+			return nil, err
+		}
+	}
+
+	leaves := make([][]byte, len(stored))
+	index := -1
+	for i, leaf := range stored {
+		if leaves[i], err = hex.DecodeString(leaf.LeafHash); err != nil {
+			return nil, fmt.Errorf("invalid leaf %d of checkpoint %s: %w", i, cp.ID, err)
+		}
+		if leaf.EventID == eventID {
+			index = i
+		}
+	}
+	if int64(len(leaves)) != cp.TreeSize || hex.EncodeToString(RootHash(leaves)) != cp.RootHash {
+		return nil, fmt.Errorf("%w: checkpoint %s, run `witness verify` for details", ErrTreeChanged, cp.ID)
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("%w: event %s is not in checkpoint %s", ErrTreeChanged, eventID, cp.ID)
+	}
+
+	canonical, err := integrity.Canonical(event)
+	if err != nil {
+		return nil, err
+	}
+	eventHash := sha256.Sum256(canonical)
+	if !bytes.Equal(LeafHash(eventHash[:]), leaves[index]) {
+		return nil, fmt.Errorf("%w: event %s differs from its leaf in checkpoint %s", ErrTreeChanged, eventID, cp.ID)
+	}
+	path := InclusionPath(leaves, index)
+	proof := &models.InclusionProof{
+		EventID:    eventID,
+		EventJSON:  string(canonical),
+		EventHash:  hex.EncodeToString(eventHash[:]),
+		LeafHash:   hex.EncodeToString(leaves[index]),
+		LeafIndex:  int64(index),
+		Path:       make([]string, len(path)),
+		Checkpoint: cp,
+	}
+	for i, p := range path {
+		proof.Path[i] = hex.EncodeToString(p)
+	}
+	return proof, nil
+}
+
+// leaves возвращает листья дерева интервала [from, to) и их записи для хранилища в том же порядке.
+// Хеши событий пересчитываются, а не берутся из chain.hash.
+func (s *Service) leaves(ctx context.Context, from, to time.Time) ([][]byte, []models.CheckpointLeaf, error) {
+	var leaves [][]byte
+	var stored []models.CheckpointLeaf
+	err := s.store.ScanChain(ctx, from, to.Add(-time.Nanosecond), func(event *models.AuditEvent) error {
+		hash, err := integrity.Hash(event)
+		if err != nil {
+			return err
+		}
+		raw, _ := hex.DecodeString(hash)
+		leaf := LeafHash(raw)
+		leaves = append(leaves, leaf)
+		stored = append(stored, models.CheckpointLeaf{EventID: event.EventID, LeafHash: hex.EncodeToString(leaf)})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan chain: %w", err)
+	}
+	return leaves, stored, nil
+}
+
+// appendFile дописывает контрольную точку в файл строкой JSON.
+func appendFile(path string, cp *models.Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open checkpoint file: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,87 @@
+package checkpoint_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"witness/checkpoint"
+	"witness/integrity"
+	"witness/models"
+	"witness/storage/memory"
+)
+
+// chained принимает события через цепочку и сохраняет их; i-е событие на i часов старше t.
+func chained(t *testing.T, store *memory.Store, ts time.Time, n int) []*models.AuditEvent {
+	t.Helper()
+	ctx := context.Background()
+	chain := integrity.NewChain(store)
+	var events []*models.AuditEvent
+	for i := range n {
+		event := &models.AuditEvent{
+			EventID:   fmt.Sprintf("e%d", i),
+			Timestamp: ts.Add(-time.Duration(i) * time.Hour),
+			Status:    "SUCCESS",
+			EventType: "LOGIN",
+			Actor:     models.Actor{ID: "alice", Type: "USER"},
+			Entity:    models.Entity{ID: "doc-1", Type: "DOCUMENT"},
+		}
+		accepted, err := chain.Append(ctx, "test", event)
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		events = append(events, accepted)
+	}
+	if err := store.IndexBatch(ctx, events); err != nil {
+		t.Fatalf("IndexBatch: %v", err)
+	}
+	return events
+}
+
+func TestProveAfterRetention(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	key, _, err := checkpoint.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := checkpoint.NewService(store, key, time.Hour, "")
+
+	now := time.Now().UTC()
+	events := chained(t, store, now, 7)
+	cp, err := service.Build(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if cp.TreeSize != 7 {
+		t.Fatalf("tree size: got %d, want 7", cp.TreeSize)
+	}
+
+	// Ретенция удаляет события старше трех часов: e4..e6 пропадают из интервала точки.
+	deleted, err := store.DeleteEventsBefore(ctx, now.Add(-3*time.Hour-time.Minute), nil)
+	if err != nil || deleted != 3 {
+		t.Fatalf("DeleteEventsBefore: deleted %d, error %v", deleted, err)
+	}
+
+	for _, event := range events[:4] {
+		proof, err := service.Prove(ctx, event.EventID)
+		if err != nil {
+			t.Fatalf("Prove(%s): %v", event.EventID, err)
+		}
+		if err := checkpoint.VerifyProof(proof, key.Public().(ed25519.PublicKey)); err != nil {
+			t.Errorf("VerifyProof(%s): %v", event.EventID, err)
+		}
+	}
+
+	// Событие, измененное после подписи, доказательства не получает.
+	changed := *events[1]
+	changed.Status = "FAILURE"
+	if err := store.IndexBatch(ctx, []*models.AuditEvent{&changed}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Prove(ctx, changed.EventID); !errors.Is(err, checkpoint.ErrTreeChanged) {
+		t.Errorf("Prove of a changed event: got %v, want ErrTreeChanged", err)
+	}
+}
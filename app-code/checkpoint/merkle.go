@@ -0,0 +1,88 @@
+package checkpoint
+
+import "crypto/sha256"
+
+// Дерево Меркла строится как в RFC 9162 (Certificate Transparency): листья и узлы
+// хешируются с разными префиксами, поэтому узел нельзя выдать за лист.
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// LeafHash возвращает хеш листа над данными.
+func LeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// RootHash вычисляет корень дерева над хешами листьев. Корень пустого дерева - SHA-256 пустой строки.
+func RootHash(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		return leaves[0]
+	}
+	k := split(len(leaves))
+	return nodeHash(RootHash(leaves[:k]), RootHash(leaves[k:]))
+}
+
+// InclusionPath возвращает путь доказательства включения листа index: хеши соседних
+// поддеревьев от листа к корню.
+func InclusionPath(leaves [][]byte, index int) [][]byte {
+	if len(leaves) <= 1 {
+		return [][]byte{}
+	}
+	k := split(len(leaves))
+	if index < k {
+		return append(InclusionPath(leaves[:k], index), RootHash(leaves[k:]))
+	}
+	return append(InclusionPath(leaves[k:], index-k), RootHash(leaves[:k]))
+}
+
+// VerifyInclusion проверяет, что лист leaf с номером index входит в дерево из size листьев
+// с корнем root (алгоритм из RFC 9162, раздел 2.1.3.2).
+func VerifyInclusion(leaf []byte, index, size int64, path [][]byte, root []byte) bool {
+	if index < 0 || index >= size {
+		return false
+	}
+	fn, sn := index, size-1
+	r := leaf
+	for _, p := range path {
+		if sn == 0 {
+			return false
+		}
+		if fn%2 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn%2 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && string(r) == string(root)
+}
+
+// split возвращает наибольшую степень двойки, меньшую n (n > 1).
+func split(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
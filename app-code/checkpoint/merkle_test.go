@@ -0,0 +1,92 @@
+package checkpoint_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"witness/checkpoint"
+)
+
+func testLeaves(n int) [][]byte {
+	leaves := make([][]byte, n)
+	for i := range leaves {
+		leaves[i] = checkpoint.LeafHash(fmt.Appendf(nil, "leaf-%d", i))
+	}
+	return leaves
+}
+
+func TestRootHash(t *testing.T) {
+	empty := sha256.Sum256(nil)
+	if got := checkpoint.RootHash(nil); !bytes.Equal(got, empty[:]) {
+		t.Errorf("root of an empty tree: got %x, want %x", got, empty)
+	}
+
+	// Дерево из трех листьев по RFC 9162: левое поддерево - наибольшая степень двойки.
+	leaves := testLeaves(3)
+	node := func(left, right []byte) []byte {
+		sum := sha256.Sum256(append(append([]byte{0x01}, left...), right...))
+		return sum[:]
+	}
+	want := node(node(leaves[0], leaves[1]), leaves[2])
+	if got := checkpoint.RootHash(leaves); !bytes.Equal(got, want) {
+		t.Errorf("root of three leaves: got %x, want %x", got, want)
+	}
+	if got := checkpoint.RootHash(leaves[:1]); !bytes.Equal(got, leaves[0]) {
+		t.Errorf("root of one leaf: got %x, want the leaf %x", got, leaves[0])
+	}
+
+	data := []byte("event")
+	leaf := sha256.Sum256(append([]byte{0x00}, data...))
+	if got := checkpoint.LeafHash(data); !bytes.Equal(got, leaf[:]) {
+		t.Errorf("LeafHash: got %x, want %x", got, leaf)
+	}
+}
+
+func TestInclusion(t *testing.T) {
+	const maxSize = 70
+	all := testLeaves(maxSize + 1)
+	for size := 1; size <= maxSize; size++ {
+		leaves := all[:size]
+		root := checkpoint.RootHash(leaves)
+		for index := range size {
+			path := checkpoint.InclusionPath(leaves, index)
+			leaf, i, n := leaves[index], int64(index), int64(size)
+			if !checkpoint.VerifyInclusion(leaf, i, n, path, root) {
+				t.Fatalf("size %d, index %d: valid proof is rejected", size, index)
+			}
+
+			if checkpoint.VerifyInclusion(checkpoint.LeafHash([]byte("other")), i, n, path, root) {
+				t.Errorf("size %d, index %d: proof of another leaf is accepted", size, index)
+			}
+			if sibling := index ^ 1; sibling < size && checkpoint.VerifyInclusion(leaf, int64(sibling), n, path, root) {
+				t.Errorf("size %d, index %d: proof is accepted for index %d", size, index, sibling)
+			}
+			if checkpoint.VerifyInclusion(leaf, i, n, path, checkpoint.RootHash(all[:size+1])) {
+				t.Errorf("size %d, index %d: proof is accepted against the root of a larger tree", size, index)
+			}
+			if checkpoint.VerifyInclusion(leaf, i, n, append(path, leaf), root) {
+				t.Errorf("size %d, index %d: proof with an extra hash is accepted", size, index)
+			}
+			if len(path) == 0 {
+				continue
+			}
+			if checkpoint.VerifyInclusion(leaf, i, n, path[:len(path)-1], root) {
+				t.Errorf("size %d, index %d: truncated proof is accepted", size, index)
+			}
+			for k := range path {
+				tampered := append([][]byte(nil), path...)
+				tampered[k] = bytes.Clone(path[k])
+				tampered[k][0] ^= 0xff
+				if checkpoint.VerifyInclusion(leaf, i, n, tampered, root) {
+					t.Errorf("size %d, index %d: proof with hash %d changed is accepted", size, index, k)
+				}
+			}
+		}
+		for _, index := range []int64{-1, int64(size)} {
+			if checkpoint.VerifyInclusion(leaves[0], index, int64(size), checkpoint.InclusionPath(leaves, 0), root) {
+				t.Errorf("size %d: index %d out of range is accepted", size, index)
+			}
+		}
+	}
+}
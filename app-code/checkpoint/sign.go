@@ -0,0 +1,138 @@
+package checkpoint
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+	"witness/models"
+)
+
+// LoadKey читает закрытый ключ Ed25519 из PEM-файла (PKCS #8, "PRIVATE KEY").
+func LoadKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s: expected PEM block PRIVATE KEY", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected Ed25519 key, got %T", path, key)
+	}
+	return edKey, nil
+}
+
+// GenerateKey создает новый ключ Ed25519 и возвращает его в PEM (PKCS #8).
+func GenerateKey() (ed25519.PrivateKey, []byte, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal key: %w", err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// KeyID - короткий идентификатор открытого ключа: первые 8 байт его SHA-256 (hex).
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// SignedPayload возвращает байты, которые подписываются ключом:
+//
+//	witness-checkpoint/v1\n<from>\n<to>\n<tree_size>\n<root_hash>\n
+//
+// Время - RFC 3339 с наносекундами в UTC.
+func SignedPayload(cp *models.Checkpoint) []byte {
+	return fmt.Appendf(nil, "witness-checkpoint/v1\n%s\n%s\n%d\n%s\n",
+		cp.From.UTC().Format(time.RFC3339Nano), cp.To.UTC().Format(time.RFC3339Nano), cp.TreeSize, cp.RootHash)
+}
+
+// sign подписывает контрольную точку и заполняет подпись и сведения о ключе.
+func sign(cp *models.Checkpoint, key ed25519.PrivateKey) {
+	pub := key.Public().(ed25519.PublicKey)
+	cp.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, SignedPayload(cp)))
+	cp.KeyID = KeyID(pub)
+	cp.PublicKey = base64.StdEncoding.EncodeToString(pub)
+}
+
+// VerifySignature проверяет подпись контрольной точки. Если trusted задан, подпись
+// должна быть сделана именно им; иначе используется ключ из самой контрольной точки.
+func VerifySignature(cp *models.Checkpoint, trusted ed25519.PublicKey) error {
+	pub := trusted
+	if pub == nil {
+		raw, err := base64.StdEncoding.DecodeString(cp.PublicKey)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return errors.New("checkpoint has invalid public key")
+		}
+		pub = raw
+	}
+	sig, err := base64.StdEncoding.DecodeString(cp.Signature)
+	if err != nil {
+		return errors.New("checkpoint has invalid signature encoding")
+	}
+	if !ed25519.Verify(pub, SignedPayload(cp), sig) {
+		return fmt.Errorf("signature of checkpoint %s is not valid for key %s", cp.ID, KeyID(pub))
+	}
+	return nil
+}
+
+// VerifyProof проверяет доказательство включения без обращения к Witness:
+// хеш канонического JSON события, путь до корня дерева и подпись контрольной точки.
+// Доказательство без event_json (выдано субъекту без роли pii_reader) проверяется
+// от event_hash: оно подтверждает включение события с этим хешем.
+func VerifyProof(proof *models.InclusionProof, trusted ed25519.PublicKey) error {
+	cp := proof.Checkpoint
+	if cp == nil {
+		return errors.New("proof has no checkpoint")
+	}
+
+	sum, err := hex.DecodeString(proof.EventHash)
+	if err != nil || len(sum) != sha256.Size {
+		return errors.New("invalid event_hash")
+	}
+	if proof.EventJSON != "" {
+		computed := sha256.Sum256([]byte(proof.EventJSON))
+		if hex.EncodeToString(computed[:]) != proof.EventHash {
+			return errors.New("event_hash does not match event_json")
+		}
+	}
+	leaf := LeafHash(sum)
+	if hex.EncodeToString(leaf) != proof.LeafHash {
+		return errors.New("leaf_hash does not match event_hash")
+	}
+
+	path := make([][]byte, len(proof.Path))
+	for i, p := range proof.Path {
+		h, err := hex.DecodeString(p)
+		if err != nil {
+			return fmt.Errorf("invalid path element %d: %w", i, err)
+		}
+		path[i] = h
+	}
+	root, err := hex.DecodeString(cp.RootHash)
+	if err != nil {
+		return fmt.Errorf("invalid root_hash: %w", err)
+	}
+	if !VerifyInclusion(leaf, proof.LeafIndex, cp.TreeSize, path, root) {
+		return errors.New("inclusion path does not lead to the checkpoint root")
+	}
+	return VerifySignature(cp, trusted)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"witness/apikey"
+	"witness/models"
+)
+
+// runAPIKey реализует `witness apikey <create|list|revoke>`.
+func runAPIKey(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: witness apikey create|list|revoke [flags]")
+	}
+
+	store, err := openStorage(ctx, getEnv("STORAGE", "opensearch"))
+	if err != nil {
+		return err
+	}
+	keys := apikey.NewService(store)
+
+	fs := flag.NewFlagSet("apikey "+args[0], flag.ContinueOnError)
+	switch args[0] {
+	case "create":
+		name := fs.String("name", "", "what the key is for, e.g. siem (required)")
+		scopes := fs.String("scopes", "", "comma-separated scopes: "+strings.Join(apikey.Scopes, ", ")+" (required)")
+		expiresIn := fs.Duration("expires-in", 0, "key lifetime, e.g. 2160h; 0 means no expiry")
+		tenant := fs.String("tenant", "", "restrict the key to events of this tenant (required with MULTI_TENANT)")
+		createdBy := fs.String("created-by", "", "who issues the key")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		key, token, err := keys.Create(ctx, *name, splitList(*scopes), *expiresIn, *tenant, *createdBy)
+		if err != nil {
+			return err
+		}
+		// Токен выводится только здесь: в хранилище есть лишь его хеш.
+		return printJSON(struct {
+			*models.APIKey
+			Token string `json:"token"`
+		}{key, token})
+
+	case "list":
+		active := fs.Bool("active", false, "only keys that are neither revoked nor expired")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		list, err := keys.List(ctx)
+		if err != nil {
+			return err
+		}
+		if *active {
+			now := time.Now()
+			filtered := list[:0]
+			for _, key := range list {
+				if key.IsActive(now) {
+					filtered = append(filtered, key)
+				}
+			}
+			list = filtered
+		}
+		return printJSON(list)
+
+	case "revoke":
+		id := fs.String("id", "", "key id (required)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		key, err := keys.Revoke(ctx, *id)
+		if err != nil {
+			return err
+		}
+		return printJSON(key)
+
+	default:
+		return fmt.Errorf("unknown apikey command %q", args[0])
+	}
+}
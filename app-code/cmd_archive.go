@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"witness/archive"
+)
+
+// runArchive реализует `witness archive <run|restore|list>`.
+func runArchive(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: witness archive run|restore|list [flags]")
+	}
+
+	fs := flag.NewFlagSet("archive "+args[0], flag.ContinueOnError)
+	switch args[0] {
+	case "run":
+		days := fs.Int("older-than-days", getEnvInt("ARCHIVE_AFTER_DAYS", 0), "archive events older than this many days")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *days <= 0 {
+			return errors.New("--older-than-days must be positive")
+		}
+
+		archiver, err := newArchiver(ctx, time.Duration(*days)*24*time.Hour)
+		if err != nil {
+			return err
+		}
+		n, err := archiver.RunOnce(ctx)
+		if err != nil {
+			return err
+		}
+		return printJSON(map[string]any{"archived": n})
+
+	case "restore":
+		from := fs.String("from", "", "restore events from this time (RFC 3339, required)")
+		to := fs.String("to", "", "restore events up to this time (RFC 3339, required)")
+		index := fs.String("index", "", "target index name (default: generated "+archive.RestoreIndexPrefix+"*)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		fromT, toT, err := requiredRange(*from, *to)
+		if err != nil {
+			return err
+		}
+
+		archiver, err := newArchiver(ctx, 0)
+		if err != nil {
+			return err
+		}
+		name, n, err := archiver.Restore(ctx, fromT, toT, *index)
+		if err != nil {
+			return err
+		}
+		return printJSON(map[string]any{"index": name, "restored": n})
+
+	case "list":
+		from := fs.String("from", "", "first day (RFC 3339, required)")
+		to := fs.String("to", "", "last day (RFC 3339, required)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		fromT, toT, err := requiredRange(*from, *to)
+		if err != nil {
+			return err
+		}
+
+		archiver, err := newArchiver(ctx, 0)
+		if err != nil {
+			return err
+		}
+		manifests, err := archiver.Manifests(ctx, fromT, toT)
+		if err != nil {
+			return err
+		}
+		return printJSON(manifests)
+
+	default:
+		return fmt.Errorf("unknown archive command %q", args[0])
+	}
+}
+
+// newArchiver создает архиватор по переменным окружения сервера.
+func newArchiver(ctx context.Context, maxAge time.Duration) (*archive.Archiver, error) {
+	osClient, err := connectOpenSearch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	target, err := newArchiveTarget(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return archive.NewArchiver(osClient, target, maxAge, 0), nil
+}
+
+// requiredRange разбирает обязательные флаги --from и --to.
+func requiredRange(from, to string) (time.Time, time.Time, error) {
+	if from == "" || to == "" {
+		return time.Time{}, time.Time{}, errors.New("--from and --to are required")
+	}
+	fromT, err := optionalTime("from", from)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	toT, err := optionalTime("to", to)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return *fromT, *toT, nil
+}
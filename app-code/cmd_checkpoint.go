@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"witness/checkpoint"
+	"witness/models"
+)
+
+// runCheckpoint реализует `witness checkpoint <keygen|run|prove|verify>`.
+func runCheckpoint(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: witness checkpoint keygen|run|prove|verify [flags]")
+	}
+
+	fs := flag.NewFlagSet("checkpoint "+args[0], flag.ContinueOnError)
+	switch args[0] {
+	case "keygen":
+		out := fs.String("out", "", "write the private key (PEM) to this file (required)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *out == "" {
+			return errors.New("--out is required")
+		}
+
+		key, data, err := checkpoint.GenerateKey()
+		if err != nil {
+			return err
+		}
+		// O_EXCL: случайно перезаписанный ключ не восстановить.
+		f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to create key file: %w", err)
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write key file: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		pub := key.Public().(ed25519.PublicKey)
+		return printJSON(map[string]any{
+			"key_id":     checkpoint.KeyID(pub),
+			"public_key": base64.StdEncoding.EncodeToString(pub),
+		})
+
+	case "run":
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		svc, err := openCheckpoints(ctx)
+		if err != nil {
+			return err
+		}
+		n, err := svc.RunOnce(ctx)
+		if err != nil {
+			return err
+		}
+		return printJSON(map[string]any{"built": n})
+
+	case "prove":
+		eventID := fs.String("event-id", "", "event to prove (required)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *eventID == "" {
+			return errors.New("--event-id is required")
+		}
+		svc, err := openCheckpoints(ctx)
+		if err != nil {
+			return err
+		}
+		proof, err := svc.Prove(ctx, *eventID)
+		if err != nil {
+			return err
+		}
+		if proof == nil {
+			return fmt.Errorf("event %s is not covered by a checkpoint yet", *eventID)
+		}
+		return printJSON(proof)
+
+	case "verify":
+		// Проверка офлайн: нужны файл доказательства и доверенный ключ. Ключ, вложенный
+		// в точку, доказывает только целостность файла: подделавший точку вложит свой ключ.
+		file := fs.String("proof", "", "inclusion proof JSON file (required)")
+		pubKey := fs.String("public-key", "", "trusted public key (base64, required unless --trust-embedded-key)")
+		embedded := fs.Bool("trust-embedded-key", false, "verify against the key embedded in the checkpoint (UNTRUSTED)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *file == "" {
+			return errors.New("--proof is required")
+		}
+		if *pubKey == "" && !*embedded {
+			return errors.New("--public-key is required (or --trust-embedded-key to skip key trust)")
+		}
+
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return fmt.Errorf("failed to read proof: %w", err)
+		}
+		var proof models.InclusionProof
+		if err := json.Unmarshal(data, &proof); err != nil {
+			return fmt.Errorf("failed to parse proof: %w", err)
+		}
+		var trusted ed25519.PublicKey
+		if *pubKey != "" {
+			raw, err := base64.StdEncoding.DecodeString(*pubKey)
+			if err != nil || len(raw) != ed25519.PublicKeySize {
+				return errors.New("invalid --public-key")
+			}
+			trusted = raw
+		} else {
+			fmt.Fprintln(os.Stderr, "WARNING: UNTRUSTED KEY - the proof is checked against the key embedded in the checkpoint;"+
+				" anyone who forged the checkpoint could have embedded their own key. Pass --public-key to verify the signer.")
+		}
+		if err := checkpoint.VerifyProof(&proof, trusted); err != nil {
+			return err
+		}
+		return printJSON(map[string]any{
+			"valid":       true,
+			"key_trusted": trusted != nil,
+			"event_id":    proof.EventID,
+			"checkpoint":  proof.Checkpoint.ID,
+		})
+
+	default:
+		return fmt.Errorf("unknown checkpoint command %q", args[0])
+	}
+}
+
+// openCheckpoints открывает хранилище и сервис контрольных точек по переменным окружения.
+func openCheckpoints(ctx context.Context) (*checkpoint.Service, error) {
+	store, err := openStorage(ctx, getEnv("STORAGE", "opensearch"))
+	if err != nil {
+		return nil, err
+	}
+	return newCheckpoints(store)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"witness/auth"
+	"witness/legalhold"
+	"witness/models"
+)
+
+// runHold реализует `witness hold <create|update|release|list|get>`.
+func runHold(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: witness hold create|update|release|list|get [flags]")
+	}
+
+	store, err := openStorage(ctx, getEnv("STORAGE", "opensearch"))
+	if err != nil {
+		return err
+	}
+	recorder, flush, err := newCLIRecorder(store)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := flush(ctx); err != nil {
+			slog.Error("failed to store legal hold audit events", "error", err)
+		}
+	}()
+	holds := legalhold.NewService(store, recorder)
+	ctx = auth.WithPrincipal(ctx, cliPrincipal())
+
+	fs := flag.NewFlagSet("hold "+args[0], flag.ContinueOnError)
+	switch args[0] {
+	case "create":
+		reason := fs.String("reason", "", "reason for the hold (required)")
+		owner := fs.String("owner", "", "person responsible for the hold (required)")
+		actor := fs.String("actor", "", "hold events of this actor id")
+		entity := fs.String("entity", "", "hold events of this entity id")
+		from := fs.String("from", "", "hold events from this time (RFC 3339)")
+		to := fs.String("to", "", "hold events up to this time (RFC 3339)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		input := models.CreateLegalHoldInput{
+			Reason:   *reason,
+			Owner:    *owner,
+			ActorID:  optionalString(*actor),
+			EntityID: optionalString(*entity),
+		}
+		if input.From, err = optionalTime("from", *from); err != nil {
+			return err
+		}
+		if input.To, err = optionalTime("to", *to); err != nil {
+			return err
+		}
+
+		hold, err := holds.Create(ctx, input)
+		if err != nil {
+			return err
+		}
+		return printJSON(hold)
+
+	case "update":
+		id := fs.String("id", "", "hold id (required)")
+		reason := fs.String("reason", "", "new reason")
+		owner := fs.String("owner", "", "new owner")
+		actor := fs.String("actor", "", "new actor id condition")
+		entity := fs.String("entity", "", "new entity id condition")
+		from := fs.String("from", "", "new start of time range (RFC 3339)")
+		to := fs.String("to", "", "new end of time range (RFC 3339)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		// Меняем только явно переданные флаги, чтобы `--actor ""` снимал условие.
+		var input models.UpdateLegalHoldInput
+		var parseErr error
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "reason":
+				input.Reason = reason
+			case "owner":
+				input.Owner = owner
+			case "actor":
+				input.ActorID = actor
+			case "entity":
+				input.EntityID = entity
+			case "from":
+				if input.From, err = optionalTime("from", *from); err != nil {
+					parseErr = err
+				}
+			case "to":
+				if input.To, err = optionalTime("to", *to); err != nil {
+					parseErr = err
+				}
+			}
+		})
+		if parseErr != nil {
+			return parseErr
+		}
+
+		hold, err := holds.Update(ctx, *id, input)
+		if err != nil {
+			return err
+		}
+		return printJSON(hold)
+
+	case "release":
+		id := fs.String("id", "", "hold id (required)")
+		by := fs.String("by", "", "who releases the hold (required)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		hold, err := holds.Release(ctx, *id, *by)
+		if err != nil {
+			return err
+		}
+		return printJSON(hold)
+
+	case "list":
+		all := fs.Bool("all", false, "include released holds")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		list, err := holds.List(ctx, !*all)
+		if err != nil {
+			return err
+		}
+		return printJSON(list)
+
+	case "get":
+		id := fs.String("id", "", "hold id (required)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		hold, err := holds.Get(ctx, *id)
+		if err != nil {
+			return err
+		}
+		return printJSON(hold)
+
+	default:
+		return fmt.Errorf("unknown hold command %q", args[0])
+	}
+}
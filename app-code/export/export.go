@@ -0,0 +1,180 @@
+// Package export записывает события аудита в файл выгрузки: NDJSON (одно событие
+// JSON на строку) или CSV. Колонки задаются путями полей JSON события через точку:
+// "event_id", "actor.id", "details.amount"; для NDJSON без колонок событие пишется целиком.
+//
+// Jobs выполняет такие выгрузки в фоне и хранит готовые файлы на диске или в S3.
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"witness/models"
+)
+
+// Форматы выгрузки.
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// DefaultColumns - колонки CSV, если они не заданы.
+var DefaultColumns = []string{
+	"event_id", "timestamp", "event_type", "status",
+	"actor.id", "actor.type", "entity.id", "entity.type",
+	"context.source_service", "tenant",
+}
+
+// fields - поля верхнего уровня события, с которых может начинаться колонка.
+var fields = []string{
+	"event_id", "timestamp", "status", "event_type", "actor", "entity", "context",
+	"security", "details", "redactions", "signature_status", "tenant", "chain",
+}
+
+var (
+	ErrInvalidFormat = errors.New("invalid export format")
+	ErrInvalidColumn = errors.New("invalid export column")
+)
+
+// ContentType возвращает MIME-тип файла выгрузки в формате format.
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// Writer пишет события в выгрузку. Перед завершением нужно вызвать Flush.
+type Writer struct {
+	buf     *bufio.Writer
+	csv     *csv.Writer
+	columns []string
+	rows    int64
+}
+
+// NewWriter создает Writer формата format с колонками columns. Для CSV заголовок
+// с именами колонок пишется сразу, поэтому пустая выгрузка тоже содержит его.
+func NewWriter(w io.Writer, format string, columns []string) (*Writer, error) {
+	for _, column := range columns {
+		if !slices.Contains(fields, strings.SplitN(column, ".", 2)[0]) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidColumn, column)
+		}
+	}
+
+	out := &Writer{buf: bufio.NewWriterSize(w, 64*1024), columns: columns}
+	switch format {
+	case FormatNDJSON:
+	case FormatCSV:
+		if len(out.columns) == 0 {
+			out.columns = DefaultColumns
+		}
+		out.csv = csv.NewWriter(out.buf)
+		if err := out.csv.Write(out.columns); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q (expected %s or %s)", ErrInvalidFormat, format, FormatNDJSON, FormatCSV)
+	}
+	return out, nil
+}
+
+// Write добавляет событие в выгрузку.
+func (w *Writer) Write(event *models.AuditEvent) error {
+	if w.csv == nil && len(w.columns) == 0 {
+		if err := json.NewEncoder(w.buf).Encode(event); err != nil {
+			return err
+		}
+		w.rows++
+		return nil
+	}
+
+	// Пути колонок разрешаем по JSON события, чтобы имена совпадали с API.
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	// UseNumber: большие числа и числа без дробной части пишутся как есть, а не 1e+06.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+
+	if w.csv == nil {
+		row := make(map[string]any, len(w.columns))
+		for _, column := range w.columns {
+			row[column] = lookup(doc, column)
+		}
+		if err := json.NewEncoder(w.buf).Encode(row); err != nil {
+			return err
+		}
+		w.rows++
+		return nil
+	}
+
+	record := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		record[i], err = cell(lookup(doc, column))
+		if err != nil {
+			return err
+		}
+	}
+	if err := w.csv.Write(record); err != nil {
+		return err
+	}
+	w.rows++
+	return nil
+}
+
+// Flush дописывает буферизованные данные в исходный io.Writer.
+func (w *Writer) Flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return w.buf.Flush()
+}
+
+// Rows возвращает число записанных событий.
+func (w *Writer) Rows() int64 {
+	return w.rows
+}
+
+// lookup возвращает значение по пути через точку или nil, если его нет.
+func lookup(doc map[string]any, path string) any {
+	var v any = doc
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		if v, ok = m[key]; !ok {
+			return nil
+		}
+	}
+	return v
+}
+
+// cell переводит значение в ячейку CSV: объекты и массивы - в JSON, отсутствующее - в пустую строку.
+func cell(v any) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	case map[string]any, []any:
+		data, err := json.Marshal(val)
+		return string(data), err
+	default:
+		return fmt.Sprint(val), nil
+	}
+}
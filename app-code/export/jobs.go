@@ -0,0 +1,424 @@
+package export
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"witness/archive"
+	"witness/auth"
+	"witness/models"
+	"witness/querylog"
+	"witness/storage"
+
+	"github.com/google/uuid"
+)
+
+// progressInterval - как часто выполняющееся задание сохраняет число выгруженных событий.
+const progressInterval = 2 * time.Second
+
+// leaseTTL - на сколько задание закрепляется за экземпляром. Аренда продлевается
+// с каждым сохранением прогресса; задание экземпляра, который перестал его продлевать
+// (остановлен или упал), забирает другой экземпляр.
+const leaseTTL = time.Minute
+
+var (
+	ErrQueueFull   = errors.New("export queue is full")
+	ErrInvalidLink = errors.New("invalid or expired download link")
+)
+
+// JobsConfig - параметры фоновых выгрузок.
+type JobsConfig struct {
+	// Workers - сколько выгрузок выполняется одновременно.
+	Workers int
+	// TTL - сколько хранится готовый файл.
+	TTL time.Duration
+	// LinkTTL - сколько действует выданная ссылка на скачивание (не дольше TTL).
+	LinkTTL time.Duration
+	// LinkKey - ключ HMAC для подписи ссылок.
+	LinkKey []byte
+	// BaseURL - внешний адрес сервиса для ссылок; пустой - ссылки относительные.
+	BaseURL string
+	// MaskKeys - ключи details, скрываемые от субъектов без роли pii_reader.
+	MaskKeys []string
+}
+
+// Jobs выполняет выгрузки в фоне пулом воркеров и складывает файлы в target.
+// Состояние заданий хранится в jobs, общем для всех экземпляров Witness: задание
+// выполняет тот экземпляр, который забрал его (ClaimExportJob), а задания остановленных
+// экземпляров выполняются заново, когда истечет их аренда.
+type Jobs struct {
+	jobs storage.ExportJobStore
+	// instance - идентификатор этого экземпляра в аренде заданий.
+	instance string
+	events   storage.EventStore
+	target   archive.Target
+	queryLog *querylog.Recorder
+	cfg      JobsConfig
+	queue    chan string
+	now      func() time.Time
+}
+
+// NewJobs создает сервис выгрузок. events - хранилище для API (с ограничением арендатором
+// и расшифровкой PII): задание читает его с правами создавшего его субъекта. Готовые
+// и неудачные выгрузки записываются в журнал запросов queryLog (nil - журнал отключен).
+func NewJobs(jobs storage.ExportJobStore, events storage.EventStore, target archive.Target, queryLog *querylog.Recorder, cfg JobsConfig) *Jobs {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	return &Jobs{
+		jobs:     jobs,
+		instance: uuid.NewString(),
+		events:   events,
+		target:   target,
+		queryLog: queryLog,
+		cfg:      cfg,
+		queue:    make(chan string, 100),
+		now:      time.Now,
+	}
+}
+
+// Create ставит в очередь выгрузку событий, подходящих под фильтр, от имени субъекта из ctx.
+func (s *Jobs) Create(ctx context.Context, filter *models.AuditEventFilter, format string, columns []string, compress bool) (*models.ExportJob, error) {
+	// Формат и колонки проверяем сразу, а не в воркере.
+	if _, err := NewWriter(io.Discard, format, columns); err != nil {
+		return nil, err
+	}
+
+	job := &models.ExportJob{
+		ID:        uuid.NewString(),
+		Status:    models.ExportPending,
+		Format:    format,
+		Filter:    filter,
+		Columns:   columns,
+		Gzip:      compress,
+		CreatedBy: "anonymous",
+		CreatedAt: s.now().UTC(),
+	}
+	if p := auth.FromContext(ctx); p != nil {
+		job.CreatedBy, job.Roles, job.Tenant = p.Subject, p.Roles, p.Tenant
+	}
+	if err := s.jobs.SaveExportJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.queue <- job.ID:
+		return job, nil
+	default:
+	}
+	job.Status, job.Error = models.ExportFailed, ErrQueueFull.Error()
+	if err := s.jobs.SaveExportJob(ctx, job); err != nil {
+		slog.Error("failed to save export job", "job_id", job.ID, "error", err)
+	}
+	return nil, ErrQueueFull
+}
+
+// Get возвращает задание, если его создал субъект из ctx или субъект - администратор
+// арендатора задания (или администратор без арендатора), иначе ErrNotFound.
+func (s *Jobs) Get(ctx context.Context, id string) (*models.ExportJob, error) {
+	job, err := s.jobs.GetExportJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	p := auth.FromContext(ctx)
+	if p.IsGlobalAdmin() || (p != nil && p.Tenant == job.Tenant && (p.Subject == job.CreatedBy || p.HasRole(auth.RoleAdmin))) {
+		return job, nil
+	}
+	return nil, storage.ErrNotFound
+}
+
+// DownloadURL возвращает подписанную ссылку на файл готового задания, действующую
+// LinkTTL, но не дольше хранения файла. Для остальных заданий возвращает пустую строку.
+func (s *Jobs) DownloadURL(job *models.ExportJob) string {
+	now := s.now()
+	if job.Status != models.ExportSucceeded || job.ExpiresAt == nil || !now.Before(*job.ExpiresAt) {
+		return ""
+	}
+	expires := now.Add(s.cfg.LinkTTL)
+	if expires.After(*job.ExpiresAt) {
+		expires = *job.ExpiresAt
+	}
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	q := url.Values{"expires": {exp}, "signature": {s.sign(job.ID, exp)}}
+	return s.cfg.BaseURL + "/exports/" + url.PathEscape(job.ID) + "/download?" + q.Encode()
+}
+
+// Open проверяет подпись и срок ссылки и открывает файл задания.
+func (s *Jobs) Open(ctx context.Context, id, expires, signature string) (*models.ExportJob, io.ReadCloser, error) {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(s.sign(id, expires))) || s.now().Unix() >= exp {
+		return nil, nil, ErrInvalidLink
+	}
+	job, err := s.jobs.GetExportJob(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrInvalidLink
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status != models.ExportSucceeded {
+		return nil, nil, ErrInvalidLink
+	}
+	r, err := s.target.Get(ctx, job.Key)
+	if errors.Is(err, archive.ErrNotExist) {
+		return nil, nil, ErrInvalidLink
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return job, r, nil
+}
+
+// sign возвращает подпись ссылки на файл задания id, действующей до expires (unix).
+func (s *Jobs) sign(id, expires string) string {
+	mac := hmac.New(sha256.New, s.cfg.LinkKey)
+	mac.Write([]byte(id + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run запускает воркеры и удаление файлов с истекшим сроком до отмены ctx.
+// Задания, не завершенные до остановки, возобновляются при следующем запуске.
+func (s *Jobs) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range s.cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-s.queue:
+					s.run(ctx, id)
+				}
+			}
+		}()
+	}
+
+	s.resume(ctx)
+	s.expire(ctx)
+	resume := time.NewTicker(leaseTTL)
+	defer resume.Stop()
+	ticker := time.NewTicker(min(s.cfg.TTL, time.Hour))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-resume.C:
+			s.resume(ctx)
+		case <-ticker.C:
+			s.expire(ctx)
+		}
+	}
+}
+
+// resume ставит в очередь задания остановленных экземпляров: выполняющиеся с истекшей
+// арендой и ожидающие дольше leaseTTL (экземпляр, поставивший их в свою очередь, не успел
+// их забрать). Задания, которые выполняет или вот-вот заберет живой экземпляр, не трогает;
+// если два экземпляра все же поставят задание в очередь, выполнит его только забравший.
+func (s *Jobs) resume(ctx context.Context) {
+	jobs, err := s.jobs.ListExportJobs(ctx, true)
+	if err != nil {
+		slog.Error("failed to list unfinished export jobs", "error", err)
+		return
+	}
+	now := s.now()
+	// Старые задания первыми.
+	for i := len(jobs) - 1; i >= 0; i-- {
+		job := jobs[i]
+		if !storage.Claimable(job, now) || (job.Status == models.ExportPending && now.Sub(job.CreatedAt) < leaseTTL) {
+			continue
+		}
+		select {
+		case s.queue <- job.ID:
+			slog.Info("resuming export job", "job_id", job.ID, "status", job.Status, "owner", job.Owner)
+		default:
+			// Очередь занята: задание останется незабранным до следующей проверки.
+			return
+		}
+	}
+}
+
+// expire удаляет файлы заданий с истекшим сроком хранения.
+func (s *Jobs) expire(ctx context.Context) {
+	jobs, err := s.jobs.ListExportJobs(ctx, false)
+	if err != nil {
+		slog.Error("failed to list export jobs", "error", err)
+		return
+	}
+	now := s.now()
+	for _, job := range jobs {
+		if job.Status != models.ExportSucceeded || job.ExpiresAt == nil || now.Before(*job.ExpiresAt) {
+			continue
+		}
+		if err := s.target.Delete(ctx, job.Key); err != nil {
+			slog.Error("failed to delete expired export", "job_id", job.ID, "key", job.Key, "error", err)
+			continue
+		}
+		job.Status = models.ExportExpired
+		if err := s.jobs.SaveExportJob(ctx, job); err != nil {
+			slog.Error("failed to save export job", "job_id", job.ID, "error", err)
+			continue
+		}
+		slog.Info("export expired", "job_id", job.ID)
+	}
+}
+
+// run забирает и выполняет задание id. Задание, которое уже выполняет другой экземпляр
+// или которое завершено, пропускается. Если ctx отменен посреди выгрузки, задание
+// остается RUNNING и будет выполнено заново, когда истечет его аренда.
+func (s *Jobs) run(ctx context.Context, id string) {
+	started := s.now().UTC()
+	job, err := s.jobs.ClaimExportJob(ctx, id, s.instance, started, started.Add(leaseTTL))
+	if errors.Is(err, storage.ErrClaimed) {
+		slog.Debug("export job is claimed by another instance", "job_id", id)
+		return
+	}
+	if err != nil {
+		slog.Error("failed to claim export job", "job_id", id, "error", err)
+		return
+	}
+	job.StartedAt, job.Rows, job.Total, job.Error = &started, 0, 0, ""
+	if err := s.jobs.SaveExportJob(ctx, job); err != nil {
+		slog.Error("failed to save export job", "job_id", id, "error", err)
+		return
+	}
+	slog.Info("export started", "job_id", id, "format", job.Format)
+
+	// Выгрузка идет с правами создателя: арендатор и роли из задания.
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: job.CreatedBy, Roles: job.Roles, Tenant: job.Tenant})
+	err = s.write(ctx, job)
+	if ctx.Err() != nil {
+		return
+	}
+
+	finished := s.now().UTC()
+	job.FinishedAt = &finished
+	if err != nil {
+		job.Status, job.Error = models.ExportFailed, err.Error()
+		slog.Error("export failed", "job_id", id, "rows", job.Rows, "error", err)
+	} else {
+		expires := finished.Add(s.cfg.TTL)
+		job.Status, job.ExpiresAt = models.ExportSucceeded, &expires
+		slog.Info("export finished", "job_id", id, "rows", job.Rows, "size", job.Size)
+	}
+	if err := s.jobs.SaveExportJob(ctx, job); err != nil {
+		slog.Error("failed to save export job", "job_id", id, "error", err)
+	}
+
+	details := map[string]any{
+		"job_id":      job.ID,
+		"format":      job.Format,
+		"columns":     job.Columns,
+		"gzip":        job.Gzip,
+		"rows":        job.Rows,
+		"size":        job.Size,
+		"checksum":    job.Checksum,
+		"duration_ms": finished.Sub(started).Milliseconds(),
+	}
+	if job.Filter != nil {
+		details["filter"] = job.Filter
+	}
+	if job.Error != "" {
+		details["error"] = job.Error
+	}
+	s.queryLog.Record(ctx, querylog.ExportEventType, models.Entity{ID: job.ID, Type: "EXPORT_JOB", Name: job.Key}, err == nil, details)
+}
+
+// write выгружает события задания во временный файл, считая размер и SHA-256,
+// и переносит готовый файл в хранилище выгрузок.
+func (s *Jobs) write(ctx context.Context, job *models.ExportJob) error {
+	_, total, err := s.events.Search(ctx, storage.Query{Filter: job.Filter, Limit: 1})
+	if err != nil {
+		return err
+	}
+	job.Total = total
+
+	tmp, err := os.CreateTemp("", "witness-export-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(tmp, hash)}
+	var out io.Writer = counter
+	var zw *gzip.Writer
+	if job.Gzip {
+		zw = gzip.NewWriter(counter)
+		out = zw
+	}
+	w, err := NewWriter(out, job.Format, job.Columns)
+	if err != nil {
+		return err
+	}
+
+	saved := s.now()
+	err = s.events.Scan(ctx, job.Filter, func(event *models.AuditEvent) error {
+		if err := w.Write(View(ctx, event, s.cfg.MaskKeys)); err != nil {
+			return err
+		}
+		if now := s.now(); now.Sub(saved) >= progressInterval {
+			saved = now
+			lease := now.UTC().Add(leaseTTL)
+			job.Rows, job.LeaseUntil = w.Rows(), &lease
+			if err := s.jobs.SaveExportJob(ctx, job); err != nil {
+				slog.Warn("failed to save export progress", "job_id", job.ID, "error", err)
+			}
+		}
+		return nil
+	})
+	job.Rows = w.Rows()
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind temp file: %w", err)
+	}
+
+	key := job.ID + "." + job.Format
+	if job.Gzip {
+		key += ".gz"
+	}
+	if err := s.target.Put(ctx, key, tmp, counter.n); err != nil {
+		return err
+	}
+	job.Key, job.Size, job.Checksum = key, counter.n, hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// countingWriter считает записанные байты.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
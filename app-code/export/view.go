@@ -0,0 +1,19 @@
+package export
+
+import (
+	"context"
+	"witness/auth"
+	"witness/models"
+	"witness/redaction"
+)
+
+// View возвращает копию события для отдачи вне GraphQL: без шифротекстов PII, а субъекту
+// без роли pii_reader - со скрытыми IP актора и ключами details maskKeys, как в GraphQL (@mask).
+func View(ctx context.Context, event *models.AuditEvent, maskKeys []string) *models.AuditEvent {
+	e := *event
+	if !auth.FromContext(ctx).HasRole(auth.RolePIIReader) {
+		e = *redaction.MaskEvent(event, maskKeys)
+	}
+	e.PII = nil
+	return &e
+}
@@ -0,0 +1,34 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.81
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"witness/models"
+	"witness/storage"
+)
+
+// Checkpoint is the resolver for the checkpoint field.
+func (r *queryResolver) Checkpoint(ctx context.Context, eventID string) (*models.InclusionProof, error) {
+	// Доказательство содержит событие целиком, поэтому сначала проверяем, что оно доступно субъекту.
+	if _, err := r.Store.Get(ctx, eventID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	proof, err := r.Checkpoints.Prove(ctx, eventID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("failed to build inclusion proof", "event_id", eventID, "error", err)
+		return nil, fmt.Errorf("failed to build inclusion proof: %w", err)
+	}
+	return proof, nil
+}
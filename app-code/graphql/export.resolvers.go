@@ -0,0 +1,40 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.81
+
+import (
+	"context"
+	"errors"
+	"witness/graphql/generated"
+	"witness/models"
+	"witness/storage"
+)
+
+// DownloadURL is the resolver for the download_url field.
+func (r *exportJobResolver) DownloadURL(ctx context.Context, obj *models.ExportJob) (*string, error) {
+	if link := r.Exports.DownloadURL(obj); link != "" {
+		return &link, nil
+	}
+	return nil, nil
+}
+
+// CreateExport is the resolver for the createExport field.
+func (r *mutationResolver) CreateExport(ctx context.Context, filter *models.AuditEventFilter, format string, columns []string, gzip *bool) (*models.ExportJob, error) {
+	return r.Exports.Create(ctx, filter, format, columns, gzip != nil && *gzip)
+}
+
+// Export is the resolver for the export field.
+func (r *queryResolver) Export(ctx context.Context, id string) (*models.ExportJob, error) {
+	job, err := r.Exports.Get(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	return job, err
+}
+
+// ExportJob returns generated.ExportJobResolver implementation.
+func (r *Resolver) ExportJob() generated.ExportJobResolver { return &exportJobResolver{r} }
+
+type exportJobResolver struct{ *Resolver }
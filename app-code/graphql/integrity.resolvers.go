@@ -0,0 +1,26 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.81
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	"witness/models"
+)
+
+// VerifyIntegrity is the resolver for the verifyIntegrity field.
+func (r *queryResolver) VerifyIntegrity(ctx context.Context, from time.Time, to time.Time) (*models.IntegrityReport, error) {
+	if err := requireGlobalAdmin(ctx); err != nil {
+		return nil, err
+	}
+	report, err := r.Integrity.Verify(ctx, from, to)
+	if err != nil {
+		slog.Error("failed to verify integrity", "error", err)
+		return nil, fmt.Errorf("failed to verify integrity: %w", err)
+	}
+	return report, nil
+}
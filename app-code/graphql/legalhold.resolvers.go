@@ -0,0 +1,66 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.81
+
+import (
+	"context"
+	"errors"
+	"witness/graphql/generated"
+	"witness/models"
+	"witness/storage"
+)
+
+// CreateLegalHold is the resolver for the createLegalHold field.
+func (r *mutationResolver) CreateLegalHold(ctx context.Context, input models.CreateLegalHoldInput) (*models.LegalHold, error) {
+	if err := requireGlobalAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return r.Holds.Create(ctx, input)
+}
+
+// UpdateLegalHold is the resolver for the updateLegalHold field.
+func (r *mutationResolver) UpdateLegalHold(ctx context.Context, id string, input models.UpdateLegalHoldInput) (*models.LegalHold, error) {
+	if err := requireGlobalAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return r.Holds.Update(ctx, id, input)
+}
+
+// ReleaseLegalHold is the resolver for the releaseLegalHold field.
+func (r *mutationResolver) ReleaseLegalHold(ctx context.Context, id string, releasedBy string) (*models.LegalHold, error) {
+	if err := requireGlobalAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return r.Holds.Release(ctx, id, releasedBy)
+}
+
+// LegalHolds is the resolver for the legalHolds field.
+func (r *queryResolver) LegalHolds(ctx context.Context, activeOnly *bool) ([]*models.LegalHold, error) {
+	if err := requireGlobalAdmin(ctx); err != nil {
+		return nil, err
+	}
+	active := true
+	if activeOnly != nil {
+		active = *activeOnly
+	}
+	return r.Holds.List(ctx, active)
+}
+
+// LegalHold is the resolver for the legalHold field.
+func (r *queryResolver) LegalHold(ctx context.Context, id string) (*models.LegalHold, error) {
+	if err := requireGlobalAdmin(ctx); err != nil {
+		return nil, err
+	}
+	hold, err := r.Holds.Get(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	return hold, err
+}
+
+// Mutation returns generated.MutationResolver implementation.
+func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
+
+type mutationResolver struct{ *Resolver }
@@ -0,0 +1,25 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.81
+
+import (
+	"context"
+	"errors"
+	"witness/graphql/generated"
+	"witness/models"
+)
+
+// EventStream is the resolver for the eventStream field.
+func (r *subscriptionResolver) EventStream(ctx context.Context, filter *models.AuditEventFilter) (<-chan *models.AuditEvent, error) {
+	if r.Live == nil {
+		return nil, errors.New("live stream is disabled")
+	}
+	return r.Live.Subscribe(ctx, filter)
+}
+
+// Subscription returns generated.SubscriptionResolver implementation.
+func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
+
+type subscriptionResolver struct{ *Resolver }
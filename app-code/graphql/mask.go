@@ -0,0 +1,68 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"witness/auth"
+	"witness/redaction"
+
+	gql "github.com/99designs/gqlgen/graphql"
+)
+
+// Mask возвращает реализацию директивы @mask. Проверка выполняется на сервере
+// после резолвера поля, поэтому клиент не может ее обойти. detailKeys дополняют
+// keys директивы (настраиваются через MASK_DETAILS_KEYS).
+func Mask(detailKeys []string) func(ctx context.Context, obj any, next gql.Resolver, role string, keys []string) (any, error) {
+	return func(ctx context.Context, obj any, next gql.Resolver, role string, keys []string) (any, error) {
+		res, err := next(ctx)
+		if err != nil || auth.FromContext(ctx).HasRole(role) {
+			return res, err
+		}
+
+		if keys != nil {
+			return maskJSON(res, append(slices.Clone(keys), detailKeys...))
+		}
+
+		field := gql.GetFieldContext(ctx).Field.Definition
+		if !field.Type.NonNull {
+			return nil, nil
+		}
+		if name := field.Type.NamedType; name != "String" && name != "ID" {
+			return nil, fmt.Errorf("@mask: non-null field %s of type %s cannot be masked", field.Name, field.Type)
+		}
+		return redaction.Mask, nil
+	}
+}
+
+// maskJSON заменяет значения ключей keys на любой глубине в поле с JSON-объектом.
+// Если значение не удалось разобрать, оно скрывается целиком.
+func maskJSON(res any, keys []string) (any, error) {
+	var raw string
+	switch v := res.(type) {
+	case *string:
+		if v == nil {
+			return v, nil
+		}
+		raw = *v
+	case string:
+		raw = v
+	default:
+		return nil, nil
+	}
+
+	var doc any
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(redaction.MaskKeys(doc, keys))
+	if err != nil {
+		return nil, fmt.Errorf("@mask: failed to marshal masked value: %w", err)
+	}
+	masked := string(data)
+	if _, ok := res.(string); ok {
+		return masked, nil
+	}
+	return &masked, nil
+}
@@ -0,0 +1,18 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.81
+
+import (
+	"context"
+	"witness/models"
+)
+
+// ForgetActor is the resolver for the forgetActor field.
+func (r *mutationResolver) ForgetActor(ctx context.Context, actorID string, requestedBy string) (*models.ForgetActorResult, error) {
+	if err := requireGlobalAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return r.Shredder.Forget(ctx, actorID, requestedBy)
+}
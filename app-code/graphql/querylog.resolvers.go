@@ -0,0 +1,42 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.81
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"witness/models"
+	"witness/querylog"
+	"witness/storage"
+)
+
+// QueryLog is the resolver for the queryLog field.
+func (r *queryResolver) QueryLog(ctx context.Context, filter *models.AuditEventFilter, limit *int, offset *int) (*models.AuditEventConnection, error) {
+	if r.Queries == nil {
+		return nil, errors.New("query log is disabled")
+	}
+	l := 20
+	if limit != nil {
+		l = *limit
+	}
+	o := 0
+	if offset != nil {
+		o = *offset
+	}
+
+	events, total, err := r.Queries.Search(ctx, storage.Query{Filter: filter, Limit: l, Offset: o})
+	if err != nil {
+		slog.Error("failed to search query log", "error", err)
+		return nil, fmt.Errorf("failed to search query log: %w", err)
+	}
+	querylog.AddResults(ctx, len(events))
+
+	return &models.AuditEventConnection{
+		Events: events,
+		Total:  int(total),
+	}, nil
+}
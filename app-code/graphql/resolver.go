@@ -6,14 +6,29 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"witness/checkpoint"
+	"witness/export"
 	"witness/graphql/generated"
+	"witness/integrity"
+	"witness/legalhold"
+	"witness/live"
 	"witness/models"
-	"witness/opensearch"
+	"witness/pii"
+	"witness/querylog"
+	"witness/storage"
 )
 
 // Resolver - корневой резолвер.
 type Resolver struct {
-	OSClient *opensearch.Client
+	Store       storage.EventStore
+	Holds       *legalhold.Service
+	Integrity   *integrity.Verifier
+	Checkpoints *checkpoint.Service
+	Shredder    *pii.Shredder
+	// Queries - журнал запросов к API; nil, если журнал выключен.
+	Queries storage.EventStore
+	Live    *live.Feed
+	Exports *export.Jobs
 }
 
 // Query возвращает QueryResolver.
@@ -22,27 +37,6 @@ func (r *Resolver) Query() generated.QueryResolver {
 }
 
 func (r *queryResolver) SearchEvents(ctx context.Context, filter *models.AuditEventFilter, limit *int, offset *int) (*models.AuditEventConnection, error) {
-	filterMap := make(map[string]interface{})
-	// TODO: Реализовать логику конвертации filter *generated.AuditEventFilter в filterMap
-	// Например:
-	if filter != nil {
-		if filter.Status != nil {
-			filterMap["status"] = *filter.Status
-		}
-		if filter.EventType != nil {
-			filterMap["event_type"] = *filter.EventType
-		}
-		if filter.ActorID != nil {
-			filterMap["actor.id"] = *filter.ActorID
-		}
-		if filter.EntityID != nil {
-			filterMap["entity.id"] = *filter.EntityID
-		}
-		if filter.SecurityAccessLevel != nil {
-			filterMap["security.access_level"] = *filter.SecurityAccessLevel
-		}
-	}
-
 	l := 20
 	if limit != nil {
 		l = *limit
@@ -52,15 +46,15 @@ func (r *queryResolver) SearchEvents(ctx context.Context, filter *models.AuditEv
 		o = *offset
 	}
 
-	// Вызываем OpenSearch для получения событий.
-	events, total, err := r.OSClient.SearchEvents(ctx, filterMap, l, o)
+	events, total, err := r.Store.Search(ctx, storage.Query{Filter: filter, Limit: l, Offset: o})
 	if err != nil {
-		slog.Error("failed to search events in OpenSearch", "error", err)
+		slog.Error("failed to search events in storage", "error", err)
 		return nil, fmt.Errorf("failed to search events: %w", err)
 	}
+	querylog.AddResults(ctx, len(events))
 
 	return &models.AuditEventConnection{
-		Events: events, 
+		Events: events,
 		Total:  int(total),
 	}, nil
 }
@@ -0,0 +1,33 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"witness/auth"
+
+	gql "github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// HasRole - реализация директивы @hasRole: поле разрешается только субъекту с ролью role.
+func HasRole(ctx context.Context, obj any, next gql.Resolver, role string) (any, error) {
+	if !auth.FromContext(ctx).HasRole(role) {
+		return nil, &gqlerror.Error{
+			Message:    fmt.Sprintf("forbidden: role %s is required", role),
+			Extensions: map[string]any{"code": "FORBIDDEN"},
+		}
+	}
+	return next(ctx)
+}
+
+// requireGlobalAdmin разрешает операцию над всем хранилищем только администратору без
+// арендатора: удержания, проверка цепочек и забвение не разделены по арендаторам.
+func requireGlobalAdmin(ctx context.Context) error {
+	if !auth.FromContext(ctx).IsGlobalAdmin() {
+		return &gqlerror.Error{
+			Message:    "forbidden: operation is not available to tenant-scoped principals",
+			Extensions: map[string]any{"code": "FORBIDDEN"},
+		}
+	}
+	return nil
+}
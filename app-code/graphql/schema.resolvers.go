@@ -6,14 +6,53 @@ package graphql
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"witness/graphql/generated"
 	"witness/models"
+	"witness/querylog"
+	"witness/storage"
 )
 
 // Details is the resolver for the details field.
 func (r *auditEventResolver) Details(ctx context.Context, obj *models.AuditEvent) (*string, error) {
-	panic(fmt.Errorf("not implemented: Details - details"))
+	if len(obj.Details) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(obj.Details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal details of %s: %w", obj.EventID, err)
+	}
+	details := string(data)
+	return &details, nil
+}
+
+// Event is the resolver for the event field.
+func (r *queryResolver) Event(ctx context.Context, id string) (*models.AuditEvent, error) {
+	event, err := r.Store.Get(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	querylog.AddResults(ctx, 1)
+	return event, nil
+}
+
+// EventFacets is the resolver for the eventFacets field.
+func (r *queryResolver) EventFacets(ctx context.Context, field string, filter *models.AuditEventFilter, size *int) ([]*models.FacetBucket, error) {
+	n := 10
+	if size != nil {
+		n = *size
+	}
+	buckets, err := r.Store.Aggregate(ctx, filter, field, n)
+	if err != nil {
+		return nil, err
+	}
+	querylog.AddResults(ctx, len(buckets))
+	return buckets, nil
 }
 
 // AuditEvent returns generated.AuditEventResolver implementation.
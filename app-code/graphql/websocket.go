@@ -0,0 +1,33 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"witness/auth"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+)
+
+// WebsocketInit проверяет субъекта подключения WebSocket. Если он не определен при
+// открытии соединения (заголовком Authorization или анонимным режимом), токен берется
+// из payload сообщения connection_init: {"Authorization": "Bearer <token>"}.
+// authenticator == nil означает, что аутентификация выключена.
+func WebsocketInit(authenticator auth.Authenticator) transport.WebsocketInitFunc {
+	return func(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		if auth.FromContext(ctx) != nil {
+			return ctx, nil, nil
+		}
+		token, ok := auth.BearerToken(payload.Authorization())
+		if !ok || authenticator == nil {
+			return nil, nil, errors.New("unauthenticated: missing bearer token")
+		}
+		principal, err := authenticator.Authenticate(ctx, token)
+		if errors.Is(err, auth.ErrUnauthenticated) {
+			return nil, nil, errors.New("unauthenticated: invalid token")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return auth.WithPrincipal(ctx, principal), nil, nil
+	}
+}
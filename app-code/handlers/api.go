@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"witness/auth"
+	"witness/export"
+	"witness/models"
+	"witness/pii"
+	"witness/querylog"
+	"witness/storage"
+	"witness/tenant"
+
+	"github.com/labstack/echo/v4"
+)
+
+// openAPISpec - описание REST API в формате OpenAPI 3 (GET /api/v1/openapi.json).
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// API - REST-аналог запросов GraphQL searchEvents, event и eventFacets для клиентов,
+// которые не умеют GraphQL. Хранилище, фильтр и роли - те же.
+type API struct {
+	store    storage.EventStore
+	queryLog *querylog.Recorder
+	// maskKeys - ключи details, скрываемые от субъектов без роли pii_reader.
+	maskKeys []string
+}
+
+// NewAPI создает обработчики REST API. store - хранилище для API (с ограничением
+// арендатором и расшифровкой PII); запросы записываются в журнал queryLog
+// событиями AUDIT_LOG_QUERY, как операции GraphQL (nil - журнал отключен).
+func NewAPI(store storage.EventStore, queryLog *querylog.Recorder, maskKeys []string) *API {
+	return &API{store: store, queryLog: queryLog, maskKeys: maskKeys}
+}
+
+// Register добавляет маршруты API в группу (например, /api/v1). Спецификация
+// OpenAPI отдается без аутентификации, остальные маршруты проходят через mw.
+func (a *API) Register(g *echo.Group, mw ...echo.MiddlewareFunc) {
+	g.GET("/openapi.json", func(c echo.Context) error {
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, openAPISpec)
+	})
+	g.GET("/events", a.searchEvents, mw...)
+	g.GET("/events/:id", a.getEvent, mw...)
+	g.GET("/facets", a.facets, mw...)
+}
+
+// eventsResponse - ответ GET /events, как AuditEventConnection в GraphQL.
+type eventsResponse struct {
+	Events []*models.AuditEvent `json:"events"`
+	Total  int64                `json:"total"`
+}
+
+// facetsResponse - ответ GET /facets.
+type facetsResponse struct {
+	Field   string                `json:"field"`
+	Buckets []*models.FacetBucket `json:"buckets"`
+}
+
+func (a *API) searchEvents(c echo.Context) error {
+	if err := requireRole(c, models.ScopeEventsRead); err != nil {
+		return err
+	}
+	filter, err := filterFromQuery(c)
+	if err != nil {
+		return err
+	}
+	limit, err := intParam(c, "limit", 20)
+	if err != nil {
+		return err
+	}
+	offset, err := intParam(c, "offset", 0)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	ctx := c.Request().Context()
+	events, total, err := a.store.Search(ctx, storage.Query{Filter: filter, Limit: limit, Offset: offset})
+	a.record(c, len(events), start, err)
+	if err != nil {
+		return storeError(err)
+	}
+	for i, event := range events {
+		events[i] = export.View(ctx, event, a.maskKeys)
+	}
+	return c.JSON(http.StatusOK, eventsResponse{Events: events, Total: total})
+}
+
+func (a *API) getEvent(c echo.Context) error {
+	if err := requireRole(c, models.ScopeEventsRead); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	ctx := c.Request().Context()
+	event, err := a.store.Get(ctx, c.Param("id"))
+	if errors.Is(err, storage.ErrNotFound) {
+		a.record(c, 0, start, nil)
+		return echo.NewHTTPError(http.StatusNotFound, "event not found")
+	}
+	a.record(c, 1, start, err)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, export.View(ctx, event, a.maskKeys))
+}
+
+func (a *API) facets(c echo.Context) error {
+	if err := requireRole(c, models.ScopeAggregatesRead); err != nil {
+		return err
+	}
+	field := c.QueryParam("field")
+	if err := storage.ValidateFacetField(field); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	filter, err := filterFromQuery(c)
+	if err != nil {
+		return err
+	}
+	size, err := intParam(c, "size", 10)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	buckets, err := a.store.Aggregate(c.Request().Context(), filter, field, size)
+	a.record(c, len(buckets), start, err)
+	if err != nil {
+		return storeError(err)
+	}
+	return c.JSON(http.StatusOK, facetsResponse{Field: field, Buckets: buckets})
+}
+
+// record записывает запрос в журнал запросов с теми же полями details, что у операций GraphQL.
+func (a *API) record(c echo.Context, results int, start time.Time, err error) {
+	req := c.Request()
+	operation := req.Method + " " + c.Path()
+	details := map[string]any{
+		"operation":      operation,
+		"operation_type": "rest",
+		"result_count":   results,
+		"latency_ms":     time.Since(start).Milliseconds(),
+	}
+	if params := req.URL.Query(); len(params) > 0 {
+		details["variables"] = params
+	}
+	if id := c.Param("id"); id != "" {
+		details["id"] = id
+	}
+	if err != nil {
+		details["errors"] = 1
+	}
+	entity := models.Entity{ID: operation, Type: "REST_REQUEST", Name: operation}
+	a.queryLog.Record(req.Context(), querylog.EventType, entity, err == nil, details)
+}
+
+// filterFromQuery собирает AuditEventFilter из параметров запроса с теми же именами,
+// что у полей фильтра в GraphQL; from и to - в формате RFC 3339.
+func filterFromQuery(c echo.Context) (*models.AuditEventFilter, error) {
+	filter := &models.AuditEventFilter{}
+	set := false
+	for name, field := range map[string]**string{
+		"status":              &filter.Status,
+		"eventType":           &filter.EventType,
+		"actorId":             &filter.ActorID,
+		"entityId":            &filter.EntityID,
+		"securityAccessLevel": &filter.SecurityAccessLevel,
+		"signatureStatus":     &filter.SignatureStatus,
+		"name":                &filter.Name,
+	} {
+		if value := c.QueryParam(name); value != "" {
+			*field, set = &value, true
+		}
+	}
+	for name, field := range map[string]**time.Time{
+		"from": &filter.From,
+		"to":   &filter.To,
+	} {
+		value := c.QueryParam(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid %s: %v", name, err))
+		}
+		*field, set = &t, true
+	}
+	if !set {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+// intParam разбирает неотрицательный целый параметр запроса.
+func intParam(c echo.Context, name string, fallback int) (int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid %s: expected a non-negative integer", name))
+	}
+	return n, nil
+}
+
+// requireRole отвечает 403, если у субъекта запроса нет роли, как @hasRole в GraphQL.
+func requireRole(c echo.Context, role string) error {
+	if !auth.FromContext(c.Request().Context()).HasRole(role) {
+		return echo.NewHTTPError(http.StatusForbidden, "forbidden: role "+role+" is required")
+	}
+	return nil
+}
+
+// storeError переводит ошибку хранилища в ответ: субъект без арендатора получает 403,
+// фильтр по имени при шифровании PII - 400.
+func storeError(err error) error {
+	switch {
+	case errors.Is(err, tenant.ErrNoTenant):
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	case errors.Is(err, pii.ErrNameFilter):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return err
+}
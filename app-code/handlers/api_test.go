@@ -0,0 +1,360 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"witness/auth"
+	"witness/handlers"
+	"witness/models"
+	"witness/storage/memory"
+	"witness/tenant"
+
+	"github.com/labstack/echo/v4"
+)
+
+// spec - документ OpenAPI, разобранный в map для обхода по $ref.
+type spec map[string]any
+
+func loadSpec(t *testing.T) spec {
+	t.Helper()
+	data, err := os.ReadFile("openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc spec
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	return doc
+}
+
+// resolve раскрывает ссылку $ref вида #/components/...; объект без $ref возвращается как есть.
+func (s spec) resolve(t *testing.T, obj map[string]any) map[string]any {
+	t.Helper()
+	for {
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			return obj
+		}
+		var node any = map[string]any(s)
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, _ := node.(map[string]any)
+			node = m[part]
+		}
+		if obj, ok = node.(map[string]any); !ok {
+			t.Fatalf("unresolved $ref %s", ref)
+		}
+	}
+}
+
+func (s spec) object(t *testing.T, obj map[string]any, keys ...string) map[string]any {
+	t.Helper()
+	for _, key := range keys {
+		next, ok := obj[key].(map[string]any)
+		if !ok {
+			t.Fatalf("openapi.json: no %s", strings.Join(keys, "."))
+		}
+		obj = s.resolve(t, next)
+	}
+	return obj
+}
+
+// responseSchema возвращает схему JSON-ответа операции с кодом status.
+func (s spec) responseSchema(t *testing.T, path, method, status string) map[string]any {
+	t.Helper()
+	op := s.object(t, map[string]any(s), "paths", path, strings.ToLower(method))
+	if _, ok := op["responses"].(map[string]any)[status]; !ok {
+		t.Fatalf("%s %s: status %s is not documented", method, path, status)
+	}
+	return s.object(t, op, "responses", status, "content", echo.MIMEApplicationJSON, "schema")
+}
+
+// validate проверяет значение по подмножеству JSON Schema, которое использует openapi.json:
+// type, required, properties, additionalProperties, items, enum, nullable и format date-time.
+// Свойства, не описанные в схеме объекта, считаются ошибкой: так заметно расхождение
+// документа с моделями.
+func (s spec) validate(t *testing.T, schema map[string]any, value any, at string) []string {
+	t.Helper()
+	schema = s.resolve(t, schema)
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return nil
+		}
+		return []string{at + ": null"}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		return []string{fmt.Sprintf("%s: %v is not one of %v", at, value, enum)}
+	}
+
+	var errs []string
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: got %T, want object", at, value)}
+		}
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing required %s", at, name))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		additional, _ := schema["additionalProperties"].(bool)
+		for name, v := range obj {
+			prop, ok := properties[name].(map[string]any)
+			if !ok {
+				if properties != nil && !additional {
+					errs = append(errs, fmt.Sprintf("%s: undocumented property %s", at, name))
+				}
+				continue
+			}
+			errs = append(errs, s.validate(t, prop, v, at+"."+name)...)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: got %T, want array", at, value)}
+		}
+		itemSchema, _ := schema["items"].(map[string]any)
+		for i, item := range items {
+			errs = append(errs, s.validate(t, itemSchema, item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: got %T, want string", at, value)}
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", at, err))
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			return []string{fmt.Sprintf("%s: got %v, want integer", at, value)}
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return []string{fmt.Sprintf("%s: got %T, want number", at, value)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: got %T, want boolean", at, value)}
+		}
+	}
+	return errs
+}
+
+// tokens - субъекты, которых тестовый способ аутентификации выдает по токену.
+var tokens = map[string]*auth.Principal{
+	"reader":  {Subject: "reader", Roles: []string{models.ScopeEventsRead, models.ScopeAggregatesRead}, Tenant: "acme"},
+	"pii":     {Subject: "dpo", Roles: []string{models.ScopeEventsRead, auth.RolePIIReader}, Tenant: "acme"},
+	"facets":  {Subject: "analyst", Roles: []string{models.ScopeAggregatesRead}, Tenant: "acme"},
+	"orphan":  {Subject: "orphan", Roles: []string{models.ScopeEventsRead, models.ScopeAggregatesRead}},
+	"nothing": {Subject: "nobody", Tenant: "acme"},
+}
+
+type tokenAuth struct{}
+
+func (tokenAuth) Authenticate(_ context.Context, token string) (*auth.Principal, error) {
+	if p, ok := tokens[token]; ok {
+		return p, nil
+	}
+	return nil, auth.ErrUnauthenticated
+}
+
+func newAPIServer(t *testing.T) *echo.Echo {
+	t.Helper()
+	store := memory.New()
+	ts := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	event := func(id, tenantID string) *models.AuditEvent {
+		ts = ts.Add(time.Minute)
+		return &models.AuditEvent{
+			EventID:         id,
+			Timestamp:       ts,
+			Status:          "SUCCESS",
+			EventType:       "LOGIN",
+			Actor:           models.Actor{ID: "alice", Type: "USER", Name: "Alice Smith", IPAddress: "10.0.0.1"},
+			Entity:          models.Entity{ID: "doc-1", Type: "DOCUMENT", Name: "Quarterly report"},
+			Context:         models.Context{SourceService: "auth", TraceID: "t-1", RequestID: "r-1"},
+			Security:        &models.Security{AccessLevel: "HIGH"},
+			Details:         map[string]any{"email": "alice@example.com", "attempt": 1.0, "tags": []any{"a"}},
+			Redactions:      1,
+			SignatureStatus: "VERIFIED",
+			Tenant:          tenantID,
+			Chain:           &models.ChainLink{Stream: "kafka/audit", Seq: 1, IngestedAt: ts, PrevHash: "00", Hash: "ab"},
+		}
+	}
+	batch := []*models.AuditEvent{event("e1", "acme"), event("e2", "globex"), event("e3", "acme")}
+	batch[2].Details = nil
+	if err := store.IndexBatch(context.Background(), batch); err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	handlers.NewAPI(tenant.NewStore(store, true), nil, []string{"email"}).Register(e.Group("/api/v1"), auth.Middleware(tokenAuth{}))
+	return e
+}
+
+// apiCall - запрос к маршруту из документа и ожидаемый код ответа.
+type apiCall struct {
+	path   string // путь из openapi.json, параметры в фигурных скобках
+	params map[string]string
+	query  string
+	token  string
+	status int
+	check  func(t *testing.T, body any)
+}
+
+func TestAPIMatchesOpenAPI(t *testing.T) {
+	doc := loadSpec(t)
+	e := newAPIServer(t)
+
+	eventIDs := func(want ...string) func(t *testing.T, body any) {
+		return func(t *testing.T, body any) {
+			var got []string
+			for _, ev := range body.(map[string]any)["events"].([]any) {
+				got = append(got, ev.(map[string]any)["event_id"].(string))
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("got events %v, want %v", got, want)
+			}
+		}
+	}
+	calls := []apiCall{
+		{path: "/events", token: "reader", status: http.StatusOK, check: eventIDs("e3", "e1")},
+		{path: "/events", token: "reader", query: "limit=1&offset=1&status=SUCCESS&from=2024-07-01T12:00:00Z", status: http.StatusOK, check: eventIDs("e1")},
+		{path: "/events", token: "reader", query: "limit=-1", status: http.StatusBadRequest},
+		{path: "/events", token: "reader", query: "to=yesterday", status: http.StatusBadRequest},
+		{path: "/events", status: http.StatusUnauthorized},
+		{path: "/events", token: "facets", status: http.StatusForbidden},
+		{path: "/events", token: "orphan", status: http.StatusForbidden},
+
+		{path: "/events/{id}", params: map[string]string{"id": "e1"}, token: "reader", status: http.StatusOK, check: func(t *testing.T, body any) {
+			ev := body.(map[string]any)
+			if ev["details"].(map[string]any)["email"] != "[REDACTED]" || ev["actor"].(map[string]any)["ip_address"] != "[REDACTED]" {
+				t.Errorf("PII is not masked without pii_reader: %v", ev)
+			}
+		}},
+		{path: "/events/{id}", params: map[string]string{"id": "e1"}, token: "pii", status: http.StatusOK, check: func(t *testing.T, body any) {
+			if email := body.(map[string]any)["details"].(map[string]any)["email"]; email != "alice@example.com" {
+				t.Errorf("got email %v for pii_reader", email)
+			}
+		}},
+		{path: "/events/{id}", params: map[string]string{"id": "e3"}, token: "reader", status: http.StatusOK},
+		{path: "/events/{id}", params: map[string]string{"id": "e2"}, token: "reader", status: http.StatusNotFound},
+		{path: "/events/{id}", params: map[string]string{"id": "missing"}, token: "reader", status: http.StatusNotFound},
+		{path: "/events/{id}", params: map[string]string{"id": "e1"}, token: "bad", status: http.StatusUnauthorized},
+		{path: "/events/{id}", params: map[string]string{"id": "e1"}, token: "nothing", status: http.StatusForbidden},
+
+		{path: "/facets", token: "facets", query: "field=tenant&size=5", status: http.StatusOK, check: func(t *testing.T, body any) {
+			buckets := body.(map[string]any)["buckets"].([]any)
+			if len(buckets) != 1 || buckets[0].(map[string]any)["key"] != "acme" || buckets[0].(map[string]any)["count"] != 2.0 {
+				t.Errorf("got buckets %v, want only acme with 2 events", buckets)
+			}
+		}},
+		{path: "/facets", token: "facets", query: "field=status&eventType=LOGOUT", status: http.StatusOK},
+		{path: "/facets", token: "facets", query: "field=details.email", status: http.StatusBadRequest},
+		{path: "/facets", token: "facets", status: http.StatusBadRequest},
+		{path: "/facets", query: "field=status", status: http.StatusUnauthorized},
+		{path: "/facets", token: "nothing", query: "field=status", status: http.StatusForbidden},
+		{path: "/facets", token: "orphan", query: "field=status", status: http.StatusForbidden},
+
+		{path: "/openapi.json", status: http.StatusOK},
+	}
+
+	covered := map[string]bool{}
+	for _, call := range calls {
+		target := call.path
+		for name, value := range call.params {
+			target = strings.ReplaceAll(target, "{"+name+"}", url.PathEscape(value))
+		}
+		target = "/api/v1" + target
+		if call.query != "" {
+			target += "?" + call.query
+		}
+		status := fmt.Sprint(call.status)
+		covered[call.path+" "+status] = true
+
+		t.Run(fmt.Sprintf("%s %s %s", target, call.token, status), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			if call.token != "" {
+				req.Header.Set(echo.HeaderAuthorization, "Bearer "+call.token)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != call.status {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, call.status, rec.Body)
+			}
+			if ct := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(ct, echo.MIMEApplicationJSON) {
+				t.Fatalf("got Content-Type %q", ct)
+			}
+			var body any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("response is not JSON: %v", err)
+			}
+			schema := doc.responseSchema(t, call.path, http.MethodGet, status)
+			for _, err := range doc.validate(t, schema, body, "response") {
+				t.Error(err)
+			}
+			if call.check != nil {
+				call.check(t, body)
+			}
+		})
+	}
+
+	// Каждый документированный ответ проверен хотя бы одним запросом.
+	for path, item := range doc["paths"].(map[string]any) {
+		for method, op := range item.(map[string]any) {
+			if method != "get" {
+				t.Errorf("%s %s: no test calls for method", strings.ToUpper(method), path)
+				continue
+			}
+			for status := range op.(map[string]any)["responses"].(map[string]any) {
+				if !covered[path+" "+status] {
+					t.Errorf("GET %s: response %s is not exercised", path, status)
+				}
+			}
+		}
+	}
+}
+
+func TestAPIRoutesAreDocumented(t *testing.T) {
+	doc := loadSpec(t)
+	paths := doc["paths"].(map[string]any)
+	param := regexp.MustCompile(`:(\w+)`)
+
+	routes := 0
+	for _, route := range newAPIServer(t).Routes() {
+		path, ok := strings.CutPrefix(route.Path, "/api/v1")
+		if !ok {
+			continue
+		}
+		routes++
+		path = param.ReplaceAllString(path, "{$1}")
+		item, ok := paths[path].(map[string]any)
+		if !ok {
+			t.Errorf("%s %s is not in openapi.json", route.Method, route.Path)
+			continue
+		}
+		if _, ok := item[strings.ToLower(route.Method)]; !ok {
+			t.Errorf("%s %s is not in openapi.json", route.Method, route.Path)
+		}
+	}
+	if routes != len(paths) {
+		t.Errorf("got %d routes under /api/v1, openapi.json documents %d paths", routes, len(paths))
+	}
+}
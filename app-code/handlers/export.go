@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"witness/export"
+	"witness/models"
+	"witness/querylog"
+	"witness/storage"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Export выгружает события в файл NDJSON или CSV (GET /export).
+type Export struct {
+	store    storage.EventStore
+	queryLog *querylog.Recorder
+	// maskKeys - ключи details, скрываемые от субъектов без роли pii_reader.
+	maskKeys []string
+}
+
+// NewExport создает обработчик. store - хранилище для API (с ограничением арендатором
+// и расшифровкой PII); каждая выгрузка записывается в журнал запросов queryLog
+// событием AUDIT_LOG_EXPORT (nil - журнал отключен).
+func NewExport(store storage.EventStore, queryLog *querylog.Recorder, maskKeys []string) *Export {
+	return &Export{store: store, queryLog: queryLog, maskKeys: maskKeys}
+}
+
+// Handle отдает все события, подходящие под фильтр, без ограничения окна пагинации
+// searchEvents. Параметры: format (ndjson или csv), filter (JSON в формате
+// AuditEventFilter), columns (пути полей через запятую), gzip (сжать файл).
+// События пишутся по мере чтения; отключение клиента прерывает выгрузку.
+func (h *Export) Handle(c echo.Context) error {
+	ctx := c.Request().Context()
+	if err := requireRole(c, models.ScopeEventsRead); err != nil {
+		return err
+	}
+
+	format := c.QueryParam("format")
+	if format == "" {
+		format = export.FormatNDJSON
+	}
+	filter, err := parseFilter(c)
+	if err != nil {
+		return err
+	}
+	var columns []string
+	for _, column := range strings.Split(c.QueryParam("columns"), ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	compress := false
+	if raw := c.QueryParam("gzip"); raw != "" {
+		if compress, err = strconv.ParseBool(raw); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid gzip: %v", err))
+		}
+	}
+
+	res := c.Response()
+	var out io.Writer = res
+	var zw *gzip.Writer
+	if compress {
+		zw = gzip.NewWriter(res)
+		out = zw
+	}
+	w, err := export.NewWriter(out, format, columns)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	start := time.Now()
+	filename := "audit-export-" + start.UTC().Format("20060102T150405Z") + "." + format
+	res.Header().Set(echo.HeaderContentType, export.ContentType(format))
+	if compress {
+		filename += ".gz"
+		res.Header().Set(echo.HeaderContentType, "application/gzip")
+	}
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+
+	// Ответ уходит клиенту, только когда заполнится буфер Writer: ошибка в начале
+	// выгрузки (нет арендатора, хранилище недоступно) еще может стать кодом ответа.
+	err = h.store.Scan(ctx, filter, func(event *models.AuditEvent) error {
+		return w.Write(export.View(ctx, event, h.maskKeys))
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil && zw != nil {
+		err = zw.Close()
+	}
+
+	details := map[string]any{
+		"format":      format,
+		"columns":     columns,
+		"gzip":        compress,
+		"rows":        w.Rows(),
+		"duration_ms": time.Since(start).Milliseconds(),
+	}
+	if filter != nil {
+		details["filter"] = filter
+	}
+	if err != nil {
+		details["error"] = err.Error()
+		details["cancelled"] = ctx.Err() != nil
+	}
+	h.queryLog.Record(ctx, querylog.ExportEventType, models.Entity{ID: filename, Type: "EXPORT", Name: filename}, err == nil, details)
+
+	if err == nil {
+		return nil
+	}
+	if !res.Committed {
+		return storeError(err)
+	}
+	// Заголовки уже отправлены: клиент увидит оборванный файл.
+	slog.Warn("export interrupted", "rows", w.Rows(), "error", err)
+	return nil
+}
+
+// ExportDownload отдает файл фоновой выгрузки по подписанной ссылке из ExportJob.download_url
+// (GET /exports/:id/download). Ссылка сама служит пропуском, поэтому токен не нужен.
+func ExportDownload(jobs *export.Jobs) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		job, file, err := jobs.Open(c.Request().Context(), c.Param("id"), c.QueryParam("expires"), c.QueryParam("signature"))
+		if errors.Is(err, export.ErrInvalidLink) {
+			return echo.NewHTTPError(http.StatusForbidden, err.Error())
+		}
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		res := c.Response()
+		name := path.Base(job.Key)
+		contentType := export.ContentType(job.Format)
+		if job.Gzip {
+			contentType = "application/gzip"
+		}
+		res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name))
+		res.Header().Set(echo.HeaderContentLength, strconv.FormatInt(job.Size, 10))
+		// Клиент может сверить файл с ExportJob.checksum.
+		res.Header().Set("X-Checksum-Sha256", job.Checksum)
+		return c.Stream(http.StatusOK, contentType, file)
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"witness/ingest"
+	"witness/models"
+	"witness/signing"
+	"witness/tenant"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Результаты приема события через POST /ingest.
+const (
+	// IngestAccepted - событие подготовлено и будет сохранено вместе с ближайшей пачкой.
+	IngestAccepted = "accepted"
+	// IngestRejected - событие не разобрано или не прошло проверку. Повторять бесполезно.
+	IngestRejected = "rejected"
+	// IngestFailed - событие не удалось подготовить из-за временной ошибки, его можно отправить снова.
+	IngestFailed = "failed"
+)
+
+// maxIngestLine - предельный размер одной строки пакета NDJSON.
+const maxIngestLine = 1 << 20
+
+// Ingest принимает события по HTTP (POST /ingest) для продюсеров, у которых нет
+// доступа к Kafka. События проходят тот же конвейер приема и буфер записи, что и сообщения Kafka.
+type Ingest struct {
+	pipeline *ingest.Pipeline
+	buffer   *ingest.Buffer
+	tenants  *tenant.Resolver
+	// stream - поток цепочки хешей для событий, принятых этим экземпляром.
+	stream  string
+	maxBody int64
+}
+
+// NewIngest создает обработчик. Все события, принятые по HTTP, попадают в цепочку
+// потока stream; он должен быть своим у каждого экземпляра Witness. maxBody -
+// предельный размер тела запроса в байтах.
+func NewIngest(pipeline *ingest.Pipeline, buffer *ingest.Buffer, tenants *tenant.Resolver, stream string, maxBody int64) *Ingest {
+	return &Ingest{pipeline: pipeline, buffer: buffer, tenants: tenants, stream: stream, maxBody: maxBody}
+}
+
+// ingestResult - итог приема одного события.
+type ingestResult struct {
+	// Index - номер события в запросе (строки пакета NDJSON), с нуля.
+	Index   int    `json:"index"`
+	EventID string `json:"event_id,omitempty"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ingestResponse - ответ POST /ingest.
+type ingestResponse struct {
+	Accepted int             `json:"accepted"`
+	Rejected int             `json:"rejected"`
+	Failed   int             `json:"failed"`
+	Results  []*ingestResult `json:"results"`
+}
+
+// Handle принимает одно событие (application/json) или пакет NDJSON
+// (application/x-ndjson, одно событие или конверт с подписью на строку) и отвечает
+// 202 с результатом по каждому событию. Подпись продюсера из заголовка
+// Witness-Signature относится только к одиночному событию. Арендатор - арендатор
+// ключа API, а если ключ им не ограничен - из заголовка Witness-Tenant или TENANT_DEFAULT.
+func (h *Ingest) Handle(c echo.Context) error {
+	if err := requireRole(c, models.ScopeEventsWrite); err != nil {
+		return err
+	}
+	req := c.Request()
+	ctx := req.Context()
+	if err := h.buffer.Admit(); err != nil {
+		c.Response().Header().Set("Retry-After", "5")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	batch := mediaType == "application/x-ndjson" || mediaType == "application/ndjson"
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Response(), req.Body, h.maxBody))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", h.maxBody))
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to read request body: %v", err))
+	}
+
+	var values [][]byte
+	if batch {
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		scanner.Buffer(make([]byte, 0, 64*1024), maxIngestLine)
+		for scanner.Scan() {
+			values = append(values, bytes.Clone(scanner.Bytes()))
+		}
+		if err := scanner.Err(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid ndjson: %v", err))
+		}
+	} else {
+		values = [][]byte{body}
+	}
+
+	meta := ingest.Meta{Tenant: h.tenants.ResolveCaller(ctx, req.Header.Get(tenant.Header))}
+	if !batch {
+		meta.Signature = req.Header.Get(signing.Header)
+	}
+
+	res := ingestResponse{Results: make([]*ingestResult, 0, len(values))}
+	var events []*models.AuditEvent
+	for i, value := range values {
+		if len(bytes.TrimSpace(value)) == 0 {
+			// Пустые строки пакета не считаем событиями, но номера строк сохраняем.
+			if batch {
+				continue
+			}
+			return echo.NewHTTPError(http.StatusBadRequest, "request body is empty")
+		}
+		result := &ingestResult{Index: i}
+		event, err := h.pipeline.Process(ctx, h.stream, value, meta)
+		switch {
+		case err == nil:
+			result.EventID, result.Status = event.EventID, IngestAccepted
+			events = append(events, event)
+			res.Accepted++
+		case errors.Is(err, ingest.ErrMalformed):
+			result.Status, result.Error = IngestRejected, err.Error()
+			res.Rejected++
+		default:
+			slog.Error("failed to process ingested event", "stream", h.stream, "index", i, "error", err)
+			result.Status, result.Error = IngestFailed, err.Error()
+			res.Failed++
+		}
+		res.Results = append(res.Results, result)
+	}
+	if len(res.Results) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "request body is empty")
+	}
+
+	// Пачку могут сохранить вместе с событиями других запросов: отключение клиента не должно ее прерывать.
+	h.buffer.Add(context.WithoutCancel(ctx), events...)
+	return c.JSON(http.StatusAccepted, res)
+}
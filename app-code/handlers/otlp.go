@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"witness/models"
+	"witness/otlp"
+	"witness/tenant"
+
+	"github.com/labstack/echo/v4"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Типы содержимого OTLP/HTTP.
+const (
+	otlpProtobuf = "application/x-protobuf"
+	otlpJSON     = "application/json"
+)
+
+// OTLPLogs принимает логи OpenTelemetry по OTLP/HTTP (POST /v1/logs) в protobuf или JSON,
+// в том числе сжатые gzip. Ответ - в формате запроса; отклоненные записи
+// перечисляются в partial_success. maxBody ограничивает размер тела после распаковки.
+func OTLPLogs(receiver *otlp.Receiver, maxBody int64) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := requireRole(c, models.ScopeEventsWrite); err != nil {
+			return err
+		}
+		req := c.Request()
+
+		mediaType, _, _ := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+		if mediaType != otlpProtobuf && mediaType != otlpJSON {
+			return echo.NewHTTPError(http.StatusUnsupportedMediaType, "expected "+otlpProtobuf+" or "+otlpJSON)
+		}
+
+		var body io.Reader = http.MaxBytesReader(c.Response(), req.Body, maxBody)
+		switch req.Header.Get(echo.HeaderContentEncoding) {
+		case "", "identity":
+		case "gzip":
+			zr, err := gzip.NewReader(body)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid gzip body: %v", err))
+			}
+			defer zr.Close()
+			body = io.LimitReader(zr, maxBody+1)
+		default:
+			return echo.NewHTTPError(http.StatusUnsupportedMediaType, "unsupported content encoding")
+		}
+		data, err := io.ReadAll(body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || int64(len(data)) > maxBody {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", maxBody))
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to read request body: %v", err))
+		}
+
+		logs := &collogspb.ExportLogsServiceRequest{}
+		if mediaType == otlpProtobuf {
+			err = proto.Unmarshal(data, logs)
+		} else {
+			err = otlp.UnmarshalJSON(data, logs)
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid otlp request: %v", err))
+		}
+
+		res, err := receiver.Consume(req.Context(), logs, req.Header.Get(tenant.Header))
+		if errors.Is(err, otlp.ErrUnavailable) {
+			// 503 экспортеры OTLP повторяют с задержкой.
+			return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+		}
+		if err != nil {
+			return err
+		}
+
+		if mediaType == otlpProtobuf {
+			out, err := proto.Marshal(res.Response())
+			if err != nil {
+				return err
+			}
+			return c.Blob(http.StatusOK, otlpProtobuf, out)
+		}
+		out, err := protojson.Marshal(res.Response())
+		if err != nil {
+			return err
+		}
+		return c.Blob(http.StatusOK, otlpJSON, out)
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	"witness/export"
+	"witness/live"
+	"witness/models"
+	"witness/storage"
+
+	"github.com/labstack/echo/v4"
+)
+
+// EventStream отдает новые события в формате Server-Sent Events (GET /events/stream).
+type EventStream struct {
+	feed  *live.Feed
+	store storage.EventStore
+	// maskKeys - ключи details, скрываемые от субъектов без роли pii_reader.
+	maskKeys    []string
+	heartbeat   time.Duration
+	replayLimit int
+}
+
+// NewEventStream создает обработчик. store - хранилище для API (с ограничением арендатором
+// и расшифровкой PII), в нем ищется событие из Last-Event-ID; пропущенные после него события
+// повторяет feed, за одно подключение - не больше replayLimit событий.
+func NewEventStream(feed *live.Feed, store storage.EventStore, maskKeys []string, heartbeat time.Duration, replayLimit int) *EventStream {
+	return &EventStream{feed: feed, store: store, maskKeys: maskKeys, heartbeat: heartbeat, replayLimit: replayLimit}
+}
+
+// Handle подписывает клиента на события, подходящие под фильтр из параметра filter
+// (JSON в формате AuditEventFilter из GraphQL). Идентификатор сообщения - event_id;
+// с заголовком Last-Event-ID сначала повторяются события, принятые после него.
+func (s *EventStream) Handle(c echo.Context) error {
+	ctx := c.Request().Context()
+	if err := requireRole(c, models.ScopeEventsRead); err != nil {
+		return err
+	}
+
+	filter, err := parseFilter(c)
+	if err != nil {
+		return err
+	}
+
+	// Подписываемся до повтора, чтобы не потерять события, сохраненные во время него.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := s.feed.Subscribe(ctx, filter)
+	if err != nil {
+		return storeError(err)
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	// Не даем обратному прокси (nginx) буферизовать поток.
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	var replayed map[string]bool
+	if lastID := c.Request().Header.Get("Last-Event-ID"); lastID != "" {
+		var truncated bool
+		replayed, truncated, err = s.replay(c, filter, lastID)
+		if err != nil {
+			slog.Warn("failed to replay events", "last_event_id", lastID, "error", err)
+			return nil
+		}
+		if truncated {
+			// Клиент переподключится с новым Last-Event-ID и получит следующую часть.
+			return nil
+		}
+	}
+
+	ticker := time.NewTicker(s.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if replayed[event.EventID] {
+				continue
+			}
+			if err := s.write(c, event); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// replay отправляет события, принятые после lastID, в порядке приема и возвращает их
+// идентификаторы. Курсор - время приема (chain.ingested_at), а не время события, поэтому
+// события, пришедшие с опозданием и временем раньше lastID, тоже повторяются.
+// truncated - повторены не все.
+func (s *EventStream) replay(c echo.Context, filter *models.AuditEventFilter, lastID string) (map[string]bool, bool, error) {
+	ctx := c.Request().Context()
+	last, err := s.store.Get(ctx, lastID)
+	if errors.Is(err, storage.ErrNotFound) {
+		// Событие удалено retention или принадлежит другому арендатору: повторять не от чего.
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	missed, truncated, err := s.feed.Replay(ctx, filter, last, s.replayLimit)
+	if err != nil {
+		return nil, false, err
+	}
+	replayed := make(map[string]bool, len(missed))
+	for _, event := range missed {
+		if err := s.write(c, event); err != nil {
+			return nil, false, err
+		}
+		replayed[event.EventID] = true
+	}
+	return replayed, truncated, nil
+}
+
+// write отправляет событие одним сообщением SSE.
+func (s *EventStream) write(c echo.Context, event *models.AuditEvent) error {
+	data, err := json.Marshal(export.View(c.Request().Context(), event, s.maskKeys))
+	if err != nil {
+		slog.Error("failed to marshal event for stream", "event_id", event.EventID, "error", err)
+		return nil
+	}
+	res := c.Response()
+	if _, err := fmt.Fprintf(res, "id: %s\ndata: %s\n\n", event.EventID, data); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
+
+// parseFilter разбирает параметр filter: JSON в формате AuditEventFilter из GraphQL.
+func parseFilter(c echo.Context) (*models.AuditEventFilter, error) {
+	raw := c.QueryParam("filter")
+	if raw == "" {
+		return nil, nil
+	}
+	filter := &models.AuditEventFilter{}
+	if err := json.Unmarshal([]byte(raw), filter); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid filter: %v", err))
+	}
+	return filter, nil
+}
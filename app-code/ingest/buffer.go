@@ -0,0 +1,254 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+	"witness/live"
+	"witness/models"
+	"witness/storage"
+)
+
+// Повторы записи пачки: неудачная пачка возвращается в буфер, а не теряется,
+// потому что звенья ее событий уже учтены в головах цепочек.
+const (
+	flushAttempts = 3
+	flushBackoff  = 500 * time.Millisecond
+)
+
+// maxPending - сколько несохраненных событий держим в памяти, пока хранилище недоступно.
+// Отбросить принятое событие нельзя (его звено уже в цепочке), поэтому сверх предела
+// транспорты не принимают новые события: см. Buffer.Admit и Buffer.Wait.
+const maxPending = 10000
+
+// ErrFull - в буфере maxPending несохраненных событий: хранилище не успевает или недоступно.
+var ErrFull = errors.New("ingest buffer is full: storage is not keeping up, retry later")
+
+// Buffer копит подготовленные события всех транспортов и сохраняет их пачками
+// (IndexBatch): при заполнении и по таймеру. Сохраненные события публикуются подписчикам live.
+type Buffer struct {
+	store         storage.EventStore
+	live          *live.Broker
+	maxSize       int
+	maxPending    int
+	flushInterval time.Duration
+	backoff       time.Duration
+
+	// flushMu упорядочивает записи: пачка, вернувшаяся в буфер после ошибки,
+	// сохраняется раньше событий, добавленных позже.
+	flushMu sync.Mutex
+	mu      sync.Mutex
+	entries []entry
+	// drained закрывается, когда буфер уменьшился (запись или Discard); его ждет Wait.
+	drained chan struct{}
+}
+
+// entry - событие в буфере и необязательный обработчик его сохранения.
+type entry struct {
+	event  *models.AuditEvent
+	stored func()
+}
+
+// NewBuffer создает буфер записи в хранилище.
+func NewBuffer(store storage.EventStore, broker *live.Broker) *Buffer {
+	return &Buffer{
+		store:         store,
+		live:          broker,
+		maxSize:       100,
+		maxPending:    maxPending,
+		flushInterval: 5 * time.Second,
+		backoff:       flushBackoff,
+		entries:       make([]entry, 0, 100),
+		drained:       make(chan struct{}),
+	}
+}
+
+// Admit возвращает ErrFull, если буфер переполнен. Транспорты с ответом (HTTP, gRPC, OTLP)
+// вызывают его до конвейера приема и отвечают 503/UNAVAILABLE: событие, прошедшее
+// конвейер, уже занимает номер в цепочке. Предел мягкий: одновременные запросы могут
+// немного превысить его.
+func (b *Buffer) Admit() error {
+	if b.Len() >= b.maxPending {
+		return ErrFull
+	}
+	return nil
+}
+
+// Wait ждет, пока в буфере не станет меньше maxPending событий, или отмены ctx.
+// Так транспорт без ответа отправителю (Kafka) перестает читать, пока хранилище недоступно.
+func (b *Buffer) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		if len(b.entries) < b.maxPending {
+			b.mu.Unlock()
+			return nil
+		}
+		drained := b.drained
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-drained:
+		}
+	}
+}
+
+// signal будит ожидающих Wait. Вызывается под b.mu.
+func (b *Buffer) signal() {
+	close(b.drained)
+	b.drained = make(chan struct{})
+}
+
+// Add добавляет события в буфер и сохраняет его, если он заполнился.
+func (b *Buffer) Add(ctx context.Context, events ...*models.AuditEvent) {
+	b.AddFunc(ctx, nil, events...)
+}
+
+// AddFunc - как Add, но вызывает stored, когда все события сохранены в хранилище.
+// Так транспорт с подтверждениями (Kafka) подтверждает сообщение только после записи.
+// Обработчики вызываются из горутины, выполнившей запись, в порядке добавления;
+// AddFunc без событий вызовет stored после сохранения всего, что добавлено раньше.
+func (b *Buffer) AddFunc(ctx context.Context, stored func(), events ...*models.AuditEvent) {
+	b.mu.Lock()
+	if len(events) == 0 {
+		b.entries = append(b.entries, entry{stored: stored})
+	}
+	for i, event := range events {
+		e := entry{event: event}
+		if i == len(events)-1 {
+			e.stored = stored
+		}
+		b.entries = append(b.entries, e)
+	}
+	size := len(b.entries)
+	b.mu.Unlock()
+
+	if size >= b.maxSize {
+		if err := b.Flush(ctx); err != nil {
+			slog.Error("failed to flush events to storage", "error", err)
+		}
+	}
+}
+
+// Run периодически сохраняет буфер, пока не отменен ctx, и сохраняет остаток при остановке.
+func (b *Buffer) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("flusher context done, performing final flush")
+			// Используем новый контекст для последней отправки
+			if err := b.Flush(context.Background()); err != nil {
+				slog.Error("final flush failed, unsaved events are lost", "count", b.Len(), "error", err)
+			}
+			return
+		case <-ticker.C:
+			if err := b.Flush(ctx); err != nil {
+				slog.Error("failed to flush events to storage", "error", err)
+			}
+		}
+	}
+}
+
+// Len возвращает число несохраненных событий.
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries)
+}
+
+// Flush отправляет накопленные события в хранилище, повторяя запись при ошибке.
+// Если все попытки неудачны, события возвращаются в начало буфера и будут
+// сохранены следующим Flush; их обработчики stored не вызываются.
+func (b *Buffer) Flush(ctx context.Context) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	if len(b.entries) == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	// Забираем накопленное, чтобы можно было разблокировать мьютекс
+	batch := b.entries
+	b.entries = make([]entry, 0, max(b.maxSize, len(batch)))
+	b.mu.Unlock()
+
+	events := make([]*models.AuditEvent, 0, len(batch))
+	for _, e := range batch {
+		if e.event != nil {
+			events = append(events, e.event)
+		}
+	}
+
+	if err := b.index(ctx, events); err != nil {
+		b.mu.Lock()
+		b.entries = append(batch, b.entries...)
+		b.mu.Unlock()
+		return fmt.Errorf("failed to store %d events: %w", len(events), err)
+	}
+
+	b.mu.Lock()
+	b.signal()
+	b.mu.Unlock()
+
+	if len(events) > 0 {
+		slog.Info("flushed events to storage", "count", len(events))
+	}
+	for _, e := range batch {
+		if e.stored != nil {
+			e.stored()
+		}
+	}
+	if len(events) > 0 {
+		b.live.Publish(events)
+	}
+	return nil
+}
+
+func (b *Buffer) index(ctx context.Context, events []*models.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	delay := b.backoff
+	for attempt := 1; ; attempt++ {
+		err := b.store.IndexBatch(ctx, events)
+		if err == nil || attempt == flushAttempts {
+			return err
+		}
+		slog.Warn("retrying flush after error", "count", len(events), "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// Discard удаляет из буфера несохраненные события потоков streams и возвращает их число.
+// Вызывается, когда поток переходит к другому экземпляру, а записать его события не удалось:
+// новый владелец продолжит цепочку с сохраненного, и эти звенья стали бы лишними.
+// После Discard головы цепочек потоков нужно сбросить (Pipeline.Reset).
+func (b *Buffer) Discard(streams ...string) int {
+	// Дожидаемся текущей записи: неудачная пачка вернется в буфер.
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(b.entries)
+	b.entries = slices.DeleteFunc(b.entries, func(e entry) bool {
+		return e.event != nil && e.event.Chain != nil && slices.Contains(streams, e.event.Chain.Stream)
+	})
+	if len(b.entries) < n {
+		b.signal()
+	}
+	return n - len(b.entries)
+}
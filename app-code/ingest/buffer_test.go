@@ -0,0 +1,75 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+	"witness/live"
+	"witness/models"
+	"witness/storage/memory"
+)
+
+// flakyStore - хранилище, запись в которое не удается, пока down.
+type flakyStore struct {
+	*memory.Store
+	down atomic.Bool
+}
+
+func (s *flakyStore) IndexBatch(ctx context.Context, events []*models.AuditEvent) error {
+	if s.down.Load() {
+		return errors.New("storage is down")
+	}
+	return s.Store.IndexBatch(ctx, events)
+}
+
+func TestBufferBackpressure(t *testing.T) {
+	ctx := context.Background()
+	store := &flakyStore{Store: memory.New()}
+	store.down.Store(true)
+	b := NewBuffer(store, live.NewBroker())
+	b.maxSize, b.maxPending, b.backoff = 2, 4, 0
+
+	for _, id := range []string{"e1", "e2", "e3", "e4"} {
+		if err := b.Admit(); err != nil {
+			t.Fatalf("Admit before %s: %v", id, err)
+		}
+		b.Add(ctx, &models.AuditEvent{EventID: id})
+	}
+	// Неудачные пачки вернулись в буфер, и он переполнен.
+	if b.Len() != 4 {
+		t.Fatalf("buffer holds %d events, want 4", b.Len())
+	}
+	if err := b.Admit(); !errors.Is(err, ErrFull) {
+		t.Fatalf("Admit on a full buffer: got %v, want ErrFull", err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := b.Wait(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait on a full buffer: got %v, want DeadlineExceeded", err)
+	}
+
+	// Wait возвращается, когда хранилище снова принимает запись.
+	waited := make(chan error, 1)
+	go func() { waited <- b.Wait(ctx) }()
+	store.down.Store(false)
+	if err := b.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return after the buffer was flushed")
+	}
+	if err := b.Admit(); err != nil {
+		t.Errorf("Admit after flush: %v", err)
+	}
+	if _, err := store.Get(ctx, "e4"); err != nil {
+		t.Errorf("e4 was not stored: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+// Package ingest готовит принятые события к сохранению независимо от транспорта.
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"witness/integrity"
+	"witness/models"
+	"witness/pii"
+	"witness/redaction"
+	"witness/signing"
+)
+
+// ErrMalformed - сообщение не удалось разобрать как событие. Повторная обработка не поможет.
+var ErrMalformed = errors.New("malformed audit event")
+
+// Pipeline проводит событие через этапы приема в фиксированном порядке:
+// проверка подписи продюсера (по исходным байтам), маскирование details,
+// шифрование PII и, последним, звено цепочки хешей - по итоговому виду события.
+type Pipeline struct {
+	signatures *signing.Registry
+	redactor   *redaction.Redactor
+	pii        *pii.Encryptor
+	chain      *integrity.Chain
+}
+
+// NewPipeline создает конвейер приема.
+func NewPipeline(signatures *signing.Registry, redactor *redaction.Redactor, encryptor *pii.Encryptor, chain *integrity.Chain) *Pipeline {
+	return &Pipeline{
+		signatures: signatures,
+		redactor:   redactor,
+		pii:        encryptor,
+		chain:      chain,
+	}
+}
+
+// Meta - метаданные сообщения, которые транспорт передает помимо тела.
+type Meta struct {
+	// Signature - подпись продюсера из метаданных транспорта, если ее нет в конверте.
+	Signature string
+	// Tenant - арендатор, определенный транспортом. Заменяет значение из тела события.
+	Tenant string
+	// Signed - байты, которые подписал продюсер, если транспорт сам построил событие
+	// из сообщения другого формата (CloudEvents). По умолчанию подписано событие.
+	Signed []byte
+}
+
+// Process разбирает сообщение (событие или конверт с подписью) и готовит событие к записи в поток stream.
+// Для повторно доставленного event_id возвращается ранее принятое событие с его звеном цепочки.
+func (p *Pipeline) Process(ctx context.Context, stream string, value []byte, meta Meta) (*models.AuditEvent, error) {
+	payload, signature := signing.Unwrap(value)
+	if signature == "" {
+		signature = meta.Signature
+	}
+
+	var event models.AuditEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if err := validate(&event); err != nil {
+		return nil, err
+	}
+
+	// Арендатора определяет Witness: продюсер не должен писать в чужой раздел.
+	event.Tenant = meta.Tenant
+	signed := payload
+	if meta.Signed != nil {
+		signed = meta.Signed
+	}
+	event.SignatureStatus = p.signatures.Verify(event.Context.SourceService, signed, signature)
+	if event.SignatureStatus == models.SignatureInvalid {
+		slog.Warn("invalid producer signature", "event_id", event.EventID, "source_service", event.Context.SourceService)
+	}
+
+	p.redactor.Redact(&event)
+
+	if err := p.pii.Encrypt(&event); err != nil {
+		return nil, fmt.Errorf("failed to encrypt pii of %s: %w", event.EventID, err)
+	}
+	accepted, err := p.chain.Append(ctx, stream, &event)
+	if errors.Is(err, integrity.ErrConflict) {
+		// Повтор с тем же event_id ничего не изменит: событие отклоняется, а не откладывается.
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if accepted != &event {
+		slog.Info("event redelivered, keeping the accepted one", "event_id", event.EventID, "stream", stream)
+	}
+	return accepted, nil
+}
+
+// Reset сбрасывает состояние цепочек потоков streams (без аргументов - всех),
+// когда потоки могли сменить владельца.
+func (p *Pipeline) Reset(streams ...string) {
+	p.chain.Reset(streams...)
+}
+
+// validate проверяет поля, без которых событие нельзя сохранить и найти:
+// event_id служит ключом в хранилище, timestamp и event_type - основа поиска.
+func validate(event *models.AuditEvent) error {
+	switch {
+	case event.EventID == "":
+		return fmt.Errorf("%w: event_id is required", ErrMalformed)
+	case event.EventType == "":
+		return fmt.Errorf("%w: event_type is required", ErrMalformed)
+	case event.Timestamp.IsZero():
+		return fmt.Errorf("%w: timestamp is required", ErrMalformed)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"witness/auth"
+	"witness/models"
+)
+
+// Recorder записывает события аудита, которые порождает сам Witness (удержания, забвение),
+// в отдельный поток цепочки. События проходят тот же конвейер, что и события продюсеров:
+// получают звено цепочки и арендатора, маскируются и шифруются, сохраняются через буфер.
+type Recorder struct {
+	pipeline *Pipeline
+	buffer   *Buffer
+	stream   string
+}
+
+// NewRecorder создает запись событий Witness в поток stream.
+func NewRecorder(pipeline *Pipeline, buffer *Buffer, stream string) *Recorder {
+	return &Recorder{pipeline: pipeline, buffer: buffer, stream: stream}
+}
+
+// Record проводит событие через конвейер и ставит его в буфер записи.
+// Арендатор события - арендатор субъекта запроса из ctx.
+func (r *Recorder) Record(ctx context.Context, event *models.AuditEvent) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event %s: %w", event.EventID, err)
+	}
+	if err := r.buffer.Admit(); err != nil {
+		return err
+	}
+	var meta Meta
+	if p := auth.FromContext(ctx); p != nil {
+		meta.Tenant = p.Tenant
+	}
+	processed, err := r.pipeline.Process(ctx, r.stream, value, meta)
+	if err != nil {
+		return err
+	}
+	// Запись может уйти пачкой с событиями других запросов: отмена ctx не должна ее прерывать.
+	r.buffer.Add(context.WithoutCancel(ctx), processed)
+	return nil
+}
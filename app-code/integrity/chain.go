@@ -0,0 +1,207 @@
+// Package integrity строит и проверяет цепочки хешей событий аудита.
+//
+// При приеме каждое событие получает звено (models.ChainLink): поток, номер в нем,
+// время приема, хеш предыдущего события потока и собственный хеш. Изменение,
+// удаление или перестановка сохраненных событий нарушает цепочку, что и находит Verifier.
+package integrity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+	"witness/models"
+	"witness/storage"
+)
+
+// Canonical возвращает канонический JSON события, по которому считается хеш:
+// событие вместе со звеном цепочки, но без поля chain.hash. Каноничность обеспечивает
+// encoding/json: поля структур идут в фиксированном порядке, ключи details отсортированы.
+func Canonical(event *models.AuditEvent) ([]byte, error) {
+	e := *event
+	if e.Chain != nil {
+		link := *e.Chain
+		link.Hash = ""
+		e.Chain = &link
+	}
+	data, err := json.Marshal(&e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event %s: %w", event.EventID, err)
+	}
+	return data, nil
+}
+
+// Hash вычисляет хеш события: SHA-256 (hex) от Canonical.
+func Hash(event *models.AuditEvent) (string, error) {
+	data, err := Canonical(event)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ErrConflict - event_id уже занят событием другого арендатора. Такое событие нельзя
+// считать повторной доставкой: иначе один арендатор мог бы подавлять события другого,
+// отправляя их event_id первым.
+var ErrConflict = errors.New("event_id is already used by another tenant")
+
+// recentSize - сколько последних принятых событий Chain помнит, чтобы распознать
+// повторную доставку, пока событие еще не сохранено.
+const recentSize = 10000
+
+// Store - хранилище, из которого Chain читает головы потоков и уже принятые события.
+type Store interface {
+	storage.ChainStore
+	Get(ctx context.Context, eventID string) (*models.AuditEvent, error)
+}
+
+// Chain продолжает цепочки потоков. Последнее звено каждого потока держится
+// в памяти, а при первом обращении к потоку читается из хранилища.
+type Chain struct {
+	store Store
+	now   func() time.Time
+
+	mu    sync.Mutex
+	heads map[string]models.ChainLink
+	// recent - последние принятые события по event_id; order - их порядок для вытеснения.
+	recent map[string]*models.AuditEvent
+	order  []string
+}
+
+// NewChain создает построитель цепочек поверх хранилища.
+func NewChain(store Store) *Chain {
+	return &Chain{
+		store:  store,
+		now:    time.Now,
+		heads:  make(map[string]models.ChainLink),
+		recent: make(map[string]*models.AuditEvent),
+	}
+}
+
+// Append добавляет событие в конец цепочки потока и заполняет event.Chain.
+// Звено, пришедшее от продюсера, перезаписывается.
+//
+// Событие с уже принятым event_id (повторная доставка) новое звено не получает:
+// иначе запись по event_id заменила бы сохраненное звено и оставила в цепочке
+// пропуск. Тогда Append возвращает ранее принятое событие, а event не меняется.
+// Если ранее принятое событие принадлежит другому арендатору, возвращается ErrConflict.
+func (c *Chain) Append(ctx context.Context, stream string, event *models.AuditEvent) (*models.AuditEvent, error) {
+	// Сохраненное событие ищем до блокировки, чтобы не останавливать прием всех потоков.
+	stored, err := c.store.Get(ctx, event.EventID)
+	switch {
+	case err == nil:
+		return redelivered(stored, event)
+	case !errors.Is(err, storage.ErrNotFound):
+		return nil, fmt.Errorf("failed to look up event %s: %w", event.EventID, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Принятое, но еще не сохраненное событие.
+	if accepted, ok := c.recent[event.EventID]; ok {
+		return redelivered(accepted, event)
+	}
+
+	head, ok := c.heads[stream]
+	if !ok {
+		loaded, err := c.loadHead(ctx, stream)
+		if err != nil {
+			return nil, err
+		}
+		head = *loaded
+	}
+
+	event.Chain = &models.ChainLink{
+		Stream:     stream,
+		Seq:        head.Seq + 1,
+		IngestedAt: c.now().UTC(),
+		PrevHash:   head.Hash,
+	}
+	hash, err := Hash(event)
+	if err != nil {
+		return nil, err
+	}
+	event.Chain.Hash = hash
+
+	c.heads[stream] = *event.Chain
+	c.remember(event)
+	return event, nil
+}
+
+// redelivered возвращает ранее принятое событие accepted вместо повторно доставленного event,
+// если оба - одного арендатора.
+func redelivered(accepted, event *models.AuditEvent) (*models.AuditEvent, error) {
+	if accepted.Tenant != event.Tenant {
+		return nil, fmt.Errorf("%w: %s", ErrConflict, event.EventID)
+	}
+	return accepted, nil
+}
+
+// loadHead читает последнее звено потока из хранилища. Если конец цепочки удален
+// по сроку хранения или выгружен в архив, цепочка продолжается от последней отметки
+// об удалении, чтобы номера не повторялись.
+func (c *Chain) loadHead(ctx context.Context, stream string) (*models.ChainLink, error) {
+	head := &models.ChainLink{Stream: stream}
+	stored, err := c.store.ChainHead(ctx, stream)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("failed to load chain head of %s: %w", stream, err)
+	default:
+		head = stored
+	}
+
+	prunes, err := c.store.ChainPrunes(ctx, stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chain prunes of %s: %w", stream, err)
+	}
+	for _, prune := range prunes {
+		if prune.LastSeq > head.Seq {
+			head = &models.ChainLink{Stream: stream, Seq: prune.LastSeq, Hash: prune.LastHash}
+		}
+	}
+	return head, nil
+}
+
+// remember запоминает принятое событие, вытесняя самое старое сверх recentSize.
+func (c *Chain) remember(event *models.AuditEvent) {
+	if len(c.order) >= recentSize {
+		delete(c.recent, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.recent[event.EventID] = event
+	c.order = append(c.order, event.EventID)
+}
+
+// Reset забывает известные звенья потоков streams (без аргументов - всех потоков).
+// Вызывается, когда потоки могли сменить владельца (ребалансировка), чтобы
+// продолжить цепочку с того, что уже сохранено.
+func (c *Chain) Reset(streams ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(streams) == 0 {
+		c.heads = make(map[string]models.ChainLink)
+		c.recent = make(map[string]*models.AuditEvent)
+		c.order = nil
+		return
+	}
+	for _, stream := range streams {
+		delete(c.heads, stream)
+	}
+	// Несохраненные события сброшенных потоков могли быть отброшены: их повторная
+	// доставка должна получить звено заново.
+	c.order = slices.DeleteFunc(c.order, func(id string) bool {
+		if event := c.recent[id]; event.Chain != nil && slices.Contains(streams, event.Chain.Stream) {
+			delete(c.recent, id)
+			return true
+		}
+		return false
+	})
+}
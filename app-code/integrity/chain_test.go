@@ -0,0 +1,129 @@
+package integrity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"witness/models"
+	"witness/storage/memory"
+)
+
+var t0 = time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+// newChain создает цепочку, которая принимает i-е событие в момент t0 + i минут.
+func newChain(store Store) *Chain {
+	c := NewChain(store)
+	tick := 0
+	c.now = func() time.Time {
+		tick++
+		return t0.Add(time.Duration(tick) * time.Minute)
+	}
+	return c
+}
+
+func newEvent(id string) *models.AuditEvent {
+	return &models.AuditEvent{
+		EventID:   id,
+		Timestamp: t0,
+		Status:    "SUCCESS",
+		EventType: "LOGIN",
+		Actor:     models.Actor{ID: "alice", Type: "USER"},
+		Entity:    models.Entity{ID: "doc-1", Type: "DOCUMENT"},
+	}
+}
+
+// appendEvents принимает в поток stream n событий с event_id "<stream>-<номер>".
+func appendEvents(t *testing.T, c *Chain, stream string, n int) []*models.AuditEvent {
+	t.Helper()
+	events := make([]*models.AuditEvent, 0, n)
+	for i := range n {
+		event, err := c.Append(context.Background(), stream, newEvent(fmt.Sprintf("%s-%d", stream, i+1)))
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestChainRedelivery(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	c := newChain(store)
+	events := appendEvents(t, c, "s", 2)
+
+	// Повтор еще не сохраненного события возвращает его звено и не продвигает цепочку.
+	again, err := c.Append(ctx, "s", newEvent("s-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != events[1] {
+		t.Errorf("redelivered unsaved event got link %+v, want %+v", again.Chain, events[1].Chain)
+	}
+	if err := store.IndexBatch(ctx, events); err != nil {
+		t.Fatal(err)
+	}
+
+	// После перезапуска повтор находится в хранилище.
+	c = newChain(store)
+	again, err = c.Append(ctx, "s", newEvent("s-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *again.Chain != *events[0].Chain {
+		t.Errorf("redelivered stored event got link %+v, want %+v", again.Chain, events[0].Chain)
+	}
+	next := appendEvents(t, c, "t", 1)[0]
+	if next.Chain.Seq != 1 {
+		t.Errorf("first event of a new stream got seq %d", next.Chain.Seq)
+	}
+	event, err := c.Append(ctx, "s", newEvent("s-3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Chain.Seq != 3 || event.Chain.PrevHash != events[1].Chain.Hash {
+		t.Errorf("event after redeliveries got seq %d, prev_hash %s; want 3, %s", event.Chain.Seq, event.Chain.PrevHash, events[1].Chain.Hash)
+	}
+
+	// event_id чужого арендатора не считается повтором ни в памяти, ни в хранилище.
+	for _, id := range []string{"s-1", "s-3"} {
+		other := newEvent(id)
+		other.Tenant = "billing"
+		if _, err := c.Append(ctx, "s", other); !errors.Is(err, ErrConflict) {
+			t.Errorf("Append of %s by another tenant: got %v, want ErrConflict", id, err)
+		}
+	}
+
+	// Сброшенный поток забывает несохраненные события: их повтор получает звено заново.
+	c.Reset("s")
+	again, err = c.Append(ctx, "s", newEvent("s-3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == event || again.Chain.Seq != 3 {
+		t.Errorf("redelivery after Reset got link %+v, want a new link with seq 3", again.Chain)
+	}
+}
+
+func TestChainContinuesAfterPrune(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	events := appendEvents(t, newChain(store), "s", 3)
+	if err := store.IndexBatch(ctx, events); err != nil {
+		t.Fatal(err)
+	}
+	// Ретенция удаляет весь поток: номера продолжаются от отметки об удалении.
+	if _, err := store.DeleteEventsBefore(ctx, t0.Add(time.Hour), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := newChain(store).Append(ctx, "s", newEvent("s-4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Chain.Seq != 4 || event.Chain.PrevHash != events[2].Chain.Hash {
+		t.Errorf("got seq %d, prev_hash %s; want 4, %s", event.Chain.Seq, event.Chain.PrevHash, events[2].Chain.Hash)
+	}
+}
@@ -0,0 +1,213 @@
+package integrity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"witness/models"
+	"witness/storage"
+)
+
+// Verifier проверяет цепочки событий, принятых за интервал.
+type Verifier struct {
+	store storage.ChainStore
+}
+
+// NewVerifier создает проверку цепочек поверх хранилища.
+func NewVerifier(store storage.ChainStore) *Verifier {
+	return &Verifier{store: store}
+}
+
+// Verify проверяет события, принятые в интервале [from, to]:
+//   - хеш каждого события совпадает с пересчитанным (иначе MODIFIED);
+//   - номера в потоке идут без пропусков, кроме закрытых отметками об удалении
+//     по сроку хранения или при выгрузке в архив (иначе DELETED);
+//   - prev_hash ссылается на предыдущее событие потока, номера не повторяются
+//     и время приема не убывает (иначе REORDERED).
+//
+// Первое событие потока в интервале сверяется с предшественником вне интервала,
+// если тот сохранился или отмечен как удаленный; его отсутствие ошибкой не считается.
+func (v *Verifier) Verify(ctx context.Context, from, to time.Time) (*models.IntegrityReport, error) {
+	if to.Before(from) {
+		return nil, errors.New("'to' is before 'from'")
+	}
+
+	report := &models.IntegrityReport{
+		From:     from,
+		To:       to,
+		Problems: make([]*models.IntegrityProblem, 0),
+	}
+	problem := func(event *models.AuditEvent, seq int64, kind, format string, args ...any) {
+		report.Problems = append(report.Problems, &models.IntegrityProblem{
+			Stream:  event.Chain.Stream,
+			Seq:     seq,
+			EventID: event.EventID,
+			Kind:    kind,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	// Первые события потоков и пропуски сверяем с хранилищем после обхода:
+	// не все хранилища допускают запросы, пока открыт курсор обхода.
+	var firsts []*models.AuditEvent
+	var gaps []gap
+	var prev *models.AuditEvent
+	err := v.store.ScanChain(ctx, from, to, func(event *models.AuditEvent) error {
+		report.Checked++
+		link := event.Chain
+
+		hash, err := Hash(event)
+		if err != nil {
+			return err
+		}
+		if hash != link.Hash {
+			problem(event, link.Seq, models.IntegrityModified, "stored hash %s does not match computed %s", link.Hash, hash)
+		}
+
+		if prev == nil || prev.Chain.Stream != link.Stream {
+			report.Streams++
+			firsts = append(firsts, event)
+			prev = event
+			return nil
+		}
+
+		switch {
+		case link.Seq == prev.Chain.Seq:
+			problem(event, link.Seq, models.IntegrityReordered, "sequence number is repeated by event %s", prev.EventID)
+		case link.Seq > prev.Chain.Seq+1:
+			gaps = append(gaps, gap{next: event, first: prev.Chain.Seq + 1, last: link.Seq - 1})
+		case link.PrevHash != prev.Chain.Hash:
+			problem(event, link.Seq, models.IntegrityReordered, "prev_hash does not match hash of seq %d", prev.Chain.Seq)
+		}
+		if link.IngestedAt.Before(prev.Chain.IngestedAt) {
+			problem(event, link.Seq, models.IntegrityReordered, "ingested before seq %d", prev.Chain.Seq)
+		}
+
+		prev = event
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan chain: %w", err)
+	}
+
+	prunes := make(map[string][]*models.ChainPrune)
+	prunesOf := func(stream string) ([]*models.ChainPrune, error) {
+		if p, ok := prunes[stream]; ok {
+			return p, nil
+		}
+		p, err := v.store.ChainPrunes(ctx, stream)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load chain prunes of %s: %w", stream, err)
+		}
+		prunes[stream] = p
+		return p, nil
+	}
+
+	for _, g := range gaps {
+		link := g.next.Chain
+		p, err := prunesOf(link.Stream)
+		if err != nil {
+			return nil, err
+		}
+		// Пропуск фиксируется одной записью с номером первого отсутствующего неотмеченного события.
+		if missing, firstMissing := uncovered(p, g.first, g.last); missing > 0 {
+			report.Problems = append(report.Problems, &models.IntegrityProblem{
+				Stream:  link.Stream,
+				Seq:     firstMissing,
+				Kind:    models.IntegrityDeleted,
+				Message: fmt.Sprintf("%d event(s) missing between seq %d and %d", missing, g.first-1, link.Seq),
+			})
+			continue
+		}
+		if prune := pruneEnding(p, g.last); prune != nil && link.PrevHash != prune.LastHash {
+			problem(g.next, link.Seq, models.IntegrityReordered, "prev_hash does not match hash of pruned seq %d", g.last)
+		}
+	}
+
+	for _, first := range firsts {
+		link := first.Chain
+		if link.Seq <= 1 {
+			if link.PrevHash != "" {
+				problem(first, link.Seq, models.IntegrityReordered, "first event of the stream has prev_hash")
+			}
+			continue
+		}
+
+		pred, err := v.store.ChainEvent(ctx, link.Stream, link.Seq-1)
+		if errors.Is(err, storage.ErrNotFound) {
+			p, err := prunesOf(link.Stream)
+			if err != nil {
+				return nil, err
+			}
+			if prune := pruneEnding(p, link.Seq-1); prune != nil && link.PrevHash != prune.LastHash {
+				problem(first, link.Seq, models.IntegrityReordered, "prev_hash does not match hash of pruned seq %d", link.Seq-1)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if link.PrevHash != pred.Chain.Hash {
+			problem(first, link.Seq, models.IntegrityReordered, "prev_hash does not match hash of seq %d", link.Seq-1)
+		}
+	}
+
+	sort.SliceStable(report.Problems, func(i, j int) bool {
+		a, b := report.Problems[i], report.Problems[j]
+		if a.Stream != b.Stream {
+			return a.Stream < b.Stream
+		}
+		return a.Seq < b.Seq
+	})
+	report.Valid = len(report.Problems) == 0
+	return report, nil
+}
+
+// gap - отсутствующие номера [first, last] перед событием next.
+type gap struct {
+	next        *models.AuditEvent
+	first, last int64
+}
+
+// uncovered считает номера из [first, last], не закрытые отметками prunes
+// (по возрастанию FirstSeq), и возвращает их число и первый из них.
+func uncovered(prunes []*models.ChainPrune, first, last int64) (missing, firstMissing int64) {
+	pos := first
+	for _, prune := range prunes {
+		if prune.LastSeq < pos {
+			continue
+		}
+		if prune.FirstSeq > last {
+			break
+		}
+		if prune.FirstSeq > pos {
+			if missing == 0 {
+				firstMissing = pos
+			}
+			missing += prune.FirstSeq - pos
+		}
+		pos = prune.LastSeq + 1
+		if pos > last {
+			return missing, firstMissing
+		}
+	}
+	if pos <= last {
+		if missing == 0 {
+			firstMissing = pos
+		}
+		missing += last - pos + 1
+	}
+	return missing, firstMissing
+}
+
+// pruneEnding возвращает отметку, последний номер которой seq, или nil.
+func pruneEnding(prunes []*models.ChainPrune, seq int64) *models.ChainPrune {
+	for _, prune := range prunes {
+		if prune.LastSeq == seq {
+			return prune
+		}
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package integrity
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+	"witness/models"
+	"witness/storage/memory"
+)
+
+// stored копирует события, чтобы подделка не задевала исходную цепочку.
+func stored(events []*models.AuditEvent) []*models.AuditEvent {
+	copies := make([]*models.AuditEvent, 0, len(events))
+	for _, event := range events {
+		e := *event
+		link := *event.Chain
+		e.Chain = &link
+		copies = append(copies, &e)
+	}
+	return copies
+}
+
+// rehash пересчитывает хеш события, как сделал бы подделывающий его злоумышленник.
+func rehash(t *testing.T, event *models.AuditEvent) {
+	t.Helper()
+	hash, err := Hash(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.Chain.Hash = hash
+}
+
+// problems сводит отчет к строкам "KIND seq" для сравнения.
+func problems(report *models.IntegrityReport) []string {
+	out := make([]string, 0, len(report.Problems))
+	for _, p := range report.Problems {
+		out = append(out, fmt.Sprintf("%s %d", p.Kind, p.Seq))
+	}
+	return out
+}
+
+func TestVerify(t *testing.T) {
+	// Поток s из шести событий; поток t не подделывается и нарушений не дает.
+	c := newChain(memory.New())
+	chain := appendEvents(t, c, "s", 6)
+	other := appendEvents(t, c, "t", 2)
+
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, events []*models.AuditEvent) []*models.AuditEvent
+		want   []string
+	}{
+		{
+			name: "intact",
+		},
+		{
+			name: "modified",
+			tamper: func(t *testing.T, events []*models.AuditEvent) []*models.AuditEvent {
+				events[2].Status = "FAILURE"
+				return events
+			},
+			want: []string{"MODIFIED 3"},
+		},
+		{
+			name: "modified link",
+			tamper: func(t *testing.T, events []*models.AuditEvent) []*models.AuditEvent {
+				events[2].Chain.Hash = events[1].Chain.Hash
+				return events
+			},
+			// Следующее событие ссылается на настоящий хеш, а не на подмененный.
+			want: []string{"MODIFIED 3", "REORDERED 4"},
+		},
+		{
+			name: "deleted",
+			tamper: func(t *testing.T, events []*models.AuditEvent) []*models.AuditEvent {
+				return slices.Delete(events, 2, 3)
+			},
+			want: []string{"DELETED 3"},
+		},
+		{
+			name: "deleted range",
+			tamper: func(t *testing.T, events []*models.AuditEvent) []*models.AuditEvent {
+				return slices.Delete(events, 1, 4)
+			},
+			want: []string{"DELETED 2"},
+		},
+		{
+			// Удаление конца потока цепочкой не обнаружить: его находит только точка проверки.
+			name: "deleted tail",
+			tamper: func(t *testing.T, events []*models.AuditEvent) []*models.AuditEvent {
+				return events[:4]
+			},
+		},
+		{
+			name: "relinked",
+			tamper: func(t *testing.T, events []*models.AuditEvent) []*models.AuditEvent {
+				events[3].Chain.PrevHash = events[1].Chain.Hash
+				rehash(t, events[3])
+				return events
+			},
+			// С пересчитанным хешем расходится и ссылка следующего события.
+			want: []string{"REORDERED 4", "REORDERED 5"},
+		},
+		{
+			// События меняются местами вместе с номерами и временем приема, хеши пересчитаны.
+			name: "swapped",
+			tamper: func(t *testing.T, events []*models.AuditEvent) []*models.AuditEvent {
+				events[2].Chain, events[3].Chain = events[3].Chain, events[2].Chain
+				rehash(t, events[2])
+				rehash(t, events[3])
+				return events
+			},
+			want: []string{"REORDERED 4", "REORDERED 5"},
+		},
+		{
+			name: "ingested out of order",
+			tamper: func(t *testing.T, events []*models.AuditEvent) []*models.AuditEvent {
+				events[4].Chain.IngestedAt = events[2].Chain.IngestedAt.Add(-time.Second)
+				rehash(t, events[4])
+				// Следующее событие подогнано под новый хеш, чтобы осталось одно нарушение.
+				events[5].Chain.PrevHash = events[4].Chain.Hash
+				rehash(t, events[5])
+				return events
+			},
+			want: []string{"REORDERED 5"},
+		},
+		{
+			name: "repeated seq",
+			tamper: func(t *testing.T, events []*models.AuditEvent) []*models.AuditEvent {
+				dup := stored(events[5:])[0]
+				dup.EventID = "s-dup"
+				rehash(t, dup)
+				return append(events, dup)
+			},
+			want: []string{"REORDERED 6"},
+		},
+		{
+			name: "first event with prev_hash",
+			tamper: func(t *testing.T, events []*models.AuditEvent) []*models.AuditEvent {
+				// Остальная цепочка перестроена под новый хеш первого события.
+				prev := "forged"
+				for _, event := range events {
+					event.Chain.PrevHash = prev
+					rehash(t, event)
+					prev = event.Chain.Hash
+				}
+				return events
+			},
+			want: []string{"REORDERED 1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			events := stored(chain)
+			if tt.tamper != nil {
+				events = tt.tamper(t, events)
+			}
+			store := memory.New()
+			if err := store.IndexBatch(ctx, append(events, other...)); err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := NewVerifier(store).Verify(ctx, t0, t0.Add(time.Hour))
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if got := problems(report); !slices.Equal(got, tt.want) {
+				t.Errorf("got problems %q, want %q", got, tt.want)
+			}
+			if report.Valid != (len(tt.want) == 0) || report.Streams != 2 || report.Checked != len(events)+len(other) {
+				t.Errorf("got valid %v, %d streams, %d checked", report.Valid, report.Streams, report.Checked)
+			}
+		})
+	}
+}
+
+func TestVerifyInterval(t *testing.T) {
+	ctx := context.Background()
+	events := stored(appendEvents(t, newChain(memory.New()), "s", 6))
+	// Первое событие интервала (seq 4) сверяется с предшественником вне интервала.
+	from := events[3].Chain.IngestedAt
+
+	store := memory.New()
+	if err := store.IndexBatch(ctx, events); err != nil {
+		t.Fatal(err)
+	}
+	report, err := NewVerifier(store).Verify(ctx, from, t0.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid || report.Checked != 3 {
+		t.Errorf("intact interval: got valid %v, %d checked, problems %q", report.Valid, report.Checked, problems(report))
+	}
+
+	events[2].Status = "FAILURE"
+	rehash(t, events[2])
+	if err := store.IndexBatch(ctx, events[2:3]); err != nil {
+		t.Fatal(err)
+	}
+	report, err = NewVerifier(store).Verify(ctx, from, t0.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := problems(report); !slices.Equal(got, []string{"REORDERED 4"}) {
+		t.Errorf("predecessor replaced outside the interval: got problems %q", got)
+	}
+}
+
+func TestVerifyPrunes(t *testing.T) {
+	tests := []struct {
+		name string
+		// expired - индексы событий, удаляемых по сроку хранения (с отметкой);
+		// lost - индексы событий, пропавших без отметки.
+		expired, lost []int
+		// relink - индекс события, prev_hash которого подменяется (-1 - нет).
+		relink int
+		want   []string
+	}{
+		{name: "pruned middle", expired: []int{1, 2}, relink: -1},
+		{name: "pruned head", expired: []int{0, 1}, relink: -1},
+		{name: "pruned separately", expired: []int{1, 3}, relink: -1},
+		{name: "pruned and lost", expired: []int{1, 2}, lost: []int{3}, relink: -1, want: []string{"DELETED 4"}},
+		{name: "lost before pruned", expired: []int{2, 3}, lost: []int{1}, relink: -1, want: []string{"DELETED 2"}},
+		{name: "lost between pruned", expired: []int{1, 3}, lost: []int{2}, relink: -1, want: []string{"DELETED 3"}},
+		{name: "relinked after prune", expired: []int{1, 2}, relink: 3, want: []string{"REORDERED 4", "REORDERED 5"}},
+		{name: "relinked after pruned head", expired: []int{0, 1}, relink: 2, want: []string{"REORDERED 3", "REORDERED 4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := newChain(memory.New())
+			var keep []*models.AuditEvent
+			for i := range 6 {
+				event := newEvent(fmt.Sprintf("s-%d", i+1))
+				if slices.Contains(tt.expired, i) {
+					event.Timestamp = t0.Add(-48 * time.Hour)
+				}
+				event, err := c.Append(ctx, "s", event)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if i == tt.relink {
+					event.Chain.PrevHash = "forged"
+					rehash(t, event)
+				}
+				if !slices.Contains(tt.lost, i) {
+					keep = append(keep, event)
+				}
+			}
+			store := memory.New()
+			if err := store.IndexBatch(ctx, keep); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.DeleteEventsBefore(ctx, t0.Add(-time.Hour), nil); err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := NewVerifier(store).Verify(ctx, t0, t0.Add(time.Hour))
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if got := problems(report); !slices.Equal(got, tt.want) {
+				t.Errorf("got problems %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUncovered(t *testing.T) {
+	prunes := []*models.ChainPrune{
+		{FirstSeq: 2, LastSeq: 3},
+		{FirstSeq: 6, LastSeq: 8},
+		{FirstSeq: 9, LastSeq: 9},
+	}
+	tests := []struct {
+		first, last           int64
+		missing, firstMissing int64
+	}{
+		{first: 2, last: 3},
+		{first: 3, last: 3},
+		{first: 6, last: 9},
+		{first: 1, last: 3, missing: 1, firstMissing: 1},
+		{first: 2, last: 5, missing: 2, firstMissing: 4},
+		{first: 4, last: 9, missing: 2, firstMissing: 4},
+		{first: 1, last: 12, missing: 6, firstMissing: 1},
+		{first: 7, last: 12, missing: 3, firstMissing: 10},
+		{first: 10, last: 12, missing: 3, firstMissing: 10},
+	}
+	for _, tt := range tests {
+		missing, firstMissing := uncovered(prunes, tt.first, tt.last)
+		if missing != tt.missing || firstMissing != tt.firstMissing {
+			t.Errorf("uncovered(%d, %d) = %d, %d; want %d, %d", tt.first, tt.last, missing, firstMissing, tt.missing, tt.firstMissing)
+		}
+	}
+	if missing, firstMissing := uncovered(nil, 4, 5); missing != 2 || firstMissing != 4 {
+		t.Errorf("uncovered without prunes = %d, %d; want 2, 4", missing, firstMissing)
+	}
+
+	if prune := pruneEnding(prunes, 8); prune != prunes[1] {
+		t.Errorf("pruneEnding(8) = %+v", prune)
+	}
+	if prune := pruneEnding(prunes, 7); prune != nil {
+		t.Errorf("pruneEnding(7) = %+v, want nil", prune)
+	}
+}
@@ -0,0 +1,261 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"witness/ingest"
+	"witness/models"
+
+	"github.com/IBM/sarama"
+)
+
+// Привязка CloudEvents к Kafka: в binary mode атрибуты передаются заголовками ce_<имя>,
+// а значение сообщения - это data; в structured mode значение - событие CloudEvents целиком в JSON.
+const (
+	cloudEventsPrefix      = "ce_"
+	cloudEventsContentType = "application/cloudevents+json"
+	contentTypeHeader      = "content-type"
+)
+
+// cloudEventsMapped - атрибуты CloudEvents, которые переносятся в поля события или описывают data.
+// Остальные атрибуты (subject, dataschema и расширения) попадают в details с префиксом ce_.
+var cloudEventsMapped = map[string]bool{
+	"specversion": true, "id": true, "source": true, "type": true, "time": true,
+	"datacontenttype": true, "data": true, "data_base64": true,
+}
+
+// auditFields - поля JSON события аудита. Остальные поля объекта data попадают в details.
+var auditFields = map[string]bool{
+	"event_id": true, "timestamp": true, "status": true, "event_type": true,
+	"actor": true, "entity": true, "context": true, "security": true, "details": true,
+	"redactions": true, "signature_status": true, "tenant": true, "pii": true, "chain": true,
+}
+
+// cloudEvent - событие CloudEvents 1.0, извлеченное из сообщения Kafka.
+type cloudEvent struct {
+	attributes  map[string]any
+	contentType string
+	data        []byte
+	// binary - data передана в data_base64: это двоичные данные, если тип содержимого не JSON.
+	binary bool
+}
+
+// unwrapCloudEvent переводит сообщение CloudEvents (binary или structured mode) в JSON события
+// аудита. signed - байты, которые подписывает продюсер: значение сообщения как есть.
+// Сообщение, не являющееся CloudEvents, возвращается без изменений и с signed = nil.
+func unwrapCloudEvent(message *sarama.ConsumerMessage) (value, signed []byte, err error) {
+	var ce *cloudEvent
+	switch {
+	case header(message, cloudEventsPrefix+"specversion") != "":
+		ce = binaryCloudEvent(message)
+	case isStructuredCloudEvent(message):
+		if ce, err = structuredCloudEvent(message.Value); err != nil {
+			return nil, nil, err
+		}
+	default:
+		return message.Value, nil, nil
+	}
+
+	event, err := ce.auditEvent(message.Timestamp)
+	if err != nil {
+		return nil, nil, err
+	}
+	value, err = json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, message.Value, nil
+}
+
+// isStructuredCloudEvent распознает structured mode по типу содержимого или, если продюсер
+// его не указал, по атрибуту specversion в JSON.
+func isStructuredCloudEvent(message *sarama.ConsumerMessage) bool {
+	if mediaType, _, _ := mime.ParseMediaType(header(message, contentTypeHeader)); mediaType != "" {
+		return mediaType == cloudEventsContentType
+	}
+	if !bytes.Contains(message.Value, []byte(`"specversion"`)) {
+		return false
+	}
+	var probe struct {
+		SpecVersion string `json:"specversion"`
+	}
+	return json.Unmarshal(message.Value, &probe) == nil && probe.SpecVersion != ""
+}
+
+func binaryCloudEvent(message *sarama.ConsumerMessage) *cloudEvent {
+	ce := &cloudEvent{
+		attributes:  make(map[string]any),
+		contentType: header(message, contentTypeHeader),
+		data:        message.Value,
+	}
+	for _, h := range message.Headers {
+		if h == nil {
+			continue
+		}
+		if name, ok := strings.CutPrefix(string(h.Key), cloudEventsPrefix); ok && name != "" {
+			ce.attributes[name] = string(h.Value)
+		}
+	}
+	return ce
+}
+
+func structuredCloudEvent(value []byte) (*cloudEvent, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(value, &raw); err != nil {
+		return nil, fmt.Errorf("%w: invalid cloudevent: %v", ingest.ErrMalformed, err)
+	}
+	ce := &cloudEvent{attributes: make(map[string]any, len(raw))}
+	for name, v := range raw {
+		switch name {
+		case "data":
+			ce.data = v
+		case "data_base64":
+			var encoded string
+			if err := json.Unmarshal(v, &encoded); err != nil {
+				return nil, fmt.Errorf("%w: invalid cloudevent data_base64: %v", ingest.ErrMalformed, err)
+			}
+			data, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid cloudevent data_base64: %v", ingest.ErrMalformed, err)
+			}
+			ce.data, ce.binary = data, true
+		default:
+			var attr any
+			if err := json.Unmarshal(v, &attr); err != nil {
+				return nil, fmt.Errorf("%w: invalid cloudevent attribute %s: %v", ingest.ErrMalformed, name, err)
+			}
+			ce.attributes[name] = attr
+		}
+	}
+	ce.contentType = ce.attribute("datacontenttype")
+	if _, ok := raw["data"]; ok && ce.contentType == "" {
+		// data в structured mode без datacontenttype - JSON.
+		ce.contentType = "application/json"
+	}
+	// Данные не в JSON (текст, XML) передаются в data строкой JSON: нужна сама строка.
+	if text := bytes.TrimSpace(ce.data); !ce.binary && !isJSON(ce.contentType) && len(text) > 0 && text[0] == '"' {
+		var s string
+		if err := json.Unmarshal(text, &s); err != nil {
+			return nil, fmt.Errorf("%w: invalid cloudevent data: %v", ingest.ErrMalformed, err)
+		}
+		ce.data = []byte(s)
+	}
+	return ce, nil
+}
+
+// attribute возвращает атрибут строкой.
+func (ce *cloudEvent) attribute(name string) string {
+	switch v := ce.attributes[name].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// auditEvent строит событие аудита. Объект JSON в data - тело события (status, actor, entity,
+// details...), а id, time, source и type главнее его полей. Без time берется время из data,
+// иначе время сообщения Kafka received.
+func (ce *cloudEvent) auditEvent(received time.Time) (*models.AuditEvent, error) {
+	for _, name := range []string{"id", "source", "type"} {
+		if ce.attribute(name) == "" {
+			return nil, fmt.Errorf("%w: cloudevent attribute %s is required", ingest.ErrMalformed, name)
+		}
+	}
+
+	event := &models.AuditEvent{}
+	details := make(map[string]any)
+	if err := ce.decodeData(event, details); err != nil {
+		return nil, err
+	}
+
+	event.EventID = ce.attribute("id")
+	event.EventType = ce.attribute("type")
+	event.Context.SourceService = ce.attribute("source")
+	if t := ce.attribute("time"); t != "" {
+		ts, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid cloudevent time %q", ingest.ErrMalformed, t)
+		}
+		event.Timestamp = ts.UTC()
+	}
+	if event.Timestamp.IsZero() && !received.IsZero() {
+		event.Timestamp = received.UTC()
+	}
+
+	for name, v := range ce.attributes {
+		if !cloudEventsMapped[name] {
+			details[cloudEventsPrefix+name] = v
+		}
+	}
+	if len(details) > 0 {
+		if event.Details == nil {
+			event.Details = make(map[string]any, len(details))
+		}
+		for k, v := range details {
+			event.Details[k] = v
+		}
+	}
+	return event, nil
+}
+
+// decodeData разбирает data. Объект JSON заполняет event, а его поля вне схемы события
+// попадают в details; любые другие данные сохраняются в details.data - строкой, значением
+// JSON или, для двоичных данных, в base64.
+func (ce *cloudEvent) decodeData(event *models.AuditEvent, details map[string]any) error {
+	data := bytes.TrimSpace(ce.data)
+	if len(data) == 0 {
+		return nil
+	}
+	if !isJSON(ce.contentType) || (ce.contentType == "" && (ce.binary || !json.Valid(data))) {
+		if !ce.binary && utf8.Valid(ce.data) {
+			details["data"] = string(ce.data)
+		} else {
+			details["data"] = base64.StdEncoding.EncodeToString(ce.data)
+		}
+		return nil
+	}
+	if data[0] != '{' {
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("%w: invalid cloudevent data: %v", ingest.ErrMalformed, err)
+		}
+		details["data"] = v
+		return nil
+	}
+
+	if err := json.Unmarshal(data, event); err != nil {
+		return fmt.Errorf("%w: invalid cloudevent data: %v", ingest.ErrMalformed, err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("%w: invalid cloudevent data: %v", ingest.ErrMalformed, err)
+	}
+	for name, v := range fields {
+		if !auditFields[name] {
+			details[name] = v
+		}
+	}
+	return nil
+}
+
+// isJSON сообщает, что данные с типом содержимого contentType - JSON. Без типа данные
+// считаются JSON, как и у обычных сообщений Witness.
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
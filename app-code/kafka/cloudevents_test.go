@@ -0,0 +1,225 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+	"witness/ingest"
+	"witness/models"
+
+	"github.com/IBM/sarama"
+)
+
+var received = time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+// message создает сообщение Kafka с заголовками headers (имя, значение, имя, значение...).
+func message(value string, headers ...string) *sarama.ConsumerMessage {
+	m := &sarama.ConsumerMessage{Topic: "audit", Value: []byte(value), Timestamp: received}
+	for i := 0; i+1 < len(headers); i += 2 {
+		m.Headers = append(m.Headers, &sarama.RecordHeader{Key: []byte(headers[i]), Value: []byte(headers[i+1])})
+	}
+	return m
+}
+
+// binary создает сообщение CloudEvents в binary mode с обязательными атрибутами.
+func binary(value string, headers ...string) *sarama.ConsumerMessage {
+	return message(value, append([]string{
+		"ce_specversion", "1.0", "ce_id", "ce-1", "ce_source", "billing-service", "ce_type", "INVOICE_PAID",
+	}, headers...)...)
+}
+
+// structured создает сообщение CloudEvents в structured mode: обязательные атрибуты и members.
+func structured(members string, headers ...string) *sarama.ConsumerMessage {
+	value := `{"specversion": "1.0", "id": "ce-1", "source": "billing-service", "type": "INVOICE_PAID"`
+	if members != "" {
+		value += ", " + members
+	}
+	return message(value+"}", headers...)
+}
+
+// expected возвращает событие с атрибутами из binary и structured и полями fields.
+func expected(fields func(event *models.AuditEvent)) models.AuditEvent {
+	event := models.AuditEvent{
+		EventID:   "ce-1",
+		EventType: "INVOICE_PAID",
+		Timestamp: received,
+		Context:   models.Context{SourceService: "billing-service"},
+	}
+	if fields != nil {
+		fields(&event)
+	}
+	return event
+}
+
+func TestUnwrapCloudEvent(t *testing.T) {
+	body := `{"status": "SUCCESS", "actor": {"id": "user-7", "type": "USER"}, "amount": 1500}`
+	paid := expected(func(e *models.AuditEvent) {
+		e.Status = "SUCCESS"
+		e.Actor = models.Actor{ID: "user-7", Type: "USER"}
+		e.Details = map[string]any{"amount": 1500.0}
+	})
+
+	tests := []struct {
+		name    string
+		message *sarama.ConsumerMessage
+		want    models.AuditEvent
+	}{
+		{
+			name:    "binary json data",
+			message: binary(body, "content-type", "application/json"),
+			want:    paid,
+		},
+		{
+			name:    "binary data without content type",
+			message: binary(body),
+			want:    paid,
+		},
+		{
+			name:    "structured with content type",
+			message: structured(`"data": `+body, "content-type", "application/cloudevents+json; charset=utf-8"),
+			want:    paid,
+		},
+		{
+			name:    "structured without content type",
+			message: structured(`"datacontenttype": "application/json", "data": ` + body),
+			want:    paid,
+		},
+		{
+			name:    "structured json data_base64",
+			message: structured(`"datacontenttype": "application/json", "data_base64": "eyJzdGF0dXMiOiAiU1VDQ0VTUyJ9"`),
+			want:    expected(func(e *models.AuditEvent) { e.Status = "SUCCESS" }),
+		},
+		{
+			name:    "structured binary data_base64",
+			message: structured(`"data_base64": "AAEC/w=="`),
+			want:    expected(func(e *models.AuditEvent) { e.Details = map[string]any{"data": "AAEC/w=="} }),
+		},
+		{
+			name:    "binary text data",
+			message: binary("invoice 42 paid", "content-type", "text/plain"),
+			want:    expected(func(e *models.AuditEvent) { e.Details = map[string]any{"data": "invoice 42 paid"} }),
+		},
+		{
+			name:    "binary non-json data without content type",
+			message: binary("invoice 42 paid"),
+			want:    expected(func(e *models.AuditEvent) { e.Details = map[string]any{"data": "invoice 42 paid"} }),
+		},
+		{
+			name:    "binary invalid utf-8",
+			message: binary("\x00\x01\xff", "content-type", "application/octet-stream"),
+			want:    expected(func(e *models.AuditEvent) { e.Details = map[string]any{"data": "AAH/"} }),
+		},
+		{
+			name:    "binary xml data",
+			message: binary("<paid/>", "content-type", "application/xml"),
+			want:    expected(func(e *models.AuditEvent) { e.Details = map[string]any{"data": "<paid/>"} }),
+		},
+		{
+			// Данные не в JSON в structured mode - строка JSON, в details попадает ее значение.
+			name:    "structured text data",
+			message: structured(`"datacontenttype": "text/plain", "data": "invoice \"42\" paid"`),
+			want:    expected(func(e *models.AuditEvent) { e.Details = map[string]any{"data": `invoice "42" paid`} }),
+		},
+		{
+			name:    "structured xml data",
+			message: structured(`"datacontenttype": "application/xml", "data": "<paid amount=\"1500\"/>"`),
+			want:    expected(func(e *models.AuditEvent) { e.Details = map[string]any{"data": `<paid amount="1500"/>`} }),
+		},
+		{
+			name:    "structured json array data",
+			message: structured(`"data": [1, "two"]`),
+			want:    expected(func(e *models.AuditEvent) { e.Details = map[string]any{"data": []any{1.0, "two"}} }),
+		},
+		{
+			name:    "structured json string data",
+			message: structured(`"data": "paid"`),
+			want:    expected(func(e *models.AuditEvent) { e.Details = map[string]any{"data": "paid"} }),
+		},
+		{
+			name: "attributes take precedence over data",
+			message: structured(`"time": "2026-10-18T13:00:00+03:00", "data": {
+				"event_id": "data-1", "event_type": "DATA_TYPE", "timestamp": "2020-01-01T00:00:00Z",
+				"status": "FAILURE", "context": {"source_service": "forged", "trace_id": "trace-1"}}`),
+			want: expected(func(e *models.AuditEvent) {
+				e.Status, e.Timestamp = "FAILURE", time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+				e.Context.TraceID = "trace-1"
+			}),
+		},
+		{
+			name:    "timestamp from data without time",
+			message: binary(`{"timestamp": "2026-10-17T08:00:00Z"}`),
+			want:    expected(func(e *models.AuditEvent) { e.Timestamp = time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC) }),
+		},
+		{
+			name: "extensions and data details",
+			message: binary(`{"details": {"invoice": "42"}, "amount": 1500}`,
+				"ce_subject", "invoice-42", "ce_traceparent", "00-abc-01", "ce_time", "2026-10-18T10:00:00Z", "witness-signature", "sig"),
+			want: expected(func(e *models.AuditEvent) {
+				e.Timestamp = time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+				e.Details = map[string]any{"invoice": "42", "amount": 1500.0, "ce_subject": "invoice-42", "ce_traceparent": "00-abc-01"}
+			}),
+		},
+		{
+			name:    "structured extension values",
+			message: structured(`"subject": "invoice-42", "sequence": 7, "data": {}`),
+			want:    expected(func(e *models.AuditEvent) { e.Details = map[string]any{"ce_subject": "invoice-42", "ce_sequence": 7.0} }),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, signed, err := unwrapCloudEvent(tt.message)
+			if err != nil {
+				t.Fatalf("unwrapCloudEvent: %v", err)
+			}
+			if !bytes.Equal(signed, tt.message.Value) {
+				t.Errorf("signed bytes: got %q, want the message value", signed)
+			}
+			var got models.AuditEvent
+			if err := json.Unmarshal(value, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnwrapPlainMessage(t *testing.T) {
+	for name, m := range map[string]*sarama.ConsumerMessage{
+		"witness event":         message(`{"event_id": "e1", "status": "SUCCESS"}`),
+		"json content type":     message(`{"specversion": "1.0", "id": "e1"}`, "content-type", "application/json"),
+		"specversion in string": message(`{"event_id": "e1", "details": {"note": "\"specversion\""}}`),
+		"not json":              message(`"specversion"`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			value, signed, err := unwrapCloudEvent(m)
+			if err != nil || signed != nil || !bytes.Equal(value, m.Value) {
+				t.Errorf("got %q, signed %q, error %v; want the message as is", value, signed, err)
+			}
+		})
+	}
+}
+
+func TestUnwrapMalformedCloudEvent(t *testing.T) {
+	for name, m := range map[string]*sarama.ConsumerMessage{
+		"binary without id": message("{}", "ce_specversion", "1.0", "ce_source", "s", "ce_type", "T"),
+		"structured without source": message(`{"specversion": "1.0", "id": "e1", "type": "T"}`,
+			"content-type", "application/cloudevents+json"),
+		"structured invalid json":  message(`{"specversion": "1.0",`, "content-type", "application/cloudevents+json"),
+		"invalid time":             binary("{}", "ce_time", "yesterday"),
+		"invalid data_base64":      structured(`"data_base64": "not base64!"`),
+		"data_base64 not a string": structured(`"data_base64": 42`),
+		"invalid json data":        binary(`{"status": `, "content-type", "application/json"),
+		"data of wrong type":       binary(`{"status": 42}`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if value, _, err := unwrapCloudEvent(m); !errors.Is(err, ingest.ErrMalformed) {
+				t.Errorf("got %q, %v; want ErrMalformed", value, err)
+			}
+		})
+	}
+}
@@ -2,40 +2,41 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"sync"
-	"time"
+	"witness/ingest"
 	"witness/models"
-	"witness/opensearch"
+	"witness/signing"
+	"witness/tenant"
 
 	"github.com/IBM/sarama"
 )
 
 // Consumer представляет собой consumer group для Kafka.
 type Consumer struct {
-	ready         chan bool
-	osClient      *opensearch.Client
-	eventBuffer   []*models.AuditEvent
-	bufferMutex   sync.Mutex
-	maxBufferSize int
-	flushInterval time.Duration
+	ready    chan bool
+	pipeline *ingest.Pipeline
+	buffer   *ingest.Buffer
+	tenants  *tenant.Resolver
 }
 
-// NewConsumer создает новый экземпляр consumer'a.
-func NewConsumer(osClient *opensearch.Client) *Consumer {
+// NewConsumer создает новый экземпляр consumer'a. Каждое сообщение проходит
+// через конвейер приема; цепочка хешей строится по партициям, арендатор
+// определяется по топику или заголовку сообщения. Подготовленные события
+// сохраняются через общий буфер записи.
+func NewConsumer(pipeline *ingest.Pipeline, buffer *ingest.Buffer, tenants *tenant.Resolver) *Consumer {
 	return &Consumer{
-		ready:         make(chan bool),
-		osClient:      osClient,
-		eventBuffer:   make([]*models.AuditEvent, 0, 100),
-		maxBufferSize: 100,
-		flushInterval: 5 * time.Second,
+		ready:    make(chan bool),
+		pipeline: pipeline,
+		buffer:   buffer,
+		tenants:  tenants,
 	}
 }
 
 // StartConsumerGroup запускает consumer group.
-func (c *Consumer) StartConsumerGroup(ctx context.Context, wg *sync.WaitGroup, brokers []string, groupID, topic string) {
+func (c *Consumer) StartConsumerGroup(ctx context.Context, wg *sync.WaitGroup, brokers []string, groupID string, topics []string) {
 	defer wg.Done()
 
 	config := sarama.NewConfig()
@@ -48,15 +49,12 @@ func (c *Consumer) StartConsumerGroup(ctx context.Context, wg *sync.WaitGroup, b
 		return
 	}
 
-	// Запускаем периодическую отправку буфера
-	go c.runFlusher(ctx)
-
 	slog.Info("kafka consumer group started")
 
 	for {
 		// `Consume` должен вызываться в бесконечном цикле, т.к. он завершается
 		// при ребалансировке сессии.
-		if err := client.Consume(ctx, []string{topic}, c); err != nil {
+		if err := client.Consume(ctx, topics, c); err != nil {
 			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
 				slog.Info("consumer group closed")
 				return
@@ -72,58 +70,41 @@ func (c *Consumer) StartConsumerGroup(ctx context.Context, wg *sync.WaitGroup, b
 	}
 }
 
-func (c *Consumer) runFlusher(ctx context.Context) {
-	ticker := time.NewTicker(c.flushInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			slog.Info("flusher context done, performing final flush")
-			c.flushBuffer(context.Background()) // Используем новый контекст для последней отправки
-			return
-		case <-ticker.C:
-			c.flushBuffer(ctx)
-		}
-	}
-}
-
-// flushBuffer отправляет накопленные события в OpenSearch.
-func (c *Consumer) flushBuffer(ctx context.Context) {
-	c.bufferMutex.Lock()
-	if len(c.eventBuffer) == 0 {
-		c.bufferMutex.Unlock()
-		return
-	}
-
-	// Копируем срез, чтобы можно было разблокировать мьютекс
-	eventsToFlush := make([]*models.AuditEvent, len(c.eventBuffer))
-	copy(eventsToFlush, c.eventBuffer)
-	c.eventBuffer = c.eventBuffer[:0] // Очищаем буфер
-	c.bufferMutex.Unlock()
-
-	err := c.osClient.IndexEventsBulk(ctx, eventsToFlush)
-	if err != nil {
-		slog.Error("failed to flush events to opensearch", "error", err)
-		// TODO: Реализовать механизм retry или отправки в dead-letter-queue
-	} else {
-		slog.Info("flushed events to opensearch", "count", len(eventsToFlush))
-	}
-}
-
 // Setup вызывается при начале новой сессии, перед ConsumeClaim.
-func (c *Consumer) Setup(sarama.ConsumerGroupSession) error {
+func (c *Consumer) Setup(session sarama.ConsumerGroupSession) error {
+	// Партиции могли перейти от другого экземпляра: продолжаем их цепочки с сохраненного.
+	// Цепочки других транспортов принадлежат этому экземпляру и не сбрасываются.
+	c.pipeline.Reset(claimedStreams(session)...)
 	close(c.ready)
 	return nil
 }
 
 // Cleanup вызывается в конце сессии, после завершения всех циклов ConsumeClaim.
-func (c *Consumer) Cleanup(sarama.ConsumerGroupSession) error {
-	// Отправляем остатки из буфера перед завершением
-	c.flushBuffer(context.Background())
+func (c *Consumer) Cleanup(session sarama.ConsumerGroupSession) error {
+	// Отправляем остатки из буфера перед завершением: сохраненные сообщения
+	// будут подтверждены вместе с коммитом смещений сессии.
+	if err := c.buffer.Flush(context.Background()); err != nil {
+		// Несохраненные сообщения не подтверждены и будут прочитаны снова, возможно
+		// другим экземпляром. Их звенья убираем, а цепочки продолжим с сохраненного.
+		streams := claimedStreams(session)
+		n := c.buffer.Discard(streams...)
+		c.pipeline.Reset(streams...)
+		slog.Error("failed to flush kafka events, they will be consumed again", "discarded", n, "error", err)
+	}
 	return nil
 }
 
+// claimedStreams возвращает потоки цепочек партиций сессии.
+func claimedStreams(session sarama.ConsumerGroupSession) []string {
+	var streams []string
+	for topic, partitions := range session.Claims() {
+		for _, partition := range partitions {
+			streams = append(streams, fmt.Sprintf("%s/%d", topic, partition))
+		}
+	}
+	return streams
+}
+
 // ConsumeClaim - основной цикл обработки сообщений.
 func (c *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	for {
@@ -134,27 +115,51 @@ func (c *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim saram
 				return nil
 			}
 
-			var event models.AuditEvent
-			if err := json.Unmarshal(message.Value, &event); err != nil {
-				slog.Error("failed to unmarshal kafka message", "error", err)
+			// Пока хранилище не успевает, не читаем дальше: сообщения подождут в Kafka.
+			if err := c.buffer.Wait(session.Context()); err != nil {
+				return nil
+			}
+			stream := fmt.Sprintf("%s/%d", message.Topic, message.Partition)
+			// Сообщения CloudEvents сначала переводятся в событие Witness.
+			value, signed, err := unwrapCloudEvent(message)
+			var event *models.AuditEvent
+			if err == nil {
+				event, err = c.pipeline.Process(session.Context(), stream, value, ingest.Meta{
+					Signature: header(message, signing.Header),
+					Tenant:    c.tenants.Resolve(message.Topic, header(message, tenant.Header)),
+					Signed:    signed,
+				})
+			}
+			// Сообщение подтверждается только после записи его события и всех предыдущих:
+			// смещение в Kafka подтверждает и все более ранние сообщения партиции.
+			mark := func() { session.MarkMessage(message, "") }
+			if errors.Is(err, ingest.ErrMalformed) {
+				slog.Error("skipping malformed kafka message", "stream", stream, "error", err)
 				// Пропускаем сбойное сообщение, но коммитим его, чтобы не читать снова.
-				session.MarkMessage(message, "")
+				c.buffer.AddFunc(session.Context(), mark)
 				continue
 			}
-
-			c.bufferMutex.Lock()
-			c.eventBuffer = append(c.eventBuffer, &event)
-			bufferSize := len(c.eventBuffer)
-			c.bufferMutex.Unlock()
-
-			if bufferSize >= c.maxBufferSize {
-				c.flushBuffer(session.Context())
+			if err != nil {
+				// Не подготовленное событие не сохраняем; сообщение будет прочитано
+				// повторно в новой сессии.
+				slog.Error("failed to process kafka message", "stream", stream, "error", err)
+				return err
 			}
 
-			session.MarkMessage(message, "")
+			c.buffer.AddFunc(session.Context(), mark, event)
 
 		case <-session.Context().Done():
 			return nil
 		}
 	}
 }
+
+// header возвращает значение заголовка сообщения или пустую строку.
+func header(message *sarama.ConsumerMessage, key string) string {
+	for _, h := range message.Headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,226 @@
+package legalhold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"witness/auth"
+	"witness/models"
+	"witness/storage"
+
+	"github.com/google/uuid"
+)
+
+// Типы событий аудита, которыми фиксируются изменения удержаний.
+const (
+	EventCreated  = "LEGAL_HOLD_CREATED"
+	EventUpdated  = "LEGAL_HOLD_UPDATED"
+	EventReleased = "LEGAL_HOLD_RELEASED"
+)
+
+// SourceService - значение context.source_service для событий, порождаемых самим Witness.
+const SourceService = "witness"
+
+var (
+	ErrInvalidHold     = errors.New("invalid legal hold")
+	ErrAlreadyReleased = errors.New("legal hold already released")
+)
+
+// Recorder сохраняет события аудита, которые порождает сам Witness. Реализация
+// (ingest.Recorder) проводит их через конвейер приема, как события продюсеров.
+type Recorder interface {
+	Record(ctx context.Context, event *models.AuditEvent) error
+}
+
+// Service управляет удержаниями. Каждое изменение удержания
+// сохраняется вместе с событием аудита о нем.
+type Service struct {
+	holds    storage.LegalHoldStore
+	recorder Recorder
+	now      func() time.Time
+}
+
+// NewService создает сервис удержаний.
+func NewService(holds storage.LegalHoldStore, recorder Recorder) *Service {
+	return &Service{holds: holds, recorder: recorder, now: time.Now}
+}
+
+// Create создает новое активное удержание.
+func (s *Service) Create(ctx context.Context, input models.CreateLegalHoldInput) (*models.LegalHold, error) {
+	now := s.now().UTC()
+	hold := &models.LegalHold{
+		ID:        uuid.NewString(),
+		Reason:    strings.TrimSpace(input.Reason),
+		Owner:     strings.TrimSpace(input.Owner),
+		Status:    models.LegalHoldActive,
+		ActorID:   input.ActorID,
+		EntityID:  input.EntityID,
+		From:      input.From,
+		To:        input.To,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := validate(hold); err != nil {
+		return nil, err
+	}
+
+	if err := s.holds.SaveLegalHold(ctx, hold); err != nil {
+		return nil, err
+	}
+	s.record(ctx, EventCreated, hold, nil)
+	return hold, nil
+}
+
+// Update изменяет условия, причину или владельца активного удержания.
+func (s *Service) Update(ctx context.Context, id string, input models.UpdateLegalHoldInput) (*models.LegalHold, error) {
+	hold, err := s.holds.GetLegalHold(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !hold.IsActive() {
+		return nil, ErrAlreadyReleased
+	}
+
+	before := *hold
+	if input.Reason != nil {
+		hold.Reason = strings.TrimSpace(*input.Reason)
+	}
+	if input.Owner != nil {
+		hold.Owner = strings.TrimSpace(*input.Owner)
+	}
+	if input.ActorID != nil {
+		hold.ActorID = emptyToNil(input.ActorID)
+	}
+	if input.EntityID != nil {
+		hold.EntityID = emptyToNil(input.EntityID)
+	}
+	if input.From != nil {
+		hold.From = input.From
+	}
+	if input.To != nil {
+		hold.To = input.To
+	}
+	hold.UpdatedAt = s.now().UTC()
+	if err := validate(hold); err != nil {
+		return nil, err
+	}
+
+	if err := s.holds.SaveLegalHold(ctx, hold); err != nil {
+		return nil, err
+	}
+	s.record(ctx, EventUpdated, hold, &before)
+	return hold, nil
+}
+
+// Release снимает удержание. После этого события снова подчиняются политике хранения.
+func (s *Service) Release(ctx context.Context, id, releasedBy string) (*models.LegalHold, error) {
+	releasedBy = strings.TrimSpace(releasedBy)
+	if releasedBy == "" {
+		return nil, fmt.Errorf("%w: releasedBy is required", ErrInvalidHold)
+	}
+
+	hold, err := s.holds.GetLegalHold(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !hold.IsActive() {
+		return nil, ErrAlreadyReleased
+	}
+
+	now := s.now().UTC()
+	hold.Status = models.LegalHoldReleased
+	hold.ReleasedAt = &now
+	hold.ReleasedBy = &releasedBy
+	hold.UpdatedAt = now
+
+	if err := s.holds.SaveLegalHold(ctx, hold); err != nil {
+		return nil, err
+	}
+	s.record(ctx, EventReleased, hold, nil)
+	return hold, nil
+}
+
+// Get возвращает удержание по идентификатору.
+func (s *Service) Get(ctx context.Context, id string) (*models.LegalHold, error) {
+	return s.holds.GetLegalHold(ctx, id)
+}
+
+// List возвращает удержания, при activeOnly - только действующие.
+func (s *Service) List(ctx context.Context, activeOnly bool) ([]*models.LegalHold, error) {
+	return s.holds.ListLegalHolds(ctx, activeOnly)
+}
+
+// record сохраняет событие аудита об изменении удержания от имени субъекта запроса.
+// owner и released_by указывает вызывающий, поэтому они остаются только в details.
+// Ошибка записи не отменяет само изменение, но логируется.
+func (s *Service) record(ctx context.Context, eventType string, hold, before *models.LegalHold) {
+	details := map[string]any{
+		"hold": hold,
+	}
+	if before != nil {
+		details["previous"] = before
+	}
+
+	event := &models.AuditEvent{
+		EventID:   uuid.NewString(),
+		Timestamp: s.now().UTC(),
+		Status:    "SUCCESS",
+		EventType: eventType,
+		Actor:     CallerActor(ctx),
+		Entity: models.Entity{
+			ID:   hold.ID,
+			Type: "LEGAL_HOLD",
+			Name: hold.Reason,
+		},
+		Context: models.Context{
+			SourceService: SourceService,
+		},
+		Security: &models.Security{AccessLevel: "HIGH"},
+		Details:  details,
+	}
+
+	if err := s.recorder.Record(ctx, event); err != nil {
+		slog.Error("failed to record legal hold audit event", "hold_id", hold.ID, "event_type", eventType, "error", err)
+	}
+}
+
+// CallerActor возвращает актора события, которое Witness записывает о действии субъекта
+// запроса из ctx. Без субъекта (аутентификация не пройдена) актор - anonymous.
+func CallerActor(ctx context.Context) models.Actor {
+	p := auth.FromContext(ctx)
+	switch {
+	case p == nil || p.Subject == "" || p.Subject == "anonymous":
+		return models.Actor{ID: "anonymous", Type: "ANONYMOUS"}
+	case strings.HasPrefix(p.Subject, "apikey:"):
+		return models.Actor{ID: p.Subject, Type: "SERVICE"}
+	case strings.HasPrefix(p.Subject, "cli:"):
+		return models.Actor{ID: p.Subject, Type: "SYSTEM"}
+	}
+	return models.Actor{ID: p.Subject, Type: "USER"}
+}
+
+func validate(hold *models.LegalHold) error {
+	if hold.Reason == "" {
+		return fmt.Errorf("%w: reason is required", ErrInvalidHold)
+	}
+	if hold.Owner == "" {
+		return fmt.Errorf("%w: owner is required", ErrInvalidHold)
+	}
+	if hold.ActorID == nil && hold.EntityID == nil && hold.From == nil && hold.To == nil {
+		return fmt.Errorf("%w: at least one of actor, entity or time range is required", ErrInvalidHold)
+	}
+	if hold.From != nil && hold.To != nil && hold.To.Before(*hold.From) {
+		return fmt.Errorf("%w: 'to' is before 'from'", ErrInvalidHold)
+	}
+	return nil
+}
+
+func emptyToNil(s *string) *string {
+	if strings.TrimSpace(*s) == "" {
+		return nil
+	}
+	return s
+}
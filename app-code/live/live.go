@@ -0,0 +1,218 @@
+// Package live раздает только что сохраненные события подписчикам в реальном времени
+// (GraphQL-подписки). События публикуются после успешной записи в хранилище,
+// поэтому подписчик видит только то, что уже можно найти поиском.
+package live
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+	"witness/models"
+	"witness/pii"
+	"witness/storage"
+	"witness/tenant"
+)
+
+// Broker рассылает события подписчикам. Медленный подписчик не задерживает
+// публикацию: когда его буфер полон, самое старое событие в буфере отбрасывается.
+type Broker struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// NewBroker создает пустой брокер.
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription - подписка на события, подходящие под фильтр.
+type Subscription struct {
+	broker  *Broker
+	filter  *models.AuditEventFilter
+	events  chan *models.AuditEvent
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+// Subscribe подписывает на события, подходящие под filter (семантика storage.Match),
+// с буфером на buffer событий. Подписку нужно закрыть через Close.
+func (b *Broker) Subscribe(filter *models.AuditEventFilter, buffer int) *Subscription {
+	if buffer < 1 {
+		buffer = 1
+	}
+	s := &Subscription{broker: b, filter: filter, events: make(chan *models.AuditEvent, buffer)}
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// Publish рассылает сохраненные события. События общие для всех подписчиков
+// и не должны изменяться получателями.
+func (b *Broker) Publish(events []*models.AuditEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for s := range b.subs {
+		for _, event := range events {
+			if storage.Match(s.filter, event) {
+				s.send(event)
+			}
+		}
+	}
+}
+
+// send кладет событие в буфер подписчика, при переполнении вытесняя самое старое.
+func (s *Subscription) send(event *models.AuditEvent) {
+	for {
+		select {
+		case s.events <- event:
+			return
+		default:
+		}
+		select {
+		case <-s.events:
+			s.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+// Events возвращает канал событий; он закрывается вместе с подпиской.
+func (s *Subscription) Events() <-chan *models.AuditEvent {
+	return s.events
+}
+
+// Dropped возвращает число событий, отброшенных из-за переполнения буфера.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close отменяет подписку. Повторный вызов ничего не делает.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.broker.mu.Lock()
+		delete(s.broker.subs, s)
+		close(s.events)
+		s.broker.mu.Unlock()
+	})
+}
+
+// Feed - подписки для API: фильтр ограничивается арендатором субъекта так же,
+// как поиск (см. tenant.Store), а PII событий расшифровываются.
+type Feed struct {
+	broker  *Broker
+	tenants *tenant.Store
+	chains  storage.ChainStore
+	pii     *pii.Encryptor
+	buffer  int
+}
+
+// NewFeed создает ленту событий для API с буфером buffer событий на подписчика.
+// Из chains повторяются события, пропущенные подписчиком (см. Replay).
+func NewFeed(broker *Broker, tenants *tenant.Store, chains storage.ChainStore, encryptor *pii.Encryptor, buffer int) *Feed {
+	return &Feed{broker: broker, tenants: tenants, chains: chains, pii: encryptor, buffer: buffer}
+}
+
+// Replay возвращает сохраненные события, подходящие под filter и принятые после события
+// after, в порядке приема (storage.IngestLess) - не больше limit. Порядок приема, а не время
+// события, нужен, чтобы не пропустить события, пришедшие с опозданием. truncated - подходящих
+// событий больше limit. После события без звена цепочки повторять не от чего.
+func (f *Feed) Replay(ctx context.Context, filter *models.AuditEventFilter, after *models.AuditEvent, limit int) (events []*models.AuditEvent, truncated bool, err error) {
+	if err := f.pii.CheckFilter(filter); err != nil {
+		return nil, false, err
+	}
+	filter, err = f.tenants.Scope(ctx, filter)
+	if err != nil {
+		return nil, false, err
+	}
+	if after.Chain == nil {
+		return nil, false, nil
+	}
+
+	// Обход идет по потокам, а не по времени приема, поэтому храним самые ранние
+	// события с запасом и отбрасываем лишние, чтобы долгий перерыв не занял всю память.
+	trim := func() {
+		slices.SortFunc(events, func(a, b *models.AuditEvent) int {
+			if storage.IngestLess(a, b) {
+				return -1
+			}
+			return 1
+		})
+		if len(events) > limit {
+			events, truncated = events[:limit], true
+		}
+	}
+	err = f.chains.ScanChain(ctx, after.Chain.IngestedAt, time.Now(), func(event *models.AuditEvent) error {
+		if event.Chain == nil || !storage.IngestLess(after, event) || !storage.Match(filter, event) {
+			return nil
+		}
+		events = append(events, event)
+		if len(events) >= 2*limit {
+			trim()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	trim()
+
+	for i, event := range events {
+		e := *event
+		if err := f.pii.Decrypt(&e); err != nil {
+			return nil, false, fmt.Errorf("failed to decrypt event %s: %w", e.EventID, err)
+		}
+		events[i] = &e
+	}
+	return events, truncated, nil
+}
+
+// Subscribe возвращает канал событий, подходящих под filter, до отмены ctx.
+func (f *Feed) Subscribe(ctx context.Context, filter *models.AuditEventFilter) (<-chan *models.AuditEvent, error) {
+	if err := f.pii.CheckFilter(filter); err != nil {
+		return nil, err
+	}
+	filter, err := f.tenants.Scope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	sub := f.broker.Subscribe(filter, f.buffer)
+	out := make(chan *models.AuditEvent)
+
+	go func() {
+		defer close(out)
+		defer func() {
+			sub.Close()
+			if n := sub.Dropped(); n > 0 {
+				slog.Warn("live subscriber was too slow, events dropped", "dropped", n)
+			}
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-sub.Events():
+				if !ok {
+					return
+				}
+				// Расшифровка меняет поля события, а оно общее для всех подписчиков.
+				e := *event
+				if err := f.pii.Decrypt(&e); err != nil {
+					slog.Error("failed to decrypt live event", "event_id", e.EventID, "error", err)
+					continue
+				}
+				select {
+				case out <- &e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
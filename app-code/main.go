@@ -4,24 +4,45 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/vektah/gqlparser/v2/ast"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/grpc"
 
+	"witness/apikey"
+	"witness/archive"
+	"witness/auth"
 	"witness/graphql"
 	"witness/graphql/generated"
 	"witness/handlers"
+	"witness/ingest"
+	"witness/integrity"
 	"witness/kafka"
+	"witness/legalhold"
+	"witness/live"
 	"witness/opensearch"
+	"witness/pii"
+	"witness/querylog"
+	"witness/retention"
+	"witness/rpc"
+	"witness/storage"
+	"witness/tenant"
 )
 
 func main() {
@@ -29,59 +50,292 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	slog.SetDefault(logger)
 
+	// Подкоманды CLI: `witness hold ...` и т.д.
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	// --- Конфигурация ---
 	port := getEnv("APP_PORT", "8080")
+	grpcPort := getEnv("GRPC_PORT", "9090")
+	kafkaEnabled := getEnvBool("KAFKA_ENABLED", true)
 	kafkaBrokers := getEnv("KAFKA_BROKERS", "localhost:9092")
 	kafkaTopic := getEnv("KAFKA_TOPIC", "audit-events")
 	kafkaGroup := getEnv("KAFKA_CONSUMER_GROUP", "witness-group")
-	opensearchURL := getEnv("OPENSEARCH_URL", "http://localhost:9200")
+	storageKind := getEnv("STORAGE", "opensearch")
+	retentionDays := getEnvInt("RETENTION_DAYS", 0)
+	retentionInterval := getEnvDuration("RETENTION_INTERVAL", time.Hour)
+	archiveAfterDays := getEnvInt("ARCHIVE_AFTER_DAYS", 0)
+	archiveInterval := getEnvDuration("ARCHIVE_INTERVAL", 6*time.Hour)
 
 	// --- Инициализация зависимостей ---
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// OpenSearch Client
-	osClient, err := opensearch.NewClient(opensearchURL)
+	// Хранилище событий
+	store, err := openStorage(ctx, storageKind)
 	if err != nil {
-		slog.Error("failed to create opensearch client", "error", err)
+		slog.Error("failed to open storage", "storage", storageKind, "error", err)
 		os.Exit(1)
 	}
+	slog.Info("storage is ready", "storage", storageKind)
 
-	// Ждем доступности OpenSearch и создаем индекс
-	err = retry(5, 2*time.Second, func() error {
-		return osClient.EnsureIndexExists(ctx)
-	})
+	// Конвейер приема: общий для всех транспортов
+	encryptor, piiKeys, err := newEncryptor()
+	if err != nil {
+		slog.Error("failed to configure pii encryption", "error", err)
+		os.Exit(1)
+	}
+	redactor, err := newRedactor()
+	if err != nil {
+		slog.Error("failed to load redaction rules", "error", err)
+		os.Exit(1)
+	}
+	pipeline, err := newPipeline(store, redactor, encryptor)
 	if err != nil {
-		slog.Error("failed to ensure opensearch index exists", "error", err)
+		slog.Error("failed to configure ingest pipeline", "error", err)
 		os.Exit(1)
 	}
-	slog.Info("opensearch client initialized and index is ready")
+	tenants, err := newTenants()
+	if err != nil {
+		slog.Error("failed to configure tenants", "error", err)
+		os.Exit(1)
+	}
+	// Брокер рассылает сохраненные события подписчикам eventStream
+	broker := live.NewBroker()
+	// Буфер сохраняет подготовленные события всех транспортов пачками
+	buffer := ingest.NewBuffer(store, broker)
+	// События самого Witness (удержания, забвение) идут через конвейер в свой поток цепочки
+	witnessStream, err := newIngestStream("witness")
+	if err != nil {
+		slog.Error("failed to configure witness stream", "error", err)
+		os.Exit(1)
+	}
+	recorder := ingest.NewRecorder(pipeline, buffer, witnessStream)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		buffer.Run(ctx)
+	}()
 
 	// Kafka Consumer
-	consumer := kafka.NewConsumer(osClient)
-	var wg sync.WaitGroup
+	if kafkaEnabled {
+		consumer := kafka.NewConsumer(pipeline, buffer, tenants)
+		wg.Add(1)
+		go consumer.StartConsumerGroup(ctx, &wg, strings.Split(kafkaBrokers, ","), kafkaGroup, splitList(kafkaTopic))
+		slog.Info("kafka consumer group started")
+	} else {
+		slog.Info("kafka is disabled, events are accepted only over HTTP, gRPC and syslog")
+	}
+
+	// Retention: удаление устаревших событий с учетом legal hold
+	retentionWorker := retention.NewWorker(store, store, time.Duration(retentionDays)*24*time.Hour, retentionInterval)
 	wg.Add(1)
-	go consumer.StartConsumerGroup(ctx, &wg, strings.Split(kafkaBrokers, ","), kafkaGroup, kafkaTopic)
-	slog.Info("kafka consumer group started")
+	go func() {
+		defer wg.Done()
+		retentionWorker.Run(ctx)
+	}()
+
+	// Архивация: выгрузка старых событий в S3/файловое хранилище
+	if archiveAfterDays > 0 {
+		osClient, ok := store.(*opensearch.Client)
+		if !ok {
+			slog.Error("archiving requires opensearch storage", "storage", storageKind)
+			os.Exit(1)
+		}
+		target, err := newArchiveTarget(ctx)
+		if err != nil {
+			slog.Error("failed to create archive target", "error", err)
+			os.Exit(1)
+		}
+		archiver := archive.NewArchiver(osClient, target, time.Duration(archiveAfterDays)*24*time.Hour, archiveInterval)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			archiver.Run(ctx)
+		}()
+	}
+
+	// Контрольные точки: подписанные корни деревьев Меркла по интервалам
+	checkpoints, err := newCheckpoints(store)
+	if err != nil {
+		slog.Error("failed to configure checkpoints", "error", err)
+		os.Exit(1)
+	}
+	if getEnv("CHECKPOINT_KEY_FILE", "") != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkpoints.Run(ctx)
+		}()
+	}
+
+	// Журнал запросов к API: событие AUDIT_LOG_QUERY о каждой операции GraphQL
+	multiTenant := getEnvBool("MULTI_TENANT", false)
+	var queryLog *querylog.Recorder
+	var queries storage.EventStore
+	if getEnvBool("QUERY_LOG", true) {
+		queryLogStore, err := openQueryLog(ctx, storageKind, store)
+		if err != nil {
+			slog.Error("failed to open query log", "storage", storageKind, "error", err)
+			os.Exit(1)
+		}
+		queryLog = querylog.NewRecorder(queryLogStore, redactor, encryptor)
+		queries = pii.NewStore(tenant.NewStore(queryLogStore, multiTenant), encryptor)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			queryLog.Run(ctx)
+		}()
+	}
 
 	// --- Настройка HTTP сервера (Echo) ---
 	e := echo.New()
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 
+	authenticator, authMiddleware, err := newAuth(ctx, apikey.NewService(store))
+	if err != nil {
+		slog.Error("failed to configure authentication", "error", err)
+		os.Exit(1)
+	}
+	wsAuthMiddleware := authMiddleware
+	if authenticator != nil {
+		wsAuthMiddleware = auth.Deferred(authenticator)
+	}
+
 	// --- GraphQL эндпоинты ---
-	gqlResolver := &graphql.Resolver{OSClient: osClient}
-	gqlSrv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: gqlResolver}))
+	events := tenant.NewStore(store, multiTenant)
+	feed := live.NewFeed(broker, events, store, encryptor, getEnvInt("LIVE_BUFFER", 100))
+	maskDetailsKeys := splitList(getEnv("MASK_DETAILS_KEYS", ""))
+	// Ключи details для REST и выгрузок скрываются так же, как у поля details в GraphQL (@mask).
+	restMaskKeys := append([]string{"email", "phone", "password", "token"}, maskDetailsKeys...)
+	apiStore := pii.NewStore(events, encryptor)
 
-	e.GET("/healthz", handlers.HealthCheck)
-	e.POST("/graphql", func(c echo.Context) error {
-		gqlSrv.ServeHTTP(c.Response(), c.Request())
-		return nil
+	// Фоновые выгрузки: пул воркеров, файлы на диске или в S3
+	exportJobs, err := newExportJobs(ctx, store, apiStore, queryLog, restMaskKeys)
+	if err != nil {
+		slog.Error("failed to configure export jobs", "error", err)
+		os.Exit(1)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		exportJobs.Run(ctx)
+	}()
+
+	gqlResolver := &graphql.Resolver{
+		Store:       apiStore,
+		Holds:       legalhold.NewService(store, recorder),
+		Shredder:    pii.NewShredder(piiKeys, store, store, recorder),
+		Integrity:   integrity.NewVerifier(store),
+		Checkpoints: checkpoints,
+		Queries:     queries,
+		Live:        feed,
+		Exports:     exportJobs,
+	}
+	gqlSrv := handler.New(generated.NewExecutableSchema(generated.Config{
+		Resolvers: gqlResolver,
+		Directives: generated.DirectiveRoot{
+			Mask:    graphql.Mask(maskDetailsKeys),
+			HasRole: graphql.HasRole,
+		},
+	}))
+	// Запросы и мутации - POST; GET /graphql принимает только подключения WebSocket для подписок.
+	gqlSrv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc:              graphql.WebsocketInit(authenticator),
 	})
-	e.GET("/playground", func(c echo.Context) error {
-		playground.Handler("GraphQL playground", "/graphql").ServeHTTP(c.Response(), c.Request())
+	gqlSrv.AddTransport(transport.Options{})
+	gqlSrv.AddTransport(transport.POST{})
+	gqlSrv.AddTransport(transport.MultipartForm{})
+	gqlSrv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	gqlSrv.Use(extension.Introspection{})
+	gqlSrv.Use(extension.AutomaticPersistedQuery{Cache: lru.New[string](100)})
+	if queryLog != nil {
+		gqlSrv.Use(queryLog)
+	}
+	serveGraphQL := func(c echo.Context) error {
+		gqlSrv.ServeHTTP(c.Response(), c.Request())
 		return nil
-	})
+	}
+
+	e.GET("/healthz", handlers.HealthCheck)
+	e.POST("/graphql", serveGraphQL, querylog.Middleware(), authMiddleware)
+	e.GET("/graphql", serveGraphQL, querylog.Middleware(), wsAuthMiddleware)
+
+	// --- Server-Sent Events: поток новых событий для curl и CLI ---
+	stream := handlers.NewEventStream(feed, gqlResolver.Store, restMaskKeys,
+		getEnvDuration("SSE_HEARTBEAT", 15*time.Second), getEnvInt("SSE_REPLAY_LIMIT", 1000))
+	e.GET("/events/stream", stream.Handle, authMiddleware)
+
+	// --- Выгрузка результатов поиска в NDJSON/CSV ---
+	exporter := handlers.NewExport(gqlResolver.Store, queryLog, restMaskKeys)
+	e.GET("/export", exporter.Handle, querylog.Middleware(), authMiddleware)
+	e.GET("/exports/:id/download", handlers.ExportDownload(exportJobs))
+
+	// --- REST API для клиентов без GraphQL ---
+	handlers.NewAPI(gqlResolver.Store, queryLog, restMaskKeys).Register(e.Group("/api/v1"), querylog.Middleware(), authMiddleware)
+
+	// --- Прием событий по HTTP для продюсеров без доступа к Kafka ---
+	ingestStream, err := newIngestStream("http")
+	if err != nil {
+		slog.Error("failed to configure http ingest", "error", err)
+		os.Exit(1)
+	}
+	ingester := handlers.NewIngest(pipeline, buffer, tenants, ingestStream, int64(getEnvInt("INGEST_MAX_BODY", 10<<20)))
+	e.POST("/ingest", ingester.Handle, authMiddleware)
+
+	// --- OTLP: записи логов OpenTelemetry с признаком события аудита ---
+	otlpReceiver, err := newOTLPReceiver(pipeline, buffer, tenants)
+	if err != nil {
+		slog.Error("failed to configure otlp receiver", "error", err)
+		os.Exit(1)
+	}
+	e.POST("/v1/logs", handlers.OTLPLogs(otlpReceiver, int64(getEnvInt("INGEST_MAX_BODY", 10<<20))), authMiddleware)
+
+	// --- gRPC: прием потоком и поиск для сервисов на gRPC, OTLP/gRPC ---
+	var grpcSrv *grpc.Server
+	if grpcPort != "" {
+		grpcStream, err := newIngestStream("grpc")
+		if err != nil {
+			slog.Error("failed to configure grpc ingest", "error", err)
+			os.Exit(1)
+		}
+		grpcService := rpc.NewService(pipeline, buffer, tenants, grpcStream, gqlResolver.Store, queryLog, restMaskKeys)
+		grpcSrv = rpc.NewServer(grpcService, authenticator, anonymousPrincipal())
+		collogspb.RegisterLogsServiceServer(grpcSrv, otlpReceiver)
+		lis, err := net.Listen("tcp", ":"+grpcPort)
+		if err != nil {
+			slog.Error("failed to listen for grpc", "port", grpcPort, "error", err)
+			os.Exit(1)
+		}
+		go func() {
+			if err := grpcSrv.Serve(lis); err != nil {
+				slog.Error("grpc server stopped", "error", err)
+			}
+		}()
+		slog.Info("grpc server started", "port", grpcPort)
+	}
+
+	// --- Syslog: межсетевые экраны и узлы, которые умеют только syslog ---
+	syslogSrv, err := startSyslog(pipeline, buffer, tenants)
+	if err != nil {
+		slog.Error("failed to start syslog receiver", "error", err)
+		os.Exit(1)
+	}
+
+	// Playground - только страница; запросы из нее к /graphql проходят ту же аутентификацию.
+	// В production она отключена.
+	if getEnv("APP_ENV", "development") != "production" {
+		e.GET("/playground", func(c echo.Context) error {
+			playground.Handler("GraphQL playground", "/graphql").ServeHTTP(c.Response(), c.Request())
+			return nil
+		})
+	} else if getEnv("AUTH_JWKS", "") == "" && !getEnvBool("AUTH_API_KEYS", false) {
+		slog.Warn("running in production without AUTH_JWKS or AUTH_API_KEYS: the API is not authenticated")
+	}
 
 	// --- Запуск и Graceful Shutdown ---
 	go func() {
@@ -107,6 +361,12 @@ func main() {
 	if err := e.Shutdown(shutdownCtx); err != nil {
 		e.Logger.Fatal(err)
 	}
+	if grpcSrv != nil {
+		stopGRPC(shutdownCtx, grpcSrv)
+	}
+	if syslogSrv != nil {
+		syslogSrv.Close()
+	}
 
 	// Сигнализируем Kafka consumer'у о завершении
 	cancel()
@@ -115,6 +375,21 @@ func main() {
 	slog.Info("server gracefully stopped")
 }
 
+// stopGRPC дожидается завершения вызовов gRPC, но не дольше ctx: потоки Ingest
+// клиентов могут не закрываться сами.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
 func getEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
 		return value
@@ -122,6 +397,56 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+// splitList разбирает список через запятую, пропуская пустые элементы.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("invalid integer in environment, using default", "key", key, "value", value)
+		return fallback
+	}
+	return n
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("invalid boolean in environment, using default", "key", key, "value", value)
+		return fallback
+	}
+	return b
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("invalid duration in environment, using default", "key", key, "value", value)
+		return fallback
+	}
+	return d
+}
+
 func retry(attempts int, sleep time.Duration, fn func() error) error {
 	if err := fn(); err != nil {
 		if attempts--; attempts > 0 {
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Области действия ключей API. Они же - роли субъекта, аутентифицированного ключом.
+const (
+	ScopeEventsRead     = "events:read"
+	ScopeAggregatesRead = "aggregates:read"
+	ScopeAdmin          = "admin"
+	// ScopeEventsWrite разрешает отправлять события через POST /ingest.
+	ScopeEventsWrite = "events:write"
+)
+
+// APIKey - ключ доступа к API для сервисов, которые не могут пройти OIDC (SIEM, скрипты).
+// Секрет ключа не хранится: сохраняется только его хеш.
+type APIKey struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Hash   string   `json:"hash"`
+	Scopes []string `json:"scopes"`
+	// Tenant - арендатор, данными которого ограничен ключ.
+	Tenant string `json:"tenant,omitempty"`
+	// CreatedBy - кто выпустил ключ.
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IsActive сообщает, можно ли пользоваться ключом в момент now.
+func (k *APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Checkpoint - подписанный корень дерева Меркла над событиями, принятыми
+// в интервале [From, To). Листья дерева - хеши событий в порядке (stream, seq).
+type Checkpoint struct {
+	ID       string    `json:"id"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	TreeSize int64     `json:"tree_size"`
+	// RootHash - корень дерева (hex). Для пустого интервала - SHA-256 пустой строки.
+	RootHash string `json:"root_hash"`
+	// Signature - подпись Ed25519 над SignedPayload (base64).
+	Signature string `json:"signature"`
+	KeyID     string `json:"key_id"`
+	// PublicKey - открытый ключ подписи (base64), чтобы проверка не требовала доступа к Witness.
+	PublicKey string    `json:"public_key"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CheckpointLeaf - лист дерева контрольной точки. Листья хранятся вместе с точкой, чтобы
+// доказательства строились и после удаления событий интервала (ретенция, архив).
+type CheckpointLeaf struct {
+	EventID string `json:"event_id"`
+	// LeafHash - лист дерева над хешем события (hex).
+	LeafHash string `json:"leaf_hash"`
+}
+
+// InclusionProof доказывает, что событие входит в дерево контрольной точки.
+type InclusionProof struct {
+	EventID string `json:"event_id"`
+	// EventJSON - канонический JSON события, EventHash - его SHA-256 (см. integrity.Hash),
+	// LeafHash - лист дерева над EventHash. В API EventJSON видят только субъекты с ролью pii_reader.
+	EventJSON  string      `json:"event_json,omitempty"`
+	EventHash  string      `json:"event_hash"`
+	LeafHash   string      `json:"leaf_hash"`
+	LeafIndex  int64       `json:"leaf_index"`
+	Path       []string    `json:"path"`
+	Checkpoint *Checkpoint `json:"checkpoint"`
+}
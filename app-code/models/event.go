@@ -14,13 +14,25 @@ type AuditEvent struct {
 	Context   Context        `json:"context"`
 	Security  *Security      `json:"security,omitempty"`
 	Details   map[string]any `json:"details"`
+	// Redactions - сколько значений в details было замаскировано при приеме.
+	Redactions int `json:"redactions,omitempty"`
+	// SignatureStatus - результат проверки подписи продюсера (см. Signature*).
+	SignatureStatus string `json:"signature_status,omitempty"`
+	// Tenant - арендатор (бизнес-подразделение), которому принадлежит событие.
+	// Определяется Witness при приеме по транспорту; значение от продюсера перезаписывается.
+	Tenant string `json:"tenant,omitempty"`
+	// PII - зашифрованные персональные данные актора по пути поля ("actor.name",
+	// "details.email"); сами поля в сохраненном событии пусты (см. пакет pii).
+	PII map[string]string `json:"pii,omitempty"`
+	// Chain заполняется Witness при приеме события; значение от продюсера перезаписывается.
+	Chain *ChainLink `json:"chain,omitempty"`
 }
 
 type Actor struct {
 	ID        string `json:"id"`
 	Type      string `json:"type"`
 	Name      string `json:"name"`
-	IPAddress string `json:"ip_address"`
+	IPAddress string `json:"ip_address,omitempty"`
 }
 
 type Entity struct {
@@ -35,6 +47,16 @@ type Context struct {
 	RequestID     string `json:"request_id"`
 }
 
+// Результаты проверки подписи продюсера.
+const (
+	// SignatureVerified - подпись верна для ключа source_service из реестра.
+	SignatureVerified = "VERIFIED"
+	// SignatureUnverified - подписи нет или для source_service нет ключей в реестре.
+	SignatureUnverified = "UNVERIFIED"
+	// SignatureInvalid - подпись не подходит ни к одному ключу source_service.
+	SignatureInvalid = "INVALID"
+)
+
 // Security содержит данные, связанные с безопасностью действия
 type Security struct {
 	AccessLevel string `json:"access_level"` // Например, "LOW", "MEDIUM", "HIGH", "CRITICAL"
@@ -46,12 +68,31 @@ type AuditEventConnection struct {
 }
 
 type AuditEventFilter struct {
-	Status              *string `json:"status,omitempty"`
-	EventType           *string `json:"eventType,omitempty"`
-	ActorID             *string `json:"actorId,omitempty"`
-	EntityID            *string `json:"entityId,omitempty"`
-	SecurityAccessLevel *string `json:"securityAccessLevel,omitempty"`
+	Status              *string    `json:"status,omitempty"`
+	EventType           *string    `json:"eventType,omitempty"`
+	ActorID             *string    `json:"actorId,omitempty"`
+	EntityID            *string    `json:"entityId,omitempty"`
+	SecurityAccessLevel *string    `json:"securityAccessLevel,omitempty"`
+	Name                *string    `json:"name,omitempty"`
+	SignatureStatus     *string    `json:"signatureStatus,omitempty"`
+	From                *time.Time `json:"from,omitempty"`
+	To                  *time.Time `json:"to,omitempty"`
+
+	// Tenant не входит в схему GraphQL: его подставляет tenant.Store по субъекту запроса.
+	Tenant *string `json:"-"`
+}
+
+// FacetBucket - количество событий с одним значением поля.
+type FacetBucket struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
 }
 
 type Query struct {
 }
+
+type Mutation struct {
+}
+
+type Subscription struct {
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Статусы задания выгрузки.
+const (
+	ExportPending   = "PENDING"
+	ExportRunning   = "RUNNING"
+	ExportSucceeded = "SUCCEEDED"
+	ExportFailed    = "FAILED"
+	// ExportExpired - срок хранения файла истек, файл удален.
+	ExportExpired = "EXPIRED"
+)
+
+// ExportJob - фоновая выгрузка событий в файл (см. пакет export).
+type ExportJob struct {
+	ID      string            `json:"id"`
+	Status  string            `json:"status"`
+	Format  string            `json:"format"`
+	Filter  *AuditEventFilter `json:"filter,omitempty"`
+	Columns []string          `json:"columns,omitempty"`
+	Gzip    bool              `json:"gzip"`
+	// CreatedBy, Roles и Tenant - субъект, создавший задание. Выгрузка выполняется
+	// с его правами: арендатор ограничивает выборку, роли определяют маскирование.
+	CreatedBy string   `json:"created_by"`
+	Roles     []string `json:"roles,omitempty"`
+	Tenant    string   `json:"tenant,omitempty"`
+	// Rows - сколько событий записано, Total - сколько подходило под фильтр при запуске.
+	Rows  int64 `json:"rows"`
+	Total int64 `json:"total"`
+	// Size и Checksum (SHA-256, hex) - размер и контрольная сумма готового файла.
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum,omitempty"`
+	// Key - ключ файла в хранилище выгрузок.
+	Key        string     `json:"key,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// ExpiresAt - когда файл будет удален и ссылка на него перестанет действовать.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Owner - экземпляр Witness, выполняющий задание, LeaseUntil - до какого момента задание
+	// за ним закреплено. Выполняющий экземпляр продлевает аренду, сохраняя прогресс;
+	// задание с истекшей арендой может забрать другой экземпляр.
+	Owner      string     `json:"owner,omitempty"`
+	LeaseUntil *time.Time `json:"lease_until,omitempty"`
+}
+
+// Progress возвращает долю выгруженных событий от 0 до 1.
+func (j *ExportJob) Progress() float64 {
+	switch {
+	case j.Status == ExportSucceeded:
+		return 1
+	case j.Total <= 0:
+		return 0
+	case j.Rows >= j.Total:
+		// События, пришедшие после запуска, тоже попадают в выгрузку.
+		return 0.99
+	}
+	return float64(j.Rows) / float64(j.Total)
+}
+
+// IsFinished сообщает, что задание больше не будет выполняться.
+func (j *ExportJob) IsFinished() bool {
+	return j.Status != ExportPending && j.Status != ExportRunning
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// ChainLink - звено цепочки хешей, которое Witness добавляет к событию при приеме.
+// Хеш события вычисляется по его каноническому JSON вместе со звеном, кроме поля hash.
+type ChainLink struct {
+	// Stream - поток, внутри которого строится цепочка (например, "<topic>/<partition>").
+	Stream     string    `json:"stream"`
+	Seq        int64     `json:"seq"`
+	IngestedAt time.Time `json:"ingested_at"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash,omitempty"`
+}
+
+// Причины удаления событий, отмечаемые в ChainPrune.
+const (
+	PruneRetention = "retention"
+	PruneArchive   = "archive"
+)
+
+// ChainPrune - отметка об удалении событий потока с номерами [FirstSeq, LastSeq]
+// по сроку хранения или при выгрузке в архив. Пропуск, закрытый отметками,
+// проверка цепочки нарушением не считает.
+type ChainPrune struct {
+	Stream   string `json:"stream"`
+	FirstSeq int64  `json:"first_seq"`
+	LastSeq  int64  `json:"last_seq"`
+	// LastHash - хеш события LastSeq: с ним сверяется prev_hash следующего события,
+	// и от него продолжается цепочка, если удален ее конец.
+	LastHash string    `json:"last_hash"`
+	Reason   string    `json:"reason"`
+	PrunedAt time.Time `json:"pruned_at"`
+}
+
+// Виды нарушений целостности, которые находит проверка цепочки.
+const (
+	IntegrityModified  = "MODIFIED"
+	IntegrityDeleted   = "DELETED"
+	IntegrityReordered = "REORDERED"
+)
+
+// IntegrityProblem - одно найденное нарушение цепочки.
+type IntegrityProblem struct {
+	Stream  string `json:"stream"`
+	Seq     int64  `json:"seq"`
+	EventID string `json:"event_id,omitempty"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// IntegrityReport - результат проверки цепочек за интервал приема событий.
+type IntegrityReport struct {
+	From     time.Time           `json:"from"`
+	To       time.Time           `json:"to"`
+	Valid    bool                `json:"valid"`
+	Checked  int                 `json:"checked"`
+	Streams  int                 `json:"streams"`
+	Problems []*IntegrityProblem `json:"problems"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Статусы юридического удержания (legal hold).
+const (
+	LegalHoldActive   = "ACTIVE"
+	LegalHoldReleased = "RELEASED"
+)
+
+// LegalHold описывает юридическое удержание: события, попадающие под его
+// условия, не должны удаляться механизмом хранения (retention), пока удержание активно.
+// Пустое условие означает "любое значение", но хотя бы одно условие должно быть задано.
+type LegalHold struct {
+	ID         string     `json:"id"`
+	Reason     string     `json:"reason"`
+	Owner      string     `json:"owner"`
+	Status     string     `json:"status"`
+	ActorID    *string    `json:"actor_id,omitempty"`
+	EntityID   *string    `json:"entity_id,omitempty"`
+	From       *time.Time `json:"from,omitempty"`
+	To         *time.Time `json:"to,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+	ReleasedBy *string    `json:"released_by,omitempty"`
+}
+
+// IsActive сообщает, действует ли удержание.
+func (h *LegalHold) IsActive() bool {
+	return h.Status == LegalHoldActive
+}
+
+// Covers проверяет, попадает ли событие под условия удержания.
+func (h *LegalHold) Covers(event *AuditEvent) bool {
+	if h.ActorID != nil && event.Actor.ID != *h.ActorID {
+		return false
+	}
+	if h.EntityID != nil && event.Entity.ID != *h.EntityID {
+		return false
+	}
+	if h.From != nil && event.Timestamp.Before(*h.From) {
+		return false
+	}
+	if h.To != nil && event.Timestamp.After(*h.To) {
+		return false
+	}
+	return true
+}
+
+// IsHeld сообщает, попадает ли событие под какое-либо из активных удержаний.
+func IsHeld(event *AuditEvent, holds []*LegalHold) bool {
+	for _, hold := range holds {
+		if hold.IsActive() && hold.Covers(event) {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateLegalHoldInput struct {
+	Reason   string     `json:"reason"`
+	Owner    string     `json:"owner"`
+	ActorID  *string    `json:"actorId,omitempty"`
+	EntityID *string    `json:"entityId,omitempty"`
+	From     *time.Time `json:"from,omitempty"`
+	To       *time.Time `json:"to,omitempty"`
+}
+
+type UpdateLegalHoldInput struct {
+	Reason   *string    `json:"reason,omitempty"`
+	Owner    *string    `json:"owner,omitempty"`
+	ActorID  *string    `json:"actorId,omitempty"`
+	EntityID *string    `json:"entityId,omitempty"`
+	From     *time.Time `json:"from,omitempty"`
+	To       *time.Time `json:"to,omitempty"`
+}
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// ForgetActorResult - результат удаления персональных данных актора.
+type ForgetActorResult struct {
+	ActorID     string    `json:"actor_id"`
+	ForgottenAt time.Time `json:"forgotten_at"`
+}
@@ -0,0 +1,169 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"witness/models"
+	"witness/storage"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+const APIKeyIndexName = "api-keys"
+
+const apiKeysMapping = `{
+        "settings": {
+            "number_of_shards": 1,
+            "number_of_replicas": 0
+        },
+        "mappings": {
+            "properties": {
+                "id": {"type": "keyword"},
+                "name": {"type": "keyword"},
+                "hash": {"type": "keyword", "index": false},
+                "scopes": {"type": "keyword"},
+                "tenant": {"type": "keyword"},
+                "created_by": {"type": "keyword"},
+                "created_at": {"type": "date_nanos"},
+                "expires_at": {"type": "date_nanos"},
+                "last_used_at": {"type": "date_nanos"},
+                "revoked_at": {"type": "date_nanos"}
+            }
+        }
+    }`
+
+// SaveAPIKey создает или перезаписывает ключ API.
+// Запрос ждет обновления индекса, чтобы отзыв ключа сразу был виден в списке.
+func (c *Client) SaveAPIKey(ctx context.Context, key *models.APIKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal api key: %w", err)
+	}
+
+	req := opensearchapi.IndexRequest{
+		Index:      APIKeyIndexName,
+		DocumentID: key.ID,
+		Body:       bytes.NewReader(data),
+		Refresh:    "wait_for",
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return fmt.Errorf("failed to save api key: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("api key indexing error: %s, body: %s", res.Status(), string(body))
+	}
+	return nil
+}
+
+// GetAPIKey возвращает ключ API по идентификатору. Get в OpenSearch читает
+// документ в реальном времени, поэтому отзыв действует сразу.
+func (c *Client) GetAPIKey(ctx context.Context, id string) (*models.APIKey, error) {
+	req := opensearchapi.GetRequest{
+		Index:      APIKeyIndexName,
+		DocumentID: id,
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, storage.ErrNotFound
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("get api key error: %s", res.Status())
+	}
+
+	var result struct {
+		Source *models.APIKey `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode api key: %w", err)
+	}
+	return result.Source, nil
+}
+
+// ListAPIKeys возвращает все ключи API, новые первыми.
+func (c *Client) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{
+		"query": map[string]interface{}{"match_all": map[string]interface{}{}},
+		"sort":  []interface{}{map[string]interface{}{"created_at": "desc"}},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to encode api keys query: %w", err)
+	}
+
+	// Ключей немного, поэтому одной страницы достаточно.
+	size := 10000
+	req := opensearchapi.SearchRequest{
+		Index: []string{APIKeyIndexName},
+		Body:  &buf,
+		Size:  &size,
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return nil, fmt.Errorf("api keys search failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("api keys search error: %s", res.Status())
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source *models.APIKey `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode api keys response: %w", err)
+	}
+
+	keys := make([]*models.APIKey, len(result.Hits.Hits))
+	for i, hit := range result.Hits.Hits {
+		keys[i] = hit.Source
+	}
+	return keys, nil
+}
+
+// TouchAPIKey частично обновляет документ ключа, меняя только last_used_at,
+// чтобы не затереть одновременный отзыв.
+func (c *Client) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"doc": map[string]interface{}{"last_used_at": usedAt},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode api key update: %w", err)
+	}
+
+	req := opensearchapi.UpdateRequest{
+		Index:      APIKeyIndexName,
+		DocumentID: id,
+		Body:       bytes.NewReader(body),
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return fmt.Errorf("failed to touch api key: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return storage.ErrNotFound
+	}
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("api key update error: %s, body: %s", res.Status(), string(body))
+	}
+	return nil
+}
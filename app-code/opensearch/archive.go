@@ -0,0 +1,184 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"witness/models"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+// scanPageSize - размер страницы при последовательном чтении событий.
+const scanPageSize = 1000
+
+// EventDays возвращает дни (UTC), за которые в индексе есть события старше before.
+func (c *Client) EventDays(ctx context.Context, before time.Time) ([]time.Time, error) {
+	query := map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"range": map[string]interface{}{
+				"timestamp": map[string]interface{}{"lt": before.Format(time.RFC3339Nano)},
+			},
+		},
+		"aggs": map[string]interface{}{
+			"days": map[string]interface{}{
+				"date_histogram": map[string]interface{}{
+					"field":             "timestamp",
+					"calendar_interval": "1d",
+					"min_doc_count":     1,
+					"time_zone":         "UTC",
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, fmt.Errorf("failed to encode days query: %w", err)
+	}
+
+	req := opensearchapi.SearchRequest{
+		Index: []string{IndexName},
+		Body:  &buf,
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return nil, fmt.Errorf("days aggregation failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("days aggregation error: %s, body: %s", res.Status(), string(body))
+	}
+
+	var result struct {
+		Aggregations struct {
+			Days struct {
+				Buckets []struct {
+					Key int64 `json:"key"`
+				} `json:"buckets"`
+			} `json:"days"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode days aggregation: %w", err)
+	}
+
+	days := make([]time.Time, len(result.Aggregations.Days.Buckets))
+	for i, bucket := range result.Aggregations.Days.Buckets {
+		days[i] = time.UnixMilli(bucket.Key).UTC()
+	}
+	return days, nil
+}
+
+// ScanEvents последовательно читает все события из интервала [from, to),
+// упорядоченные по времени, и передает их в fn.
+func (c *Client) ScanEvents(ctx context.Context, from, to time.Time, fn func(*models.AuditEvent) error) error {
+	query := map[string]interface{}{
+		"range": map[string]interface{}{
+			"timestamp": map[string]interface{}{
+				"gte": from.Format(time.RFC3339Nano),
+				"lt":  to.Format(time.RFC3339Nano),
+			},
+		},
+	}
+	sort := []interface{}{
+		map[string]interface{}{"timestamp": "asc"},
+		map[string]interface{}{"event_id": "asc"},
+	}
+	return c.scan(ctx, "", query, sort, fn)
+}
+
+// scan читает все события по запросу в заданном порядке и передает их в fn.
+// С непустым pit чтение идет в снимке индекса (point in time), иначе - по основному индексу.
+func (c *Client) scan(ctx context.Context, pit string, query map[string]interface{}, sort []interface{}, fn func(*models.AuditEvent) error) error {
+	index := IndexName
+	if pit != "" {
+		index = ""
+	}
+	return c.scanIndex(ctx, index, pit, query, sort, func(source json.RawMessage) error {
+		var event models.AuditEvent
+		if err := json.Unmarshal(source, &event); err != nil {
+			return fmt.Errorf("failed to decode event: %w", err)
+		}
+		return fn(&event)
+	})
+}
+
+// scanIndex читает все документы индекса index (или снимка pit) по запросу в заданном
+// порядке и передает их _source в fn. Используется search_after, поэтому объем выборки
+// не ограничен окном пагинации from/size; sort должен однозначно упорядочивать документы.
+func (c *Client) scanIndex(ctx context.Context, index, pit string, query map[string]interface{}, sort []interface{}, fn func(json.RawMessage) error) error {
+	// Значения сортировки храним как есть: date_nanos не помещается в float64 без потерь.
+	var searchAfter []json.RawMessage
+	for {
+		body := map[string]interface{}{
+			"size":  scanPageSize,
+			"query": query,
+			"sort":  sort,
+		}
+		if searchAfter != nil {
+			body["search_after"] = searchAfter
+		}
+		if pit != "" {
+			body["pit"] = map[string]interface{}{"id": pit, "keep_alive": pitKeepAlive}
+		}
+
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return fmt.Errorf("failed to encode scan query: %w", err)
+		}
+
+		req := opensearchapi.SearchRequest{Body: &buf}
+		if index != "" {
+			req.Index = []string{index}
+		}
+		res, err := req.Do(ctx, c.os)
+		if err != nil {
+			return fmt.Errorf("scan request failed: %w", err)
+		}
+
+		var result struct {
+			PitID string `json:"pit_id"`
+			Hits  struct {
+				Hits []struct {
+					Source json.RawMessage   `json:"_source"`
+					Sort   []json.RawMessage `json:"sort"`
+				} `json:"hits"`
+			} `json:"hits"`
+		}
+		if res.IsError() {
+			body, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			return fmt.Errorf("scan request error: %s, body: %s", res.Status(), string(body))
+		}
+		err = json.NewDecoder(res.Body).Decode(&result)
+		res.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to decode scan response: %w", err)
+		}
+
+		for _, hit := range result.Hits.Hits {
+			if err := fn(hit.Source); err != nil {
+				return err
+			}
+		}
+		if len(result.Hits.Hits) < scanPageSize {
+			return nil
+		}
+		searchAfter = result.Hits.Hits[len(result.Hits.Hits)-1].Sort
+		if pit != "" && result.PitID != "" {
+			pit = result.PitID
+		}
+	}
+}
+
+// CreateEventsIndex создает индекс с маппингом событий аудита (например, для восстановления из архива).
+func (c *Client) CreateEventsIndex(ctx context.Context, index string) error {
+	return c.createIndex(ctx, index, eventsMapping)
+}
@@ -0,0 +1,113 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"witness/models"
+	"witness/storage"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+// ChainHead возвращает последнее звено потока. Перед поиском индекс обновляется,
+// чтобы увидеть события, записанные только что (например, до ребалансировки).
+func (c *Client) ChainHead(ctx context.Context, stream string) (*models.ChainLink, error) {
+	refresh := opensearchapi.IndicesRefreshRequest{Index: []string{IndexName}}
+	res, err := refresh.Do(ctx, c.os)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh index: %w", err)
+	}
+	res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("refresh index error: %s", res.Status())
+	}
+
+	event, err := c.findOne(ctx, map[string]interface{}{
+		"term": map[string]interface{}{"chain.stream": stream},
+	}, []interface{}{
+		map[string]interface{}{"chain.seq": "desc"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event.Chain, nil
+}
+
+// ChainEvent возвращает событие потока с номером seq.
+func (c *Client) ChainEvent(ctx context.Context, stream string, seq int64) (*models.AuditEvent, error) {
+	return c.findOne(ctx, map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter": []interface{}{
+				map[string]interface{}{"term": map[string]interface{}{"chain.stream": stream}},
+				map[string]interface{}{"term": map[string]interface{}{"chain.seq": seq}},
+			},
+		},
+	}, nil)
+}
+
+// ScanChain читает события, принятые в интервале [from, to], по потокам и номерам.
+func (c *Client) ScanChain(ctx context.Context, from, to time.Time, fn func(*models.AuditEvent) error) error {
+	query := map[string]interface{}{
+		"range": map[string]interface{}{
+			"chain.ingested_at": map[string]interface{}{
+				"gte": from.Format(time.RFC3339Nano),
+				"lte": to.Format(time.RFC3339Nano),
+			},
+		},
+	}
+	sort := []interface{}{
+		map[string]interface{}{"chain.stream": "asc"},
+		map[string]interface{}{"chain.seq": "asc"},
+	}
+	return c.scan(ctx, "", query, sort, fn)
+}
+
+// findOne возвращает первое событие по запросу или storage.ErrNotFound.
+func (c *Client) findOne(ctx context.Context, query map[string]interface{}, sort []interface{}) (*models.AuditEvent, error) {
+	body := map[string]interface{}{
+		"size":  1,
+		"query": query,
+	}
+	if sort != nil {
+		body["sort"] = sort
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+
+	req := opensearchapi.SearchRequest{
+		Index: []string{IndexName},
+		Body:  &buf,
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return nil, fmt.Errorf("search request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("search request error: %s, body: %s", res.Status(), string(body))
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source *models.AuditEvent `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %w", err)
+	}
+	if len(result.Hits.Hits) == 0 {
+		return nil, storage.ErrNotFound
+	}
+	return result.Hits.Hits[0].Source, nil
+}
@@ -0,0 +1,255 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"witness/models"
+	"witness/storage"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+const CheckpointIndexName = "audit-checkpoints"
+
+const checkpointsMapping = `{
+        "settings": {
+            "number_of_shards": 1,
+            "number_of_replicas": 0
+        },
+        "mappings": {
+            "properties": {
+                "id": {"type": "keyword"},
+                "from": {"type": "date_nanos"},
+                "to": {"type": "date_nanos"},
+                "tree_size": {"type": "long"},
+                "root_hash": {"type": "keyword"},
+                "signature": {"type": "keyword", "index": false},
+                "key_id": {"type": "keyword"},
+                "public_key": {"type": "keyword", "index": false},
+                "created_at": {"type": "date_nanos"}
+            }
+        }
+    }`
+
+// CheckpointLeafIndexName - индекс листьев контрольных точек (models.CheckpointLeaf).
+// _id листа - идентификатор точки и номер листа.
+const CheckpointLeafIndexName = "audit-checkpoint-leaves"
+
+const checkpointLeavesMapping = `{
+        "settings": {
+            "number_of_shards": 1,
+            "number_of_replicas": 0
+        },
+        "mappings": {
+            "properties": {
+                "checkpoint_id": {"type": "keyword"},
+                "idx": {"type": "long"},
+                "event_id": {"type": "keyword", "index": false},
+                "leaf_hash": {"type": "keyword", "index": false}
+            }
+        }
+    }`
+
+// checkpointLeafDoc - документ индекса листьев.
+type checkpointLeafDoc struct {
+	CheckpointID string `json:"checkpoint_id"`
+	Idx          int    `json:"idx"`
+	models.CheckpointLeaf
+}
+
+// SaveCheckpoint создает или перезаписывает контрольную точку.
+func (c *Client) SaveCheckpoint(ctx context.Context, cp *models.Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	req := opensearchapi.IndexRequest{
+		Index:      CheckpointIndexName,
+		DocumentID: cp.ID,
+		Body:       bytes.NewReader(data),
+		Refresh:    "wait_for",
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("checkpoint indexing error: %s, body: %s", res.Status(), string(body))
+	}
+	return nil
+}
+
+// LatestCheckpoint возвращает контрольную точку с наибольшим to.
+func (c *Client) LatestCheckpoint(ctx context.Context) (*models.Checkpoint, error) {
+	return c.findCheckpoint(ctx, map[string]interface{}{
+		"query": map[string]interface{}{"match_all": map[string]interface{}{}},
+		"sort":  []interface{}{map[string]interface{}{"to": "desc"}},
+	})
+}
+
+// CheckpointAt возвращает контрольную точку, интервал которой содержит t.
+func (c *Client) CheckpointAt(ctx context.Context, t time.Time) (*models.Checkpoint, error) {
+	ts := t.Format(time.RFC3339Nano)
+	return c.findCheckpoint(ctx, map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"range": map[string]interface{}{"from": map[string]interface{}{"lte": ts}}},
+					map[string]interface{}{"range": map[string]interface{}{"to": map[string]interface{}{"gt": ts}}},
+				},
+			},
+		},
+	})
+}
+
+// findCheckpoint возвращает первую контрольную точку по запросу или storage.ErrNotFound.
+func (c *Client) findCheckpoint(ctx context.Context, body map[string]interface{}) (*models.Checkpoint, error) {
+	body["size"] = 1
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return nil, fmt.Errorf("failed to encode checkpoint query: %w", err)
+	}
+
+	req := opensearchapi.SearchRequest{
+		Index: []string{CheckpointIndexName},
+		Body:  &buf,
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint search failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("checkpoint search error: %s, body: %s", res.Status(), string(body))
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source *models.Checkpoint `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint response: %w", err)
+	}
+	if len(result.Hits.Hits) == 0 {
+		return nil, storage.ErrNotFound
+	}
+	return result.Hits.Hits[0].Source, nil
+}
+
+// SaveCheckpointLeaves заменяет листья контрольной точки: записывает их пачками
+// и удаляет листья с номерами за концом списка, оставшиеся от прежнего построения.
+func (c *Client) SaveCheckpointLeaves(ctx context.Context, checkpointID string, leaves []models.CheckpointLeaf) error {
+	for start := 0; start < len(leaves); start += deleteBatchSize {
+		end := min(start+deleteBatchSize, len(leaves))
+		if err := c.indexCheckpointLeaves(ctx, checkpointID, start, leaves[start:end]); err != nil {
+			return err
+		}
+	}
+
+	body := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"checkpoint_id": checkpointID}},
+					map[string]interface{}{"range": map[string]interface{}{"idx": map[string]interface{}{"gte": len(leaves)}}},
+				},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return fmt.Errorf("failed to encode checkpoint leaves query: %w", err)
+	}
+	refresh := true
+	req := opensearchapi.DeleteByQueryRequest{
+		Index:     []string{CheckpointLeafIndexName},
+		Body:      &buf,
+		Refresh:   &refresh,
+		Conflicts: "proceed",
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return fmt.Errorf("failed to delete stale checkpoint leaves: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("stale checkpoint leaves deletion error: %s, body: %s", res.Status(), string(body))
+	}
+	return nil
+}
+
+// indexCheckpointLeaves записывает листья с номерами от start одним bulk-запросом.
+func (c *Client) indexCheckpointLeaves(ctx context.Context, checkpointID string, start int, leaves []models.CheckpointLeaf) error {
+	var buf bytes.Buffer
+	for i, leaf := range leaves {
+		idx := start + i
+		meta, err := json.Marshal(map[string]interface{}{
+			"index": map[string]interface{}{
+				"_index": CheckpointLeafIndexName,
+				"_id":    fmt.Sprintf("%s/%d", checkpointID, idx),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode checkpoint leaf: %w", err)
+		}
+		data, err := json.Marshal(checkpointLeafDoc{CheckpointID: checkpointID, Idx: idx, CheckpointLeaf: leaf})
+		if err != nil {
+			return fmt.Errorf("failed to marshal checkpoint leaf: %w", err)
+		}
+		buf.Write(meta)
+		buf.WriteByte('\n')
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	req := opensearchapi.BulkRequest{Body: &buf, Refresh: "wait_for"}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint leaves: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("checkpoint leaves indexing error: %s, body: %s", res.Status(), string(body))
+	}
+	return bulkItemsError(res.Body)
+}
+
+// CheckpointLeaves возвращает листья контрольной точки по возрастанию номера.
+func (c *Client) CheckpointLeaves(ctx context.Context, checkpointID string) ([]models.CheckpointLeaf, error) {
+	query := map[string]interface{}{
+		"term": map[string]interface{}{"checkpoint_id": checkpointID},
+	}
+	sort := []interface{}{map[string]interface{}{"idx": "asc"}}
+
+	var leaves []models.CheckpointLeaf
+	err := c.scanIndex(ctx, CheckpointLeafIndexName, "", query, sort, func(source json.RawMessage) error {
+		var doc checkpointLeafDoc
+		if err := json.Unmarshal(source, &doc); err != nil {
+			return fmt.Errorf("failed to decode checkpoint leaf: %w", err)
+		}
+		leaves = append(leaves, doc.CheckpointLeaf)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint leaves search failed: %w", err)
+	}
+	return leaves, nil
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 	"witness/models"
+	"witness/storage"
 
 	"github.com/opensearch-project/opensearch-go/v2"
 	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
@@ -20,6 +21,8 @@ const IndexName = "audit-events"
 // Client - обертка над клиентом OpenSearch
 type Client struct {
 	os *opensearch.Client
+	// index - индекс событий для IndexBatch, Search, Get и Aggregate.
+	index string
 }
 
 // NewClient создает нового клиента OpenSearch
@@ -39,7 +42,7 @@ func NewClient(address string) (*Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create opensearch client: %w", err)
 	}
-	return &Client{os: client}, nil
+	return &Client{os: client, index: IndexName}, nil
 }
 
 // Ping проверяет соединение с OpenSearch
@@ -59,15 +62,38 @@ func (c *Client) Ping(ctx context.Context) error {
 	return nil
 }
 
-// EnsureIndexExists проверяет наличие индекса и создает его, если он не существует.
+// EnsureIndexExists проверяет наличие индексов и создает их, если они не существуют.
 func (c *Client) EnsureIndexExists(ctx context.Context) error {
 	// Сначала проверяем соединение
 	if err := c.Ping(ctx); err != nil {
 		return fmt.Errorf("OpenSearch not available: %w", err)
 	}
 
+	if err := c.ensureIndex(ctx, IndexName, eventsMapping); err != nil {
+		return err
+	}
+	if err := c.ensureIndex(ctx, LegalHoldIndexName, legalHoldsMapping); err != nil {
+		return err
+	}
+	if err := c.ensureIndex(ctx, CheckpointIndexName, checkpointsMapping); err != nil {
+		return err
+	}
+	if err := c.ensureIndex(ctx, CheckpointLeafIndexName, checkpointLeavesMapping); err != nil {
+		return err
+	}
+	if err := c.ensureIndex(ctx, ChainPruneIndexName, chainPrunesMapping); err != nil {
+		return err
+	}
+	if err := c.ensureIndex(ctx, APIKeyIndexName, apiKeysMapping); err != nil {
+		return err
+	}
+	return c.ensureIndex(ctx, ExportJobIndexName, exportJobsMapping)
+}
+
+// ensureIndex создает индекс с заданным маппингом, если его еще нет.
+func (c *Client) ensureIndex(ctx context.Context, index, mapping string) error {
 	req := opensearchapi.IndicesExistsRequest{
-		Index: []string{IndexName},
+		Index: []string{index},
 	}
 	res, err := req.Do(ctx, c.os)
 	if err != nil {
@@ -76,8 +102,8 @@ func (c *Client) EnsureIndexExists(ctx context.Context) error {
 	defer res.Body.Close()
 
 	if res.StatusCode == 404 {
-		slog.Info("index not found, creating it", "index", IndexName)
-		return c.createIndex(ctx)
+		slog.Info("index not found, creating it", "index", index)
+		return c.createIndex(ctx, index, mapping)
 	}
 
 	if res.IsError() {
@@ -85,12 +111,38 @@ func (c *Client) EnsureIndexExists(ctx context.Context) error {
 		return fmt.Errorf("error checking index existence: %s, body: %s", res.Status(), string(body))
 	}
 
-	slog.Info("index already exists", "index", IndexName)
+	slog.Info("index already exists", "index", index)
+	return c.updateMapping(ctx, index, mapping)
+}
+
+// updateMapping добавляет в существующий индекс поля, появившиеся в маппинге
+// после его создания. Типы уже существующих полей OpenSearch изменить не позволяет.
+func (c *Client) updateMapping(ctx context.Context, index, mapping string) error {
+	var body struct {
+		Mappings json.RawMessage `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(mapping), &body); err != nil {
+		return fmt.Errorf("invalid mapping for index %s: %w", index, err)
+	}
+
+	req := opensearchapi.IndicesPutMappingRequest{
+		Index: []string{index},
+		Body:  bytes.NewReader(body.Mappings),
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return fmt.Errorf("failed to update mapping: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("error updating mapping of %s: %s, body: %s", index, res.Status(), string(body))
+	}
 	return nil
 }
 
-func (c *Client) createIndex(ctx context.Context) error {
-	mapping := `{
+const eventsMapping = `{
         "settings": {
             "number_of_shards": 1,
             "number_of_replicas": 0
@@ -106,7 +158,7 @@ func (c *Client) createIndex(ctx context.Context) error {
                         "id": {"type": "keyword"},
                         "type": {"type": "keyword"},
                         "name": {"type": "text"},
-                        "ip_address": {"type": "ip"}
+                        "ip_address": {"type": "ip", "ignore_malformed": true}
                     }
                 },
                 "entity": {
@@ -128,13 +180,27 @@ func (c *Client) createIndex(ctx context.Context) error {
                         "access_level": {"type": "keyword"}
                     }
                 },
-                "details": {"type": "flattened"}
+                "details": {"type": "flattened"},
+                "signature_status": {"type": "keyword"},
+                "tenant": {"type": "keyword"},
+                "redactions": {"type": "integer"},
+                "pii": {"type": "object", "enabled": false},
+                "chain": {
+                    "properties": {
+                        "stream": {"type": "keyword"},
+                        "seq": {"type": "long"},
+                        "ingested_at": {"type": "date_nanos"},
+                        "prev_hash": {"type": "keyword"},
+                        "hash": {"type": "keyword"}
+                    }
+                }
             }
         }
     }`
 
+func (c *Client) createIndex(ctx context.Context, index, mapping string) error {
 	req := opensearchapi.IndicesCreateRequest{
-		Index: IndexName,
+		Index: index,
 		Body:  strings.NewReader(mapping),
 	}
 
@@ -150,15 +216,20 @@ func (c *Client) createIndex(ctx context.Context) error {
 		slog.Error("OpenSearch error response",
 			"status", res.Status(),
 			"body", string(body))
-		return fmt.Errorf("error fuck creating index: %s, body: %s", res.Status(), string(body))
+		return fmt.Errorf("error creating index: %s, body: %s", res.Status(), string(body))
 	}
 
-	slog.Info("index created successfully", "index", IndexName)
+	slog.Info("index created successfully", "index", index)
 	return nil
 }
 
 // IndexEventsBulk выполняет массовую вставку событий.
 func (c *Client) IndexEventsBulk(ctx context.Context, events []*models.AuditEvent) error {
+	return c.IndexEventsBulkTo(ctx, c.index, events)
+}
+
+// IndexEventsBulkTo выполняет массовую вставку событий в указанный индекс.
+func (c *Client) IndexEventsBulkTo(ctx context.Context, index string, events []*models.AuditEvent) error {
 	if len(events) == 0 {
 		return nil
 	}
@@ -166,12 +237,11 @@ func (c *Client) IndexEventsBulk(ctx context.Context, events []*models.AuditEven
 	var buf bytes.Buffer
 	for _, event := range events {
 		// Meta-данные для bulk-запроса
-		meta := []byte(fmt.Sprintf(`{ "index" : { "_index" : "%s", "_id" : "%s" } }%s`, IndexName, event.EventID, "\n"))
+		meta := []byte(fmt.Sprintf(`{ "index" : { "_index" : "%s", "_id" : "%s" } }%s`, index, event.EventID, "\n"))
 
 		data, err := json.Marshal(event)
 		if err != nil {
-			slog.Error("failed to marshal event for bulk indexing", "event_id", event.EventID, "error", err)
-			continue // Пропускаем сбойное событие
+			return fmt.Errorf("failed to marshal event %s for bulk indexing: %w", event.EventID, err)
 		}
 
 		buf.Grow(len(meta) + len(data) + 1)
@@ -194,21 +264,71 @@ func (c *Client) IndexEventsBulk(ctx context.Context, events []*models.AuditEven
 		body, _ := io.ReadAll(res.Body)
 		return fmt.Errorf("bulk indexing error: %s, body: %s", res.Status(), string(body))
 	}
+	if err := bulkItemsError(res.Body); err != nil {
+		return err
+	}
 
 	slog.Info("successfully indexed events in bulk", "count", len(events))
 	return nil
 }
 
-// SearchEvents выполняет поиск событий по заданным фильтрам.
-func (c *Client) SearchEvents(ctx context.Context, filter map[string]interface{}, limit, offset int) ([]*models.AuditEvent, int64, error) {
+// bulkResponse - ответ bulk API. Код 200 не означает, что записаны все документы:
+// об отказах по отдельным документам сообщают errors и items.
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string `json:"_id"`
+		Status int    `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
 
-	var query map[string]interface{}
-	// TODO: Динамическое построение запроса на основе фильтра.
-	// Пока что возвращаем все.
-	query = map[string]interface{}{
-		"query": map[string]interface{}{
-			"match_all": map[string]interface{}{},
-		},
+// bulkItemsError возвращает ошибку, если часть документов bulk-запроса не записана.
+// Повтор запроса безопасен: записанные документы перезапишутся с тем же _id.
+func bulkItemsError(body io.Reader) error {
+	var resp bulkResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if !resp.Errors {
+		return nil
+	}
+
+	var (
+		failed int
+		first  string
+	)
+	for _, item := range resp.Items {
+		for _, result := range item {
+			if result.Error == nil {
+				continue
+			}
+			if failed == 0 {
+				first = fmt.Sprintf("%s: %d %s: %s", result.ID, result.Status, result.Error.Type, result.Error.Reason)
+			}
+			failed++
+		}
+	}
+	if failed == 0 {
+		return fmt.Errorf("bulk indexing reported errors without failed items")
+	}
+	return fmt.Errorf("bulk indexing failed for %d of %d documents, first: %s", failed, len(resp.Items), first)
+}
+
+// IndexBatch реализует storage.EventStore.
+func (c *Client) IndexBatch(ctx context.Context, events []*models.AuditEvent) error {
+	return c.IndexEventsBulk(ctx, events)
+}
+
+// Search выполняет поиск событий по фильтру с пагинацией.
+func (c *Client) Search(ctx context.Context, q storage.Query) ([]*models.AuditEvent, int64, error) {
+	query := map[string]interface{}{
+		"query":            buildFilterQuery(q.Filter),
+		"sort":             eventsSort,
+		"track_total_hits": true,
 	}
 
 	var buf bytes.Buffer
@@ -217,10 +337,10 @@ func (c *Client) SearchEvents(ctx context.Context, filter map[string]interface{}
 	}
 
 	req := opensearchapi.SearchRequest{
-		Index: []string{IndexName},
+		Index: []string{c.index},
 		Body:  &buf,
-		Size:  &limit,
-		From:  &offset,
+		Size:  &q.Limit,
+		From:  &q.Offset,
 	}
 
 	res, err := req.Do(ctx, c.os)
@@ -255,3 +375,90 @@ func (c *Client) SearchEvents(ctx context.Context, filter map[string]interface{}
 
 	return events, result.Hits.Total.Value, nil
 }
+
+// Get возвращает событие по event_id.
+func (c *Client) Get(ctx context.Context, eventID string) (*models.AuditEvent, error) {
+	req := opensearchapi.GetRequest{
+		Index:      c.index,
+		DocumentID: eventID,
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, storage.ErrNotFound
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("get event error: %s", res.Status())
+	}
+
+	var result struct {
+		Source *models.AuditEvent `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+	return result.Source, nil
+}
+
+// Aggregate считает количество событий по значениям поля (terms-агрегация).
+func (c *Client) Aggregate(ctx context.Context, filter *models.AuditEventFilter, field string, size int) ([]*models.FacetBucket, error) {
+	if err := storage.ValidateFacetField(field); err != nil {
+		return nil, err
+	}
+
+	query := map[string]interface{}{
+		"size":  0,
+		"query": buildFilterQuery(filter),
+		"aggs": map[string]interface{}{
+			"facet": map[string]interface{}{
+				"terms": map[string]interface{}{
+					"field": field,
+					"size":  size,
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, fmt.Errorf("failed to encode aggregation query: %w", err)
+	}
+
+	req := opensearchapi.SearchRequest{
+		Index: []string{c.index},
+		Body:  &buf,
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return nil, fmt.Errorf("aggregation request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("aggregation request error: %s", res.Status())
+	}
+
+	var result struct {
+		Aggregations struct {
+			Facet struct {
+				Buckets []struct {
+					Key      string `json:"key"`
+					DocCount int    `json:"doc_count"`
+				} `json:"buckets"`
+			} `json:"facet"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode aggregation response: %w", err)
+	}
+
+	buckets := make([]*models.FacetBucket, len(result.Aggregations.Facet.Buckets))
+	for i, b := range result.Aggregations.Facet.Buckets {
+		buckets[i] = &models.FacetBucket{Key: b.Key, Count: b.DocCount}
+	}
+	return buckets, nil
+}
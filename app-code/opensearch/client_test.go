@@ -0,0 +1,62 @@
+package opensearch
+
+import (
+	"context"
+	"os"
+	"testing"
+	"witness/models"
+	"witness/storage"
+	"witness/storage/storagetest"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+// TestConformance работает с кластером из WITNESS_TEST_OPENSEARCH_URL. Индексы Witness
+// в нем удаляются перед каждым подтестом: не указывайте кластер с настоящими данными.
+func TestConformance(t *testing.T) {
+	url := os.Getenv("WITNESS_TEST_OPENSEARCH_URL")
+	if url == "" {
+		t.Skip("WITNESS_TEST_OPENSEARCH_URL is not set")
+	}
+	storagetest.Run(t, func(t *testing.T) storage.Backend {
+		ctx := context.Background()
+		client, err := NewClient(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		del := opensearchapi.IndicesDeleteRequest{
+			Index:             []string{IndexName, ChainPruneIndexName, LegalHoldIndexName, CheckpointIndexName, CheckpointLeafIndexName, APIKeyIndexName, ExportJobIndexName},
+			IgnoreUnavailable: opensearchapi.BoolPtr(true),
+		}
+		res, err := del.Do(ctx, client.os)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.IsError() {
+			t.Fatalf("failed to delete indices: %s", res.Status())
+		}
+		if err := client.EnsureIndexExists(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return refreshing{client}
+	})
+}
+
+// refreshing делает записанные события сразу видимыми для поиска, как у остальных хранилищ.
+type refreshing struct {
+	*Client
+}
+
+func (c refreshing) IndexBatch(ctx context.Context, events []*models.AuditEvent) error {
+	if err := c.Client.IndexBatch(ctx, events); err != nil {
+		return err
+	}
+	refresh := opensearchapi.IndicesRefreshRequest{Index: []string{IndexName}}
+	res, err := refresh.Do(ctx, c.os)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
@@ -0,0 +1,44 @@
+package opensearch
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	"witness/models"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+// Сколько снимок индекса живет между запросами страниц.
+const (
+	pitKeepAlive    = "1m"
+	pitKeepAliveDur = time.Minute
+)
+
+// Scan реализует storage.EventStore: читает события в снимке индекса (point in time)
+// по search_after, поэтому выгрузка не ограничена окном from/size и не сдвигается
+// от событий, записанных во время чтения.
+func (c *Client) Scan(ctx context.Context, filter *models.AuditEventFilter, fn func(*models.AuditEvent) error) error {
+	create := opensearchapi.PointInTimeCreateRequest{Index: []string{c.index}, KeepAlive: pitKeepAliveDur}
+	res, pit, err := create.Do(ctx, c.os)
+	if err != nil {
+		return fmt.Errorf("failed to create point in time: %w", err)
+	}
+	res.Body.Close()
+	if res.IsError() || pit == nil || pit.PitID == "" {
+		return fmt.Errorf("create point in time error: %s", res.Status())
+	}
+	defer func() {
+		// Контекст выгрузки может быть уже отменен, а снимок держит ресурсы кластера до истечения keep_alive.
+		del := opensearchapi.PointInTimeDeleteRequest{PitID: []string{pit.PitID}}
+		res, _, err := del.Do(context.Background(), c.os)
+		if err != nil {
+			slog.Warn("failed to delete point in time", "error", err)
+			return
+		}
+		res.Body.Close()
+	}()
+
+	return c.scan(ctx, pit.PitID, buildFilterQuery(filter), eventsSort, fn)
+}
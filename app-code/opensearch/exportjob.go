@@ -0,0 +1,210 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"witness/models"
+	"witness/storage"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+const ExportJobIndexName = "export-jobs"
+
+const exportJobsMapping = `{
+        "settings": {
+            "number_of_shards": 1,
+            "number_of_replicas": 0
+        },
+        "mappings": {
+            "properties": {
+                "id": {"type": "keyword"},
+                "status": {"type": "keyword"},
+                "format": {"type": "keyword"},
+                "filter": {"type": "object", "enabled": false},
+                "columns": {"type": "keyword"},
+                "gzip": {"type": "boolean"},
+                "created_by": {"type": "keyword"},
+                "roles": {"type": "keyword"},
+                "tenant": {"type": "keyword"},
+                "rows": {"type": "long"},
+                "total": {"type": "long"},
+                "size": {"type": "long"},
+                "checksum": {"type": "keyword"},
+                "key": {"type": "keyword"},
+                "error": {"type": "text"},
+                "created_at": {"type": "date_nanos"},
+                "started_at": {"type": "date_nanos"},
+                "finished_at": {"type": "date_nanos"},
+                "expires_at": {"type": "date_nanos"},
+                "owner": {"type": "keyword"},
+                "lease_until": {"type": "date_nanos"}
+            }
+        }
+    }`
+
+// SaveExportJob создает или перезаписывает задание выгрузки.
+// Запрос ждет обновления индекса, чтобы восстановление после перезапуска видело задание.
+func (c *Client) SaveExportJob(ctx context.Context, job *models.ExportJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal export job: %w", err)
+	}
+
+	req := opensearchapi.IndexRequest{
+		Index:      ExportJobIndexName,
+		DocumentID: job.ID,
+		Body:       bytes.NewReader(data),
+		Refresh:    "wait_for",
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return fmt.Errorf("failed to save export job: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("export job indexing error: %s, body: %s", res.Status(), string(body))
+	}
+	return nil
+}
+
+// GetExportJob возвращает задание выгрузки по идентификатору.
+func (c *Client) GetExportJob(ctx context.Context, id string) (*models.ExportJob, error) {
+	job, _, err := c.getExportJob(ctx, id)
+	return job, err
+}
+
+// ClaimExportJob забирает задание выгрузки. Атомарность обеспечивает оптимистическая
+// блокировка: запись проходит, только если документ не менялся с момента чтения.
+func (c *Client) ClaimExportJob(ctx context.Context, id, owner string, now, leaseUntil time.Time) (*models.ExportJob, error) {
+	job, version, err := c.getExportJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !storage.Claimable(job, now) {
+		return nil, storage.ErrClaimed
+	}
+	job.Status, job.Owner, job.LeaseUntil = models.ExportRunning, owner, &leaseUntil
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal export job: %w", err)
+	}
+
+	req := opensearchapi.IndexRequest{
+		Index:         ExportJobIndexName,
+		DocumentID:    id,
+		Body:          bytes.NewReader(data),
+		IfSeqNo:       &version.SeqNo,
+		IfPrimaryTerm: &version.PrimaryTerm,
+		Refresh:       "wait_for",
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim export job: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 409 {
+		return nil, storage.ErrClaimed
+	}
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("export job claim error: %s, body: %s", res.Status(), string(body))
+	}
+	return job, nil
+}
+
+// docVersion - версия документа для оптимистической блокировки.
+type docVersion struct {
+	SeqNo       int `json:"_seq_no"`
+	PrimaryTerm int `json:"_primary_term"`
+}
+
+// getExportJob возвращает задание выгрузки и версию его документа.
+func (c *Client) getExportJob(ctx context.Context, id string) (*models.ExportJob, docVersion, error) {
+	req := opensearchapi.GetRequest{
+		Index:      ExportJobIndexName,
+		DocumentID: id,
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return nil, docVersion{}, fmt.Errorf("failed to get export job: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, docVersion{}, storage.ErrNotFound
+	}
+	if res.IsError() {
+		return nil, docVersion{}, fmt.Errorf("get export job error: %s", res.Status())
+	}
+
+	var result struct {
+		docVersion
+		Source *models.ExportJob `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, docVersion{}, fmt.Errorf("failed to decode export job: %w", err)
+	}
+	return result.Source, result.docVersion, nil
+}
+
+// ListExportJobs возвращает задания выгрузки, при unfinishedOnly - только ожидающие и выполняющиеся.
+func (c *Client) ListExportJobs(ctx context.Context, unfinishedOnly bool) ([]*models.ExportJob, error) {
+	query := map[string]interface{}{
+		"match_all": map[string]interface{}{},
+	}
+	if unfinishedOnly {
+		query = map[string]interface{}{
+			"terms": map[string]interface{}{"status": []string{models.ExportPending, models.ExportRunning}},
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{
+		"query": query,
+		"sort":  []interface{}{map[string]interface{}{"created_at": "desc"}},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to encode export jobs query: %w", err)
+	}
+
+	// Задания с истекшим сроком остаются, но их немного: одной страницы достаточно.
+	size := 10000
+	req := opensearchapi.SearchRequest{
+		Index: []string{ExportJobIndexName},
+		Body:  &buf,
+		Size:  &size,
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return nil, fmt.Errorf("export jobs search failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("export jobs search error: %s", res.Status())
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source *models.ExportJob `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode export jobs response: %w", err)
+	}
+
+	jobs := make([]*models.ExportJob, len(result.Hits.Hits))
+	for i, hit := range result.Hits.Hits {
+		jobs[i] = hit.Source
+	}
+	return jobs, nil
+}
@@ -0,0 +1,147 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"witness/models"
+	"witness/storage"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+const LegalHoldIndexName = "legal-holds"
+
+const legalHoldsMapping = `{
+        "settings": {
+            "number_of_shards": 1,
+            "number_of_replicas": 0
+        },
+        "mappings": {
+            "properties": {
+                "id": {"type": "keyword"},
+                "reason": {"type": "text"},
+                "owner": {"type": "keyword"},
+                "status": {"type": "keyword"},
+                "actor_id": {"type": "keyword"},
+                "entity_id": {"type": "keyword"},
+                "from": {"type": "date_nanos"},
+                "to": {"type": "date_nanos"},
+                "created_at": {"type": "date_nanos"},
+                "updated_at": {"type": "date_nanos"},
+                "released_at": {"type": "date_nanos"},
+                "released_by": {"type": "keyword"}
+            }
+        }
+    }`
+
+// SaveLegalHold создает или перезаписывает удержание.
+// Запрос ждет обновления индекса, чтобы механизм хранения сразу увидел изменения.
+func (c *Client) SaveLegalHold(ctx context.Context, hold *models.LegalHold) error {
+	data, err := json.Marshal(hold)
+	if err != nil {
+		return fmt.Errorf("failed to marshal legal hold: %w", err)
+	}
+
+	req := opensearchapi.IndexRequest{
+		Index:      LegalHoldIndexName,
+		DocumentID: hold.ID,
+		Body:       bytes.NewReader(data),
+		Refresh:    "wait_for",
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return fmt.Errorf("failed to save legal hold: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("legal hold indexing error: %s, body: %s", res.Status(), string(body))
+	}
+	return nil
+}
+
+// GetLegalHold возвращает удержание по идентификатору.
+func (c *Client) GetLegalHold(ctx context.Context, id string) (*models.LegalHold, error) {
+	req := opensearchapi.GetRequest{
+		Index:      LegalHoldIndexName,
+		DocumentID: id,
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get legal hold: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, storage.ErrNotFound
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("get legal hold error: %s", res.Status())
+	}
+
+	var result struct {
+		Source *models.LegalHold `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode legal hold: %w", err)
+	}
+	return result.Source, nil
+}
+
+// ListLegalHolds возвращает удержания, при activeOnly - только действующие.
+func (c *Client) ListLegalHolds(ctx context.Context, activeOnly bool) ([]*models.LegalHold, error) {
+	query := map[string]interface{}{
+		"match_all": map[string]interface{}{},
+	}
+	if activeOnly {
+		query = map[string]interface{}{
+			"term": map[string]interface{}{"status": models.LegalHoldActive},
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{
+		"query": query,
+		"sort":  []interface{}{map[string]interface{}{"created_at": "desc"}},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to encode legal holds query: %w", err)
+	}
+
+	// Удержаний немного, поэтому одной страницы достаточно.
+	size := 10000
+	req := opensearchapi.SearchRequest{
+		Index: []string{LegalHoldIndexName},
+		Body:  &buf,
+		Size:  &size,
+	}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return nil, fmt.Errorf("legal holds search failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("legal holds search error: %s", res.Status())
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source *models.LegalHold `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode legal holds response: %w", err)
+	}
+
+	holds := make([]*models.LegalHold, len(result.Hits.Hits))
+	for i, hit := range result.Hits.Hits {
+		holds[i] = hit.Source
+	}
+	return holds, nil
+}
@@ -0,0 +1,52 @@
+package opensearch
+
+import (
+	"time"
+	"witness/models"
+	"witness/storage"
+)
+
+// eventsSort - порядок выдачи событий, совпадающий с storage.Less.
+var eventsSort = []interface{}{
+	map[string]interface{}{"timestamp": "desc"},
+	map[string]interface{}{"event_id": "desc"},
+}
+
+// buildFilterQuery переводит фильтр GraphQL в bool-запрос OpenSearch.
+func buildFilterQuery(filter *models.AuditEventFilter) map[string]interface{} {
+	filters := make([]interface{}, 0)
+	for field, value := range storage.FilterTerms(filter) {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{field: value},
+		})
+	}
+
+	if filter != nil && (filter.From != nil || filter.To != nil) {
+		rng := map[string]interface{}{}
+		if filter.From != nil {
+			rng["gte"] = filter.From.Format(time.RFC3339Nano)
+		}
+		if filter.To != nil {
+			rng["lte"] = filter.To.Format(time.RFC3339Nano)
+		}
+		filters = append(filters, map[string]interface{}{
+			"range": map[string]interface{}{"timestamp": rng},
+		})
+	}
+
+	if filter != nil && filter.Name != nil {
+		// cross_fields + and: каждое слово должно найтись хотя бы в одном из полей.
+		filters = append(filters, map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":    *filter.Name,
+				"fields":   []string{"actor.name", "entity.name"},
+				"type":     "cross_fields",
+				"operator": "and",
+			},
+		})
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{"filter": filters},
+	}
+}
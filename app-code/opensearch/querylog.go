@@ -0,0 +1,16 @@
+package opensearch
+
+import "context"
+
+// QueryLogIndexName - индекс журнала запросов к API (см. пакет querylog).
+const QueryLogIndexName = "audit-queries"
+
+// WithIndex возвращает клиента, у которого IndexBatch, Search, Get и Aggregate работают
+// с индексом index, и создает этот индекс с маппингом событий, если его нет.
+// Удержания, цепочки, архив и retention по-прежнему относятся к основному индексу.
+func (c *Client) WithIndex(ctx context.Context, index string) (*Client, error) {
+	if err := c.ensureIndex(ctx, index, eventsMapping); err != nil {
+		return nil, err
+	}
+	return &Client{os: c.os, index: index}, nil
+}
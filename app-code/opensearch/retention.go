@@ -0,0 +1,279 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"witness/models"
+	"witness/storage"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+// ChainPruneIndexName - индекс отметок об удаленных номерах цепочек (models.ChainPrune).
+const ChainPruneIndexName = "audit-chain-prunes"
+
+const chainPrunesMapping = `{
+        "settings": {
+            "number_of_shards": 1,
+            "number_of_replicas": 0
+        },
+        "mappings": {
+            "properties": {
+                "stream": {"type": "keyword"},
+                "first_seq": {"type": "long"},
+                "last_seq": {"type": "long"},
+                "last_hash": {"type": "keyword", "index": false},
+                "reason": {"type": "keyword"},
+                "pruned_at": {"type": "date_nanos"}
+            }
+        }
+    }`
+
+// deleteBatchSize - сколько событий удаляется одним bulk-запросом.
+const deleteBatchSize = 1000
+
+// DeleteEventsBefore удаляет события старше before, кроме попадающих под активные удержания.
+// Возвращает количество удаленных документов.
+func (c *Client) DeleteEventsBefore(ctx context.Context, before time.Time, holds []*models.LegalHold) (int64, error) {
+	return c.deleteEvents(ctx, map[string]interface{}{
+		"range": map[string]interface{}{
+			"timestamp": map[string]interface{}{"lt": before.Format(time.RFC3339Nano)},
+		},
+	}, holds, models.PruneRetention)
+}
+
+// DeleteEventsByID удаляет события с указанными идентификаторами, кроме попадающих под активные удержания.
+// Удаленные номера цепочек отмечаются как выгруженные в архив.
+func (c *Client) DeleteEventsByID(ctx context.Context, ids []string, holds []*models.LegalHold) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return c.deleteEvents(ctx, map[string]interface{}{
+		"ids": map[string]interface{}{"values": ids},
+	}, holds, models.PruneArchive)
+}
+
+// deleteEvents удаляет события по фильтру, исключая события под удержанием, и отмечает
+// удаленные номера цепочек с причиной reason. delete_by_query не сообщает, что удалено,
+// поэтому события сначала читаются, а удаляются пачками по _id.
+func (c *Client) deleteEvents(ctx context.Context, filter map[string]interface{}, holds []*models.LegalHold, reason string) (int64, error) {
+	mustNot := make([]interface{}, 0, len(holds))
+	for _, hold := range holds {
+		if hold.IsActive() {
+			mustNot = append(mustNot, legalHoldQuery(hold))
+		}
+	}
+	query := map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter":   []interface{}{filter},
+			"must_not": mustNot,
+		},
+	}
+	sort := []interface{}{
+		map[string]interface{}{"timestamp": "asc"},
+		map[string]interface{}{"event_id": "asc"},
+	}
+
+	var (
+		deleted int64
+		ids     []string
+		links   []*models.ChainLink
+	)
+	flush := func() error {
+		if len(ids) == 0 {
+			return nil
+		}
+		n, err := c.deleteByID(ctx, ids)
+		if err != nil {
+			return err
+		}
+		deleted += n
+		// Отметки пишутся после удаления: если запись не удастся, проверка покажет
+		// пропуск как удаление, но не скроет настоящего.
+		if err := c.saveChainPrunes(ctx, storage.Prunes(links, reason, time.Now())); err != nil {
+			return err
+		}
+		ids, links = ids[:0], links[:0]
+		return nil
+	}
+
+	// Удаление позади курсора search_after не сдвигает следующие страницы.
+	err := c.scan(ctx, "", query, sort, func(event *models.AuditEvent) error {
+		ids = append(ids, event.EventID)
+		links = append(links, event.Chain)
+		if len(ids) >= deleteBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return deleted, err
+	}
+	if deleted == 0 {
+		return 0, nil
+	}
+
+	refresh := opensearchapi.IndicesRefreshRequest{Index: []string{IndexName}}
+	res, err := refresh.Do(ctx, c.os)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to refresh index: %w", err)
+	}
+	res.Body.Close()
+	if res.IsError() {
+		return deleted, fmt.Errorf("refresh index error: %s", res.Status())
+	}
+	return deleted, nil
+}
+
+// deleteByID удаляет события по _id одним bulk-запросом и возвращает число удаленных.
+func (c *Client) deleteByID(ctx context.Context, ids []string) (int64, error) {
+	var buf bytes.Buffer
+	for _, id := range ids {
+		meta, err := json.Marshal(map[string]interface{}{
+			"delete": map[string]interface{}{"_index": IndexName, "_id": id},
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode delete of %s: %w", id, err)
+		}
+		buf.Write(meta)
+		buf.WriteByte('\n')
+	}
+
+	req := opensearchapi.BulkRequest{Body: &buf}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return 0, fmt.Errorf("failed to perform bulk delete: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return 0, fmt.Errorf("bulk delete error: %s, body: %s", res.Status(), string(body))
+	}
+
+	var result struct {
+		Items []map[string]struct {
+			Result string `json:"result"`
+			Error  *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("failed to decode bulk delete response: %w", err)
+	}
+	var deleted int64
+	for _, item := range result.Items {
+		for _, r := range item {
+			if r.Error != nil {
+				return deleted, fmt.Errorf("bulk delete failed: %s: %s", r.Error.Type, r.Error.Reason)
+			}
+			if r.Result == "deleted" {
+				deleted++
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// saveChainPrunes записывает отметки об удалении. _id - поток и первый номер отметки.
+func (c *Client) saveChainPrunes(ctx context.Context, prunes []*models.ChainPrune) error {
+	if len(prunes) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, prune := range prunes {
+		meta, err := json.Marshal(map[string]interface{}{
+			"index": map[string]interface{}{
+				"_index": ChainPruneIndexName,
+				"_id":    fmt.Sprintf("%s/%d", prune.Stream, prune.FirstSeq),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode chain prune: %w", err)
+		}
+		data, err := json.Marshal(prune)
+		if err != nil {
+			return fmt.Errorf("failed to marshal chain prune: %w", err)
+		}
+		buf.Write(meta)
+		buf.WriteByte('\n')
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	req := opensearchapi.BulkRequest{Body: &buf, Refresh: "wait_for"}
+	res, err := req.Do(ctx, c.os)
+	if err != nil {
+		return fmt.Errorf("failed to save chain prunes: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("chain prunes indexing error: %s, body: %s", res.Status(), string(body))
+	}
+	return bulkItemsError(res.Body)
+}
+
+// ChainPrunes возвращает отметки об удалении событий потока по возрастанию first_seq.
+func (c *Client) ChainPrunes(ctx context.Context, stream string) ([]*models.ChainPrune, error) {
+	query := map[string]interface{}{
+		"term": map[string]interface{}{"stream": stream},
+	}
+	// Отметки потока различаются первым номером (он же входит в _id).
+	sort := []interface{}{map[string]interface{}{"first_seq": "asc"}}
+
+	var prunes []*models.ChainPrune
+	err := c.scanIndex(ctx, ChainPruneIndexName, "", query, sort, func(source json.RawMessage) error {
+		var prune models.ChainPrune
+		if err := json.Unmarshal(source, &prune); err != nil {
+			return fmt.Errorf("failed to decode chain prune: %w", err)
+		}
+		prunes = append(prunes, &prune)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("chain prunes search failed: %w", err)
+	}
+	return prunes, nil
+}
+
+// legalHoldQuery строит запрос, которому соответствуют события под удержанием.
+func legalHoldQuery(hold *models.LegalHold) map[string]interface{} {
+	filters := make([]interface{}, 0, 3)
+	if hold.ActorID != nil {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"actor.id": *hold.ActorID},
+		})
+	}
+	if hold.EntityID != nil {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"entity.id": *hold.EntityID},
+		})
+	}
+	if hold.From != nil || hold.To != nil {
+		rng := map[string]interface{}{}
+		if hold.From != nil {
+			rng["gte"] = hold.From.Format(time.RFC3339Nano)
+		}
+		if hold.To != nil {
+			rng["lte"] = hold.To.Format(time.RFC3339Nano)
+		}
+		filters = append(filters, map[string]interface{}{
+			"range": map[string]interface{}{"timestamp": rng},
+		})
+	}
+	return map[string]interface{}{
+		"bool": map[string]interface{}{"filter": filters},
+	}
+}
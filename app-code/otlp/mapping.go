@@ -0,0 +1,246 @@
+package otlp
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"witness/models"
+
+	"github.com/google/uuid"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Атрибуты, из которых строится событие. Собственные атрибуты audit.* главнее
+// семантических соглашений OpenTelemetry, которые идут после них.
+var (
+	eventIDKeys       = []string{"audit.event_id", "log.record.uid"}
+	eventTypeKeys     = []string{"audit.event_type", "event.name"}
+	statusKeys        = []string{"audit.status"}
+	actorIDKeys       = []string{"audit.actor.id", "user.id", "enduser.id"}
+	actorTypeKeys     = []string{"audit.actor.type"}
+	actorNameKeys     = []string{"audit.actor.name", "user.name", "user.full_name"}
+	actorIPKeys       = []string{"audit.actor.ip_address", "client.address"}
+	entityIDKeys      = []string{"audit.entity.id"}
+	entityTypeKeys    = []string{"audit.entity.type"}
+	entityNameKeys    = []string{"audit.entity.name"}
+	sourceServiceKeys = []string{"audit.source_service", "service.name"}
+	requestIDKeys     = []string{"audit.request_id"}
+	accessLevelKeys   = []string{"audit.access_level"}
+)
+
+// eventNamespace - пространство имен UUID для event_id записей без собственного идентификатора.
+var eventNamespace = uuid.MustParse("7f1c4c36-3b0e-4d8e-9a51-0c2f9d6b8e14")
+
+// Mapper отбирает записи логов с атрибутом-признаком события аудита и переводит их в события.
+type Mapper struct {
+	key   string
+	value string
+}
+
+// NewMapper создает преобразователь. Событием аудита считается запись, у которой
+// атрибут key (записи или ресурса) равен value, например audit=true.
+func NewMapper(key, value string) *Mapper {
+	return &Mapper{key: key, value: value}
+}
+
+// ParseMapper разбирает признак события аудита в виде key=value.
+func ParseMapper(attribute string) (*Mapper, error) {
+	key, value, ok := strings.Cut(attribute, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return nil, fmt.Errorf("invalid audit attribute %q, expected key=value", attribute)
+	}
+	return NewMapper(strings.TrimSpace(key), strings.TrimSpace(value)), nil
+}
+
+// attributes - атрибуты записи поверх атрибутов ресурса.
+type attributes struct {
+	record   map[string]*commonpb.AnyValue
+	resource map[string]*commonpb.AnyValue
+	// used - атрибуты записи, перенесенные в поля события; в details они не попадают.
+	used map[string]bool
+}
+
+func newAttributes(resource, record []*commonpb.KeyValue) *attributes {
+	a := &attributes{
+		record:   make(map[string]*commonpb.AnyValue, len(record)),
+		resource: make(map[string]*commonpb.AnyValue, len(resource)),
+		used:     make(map[string]bool),
+	}
+	for _, kv := range resource {
+		a.resource[kv.GetKey()] = kv.GetValue()
+	}
+	for _, kv := range record {
+		a.record[kv.GetKey()] = kv.GetValue()
+	}
+	return a
+}
+
+// get возвращает строковое значение первого найденного из keys атрибута.
+func (a *attributes) get(keys ...string) string {
+	for _, key := range keys {
+		if v, ok := a.record[key]; ok {
+			a.used[key] = true
+			return stringValue(v)
+		}
+		if v, ok := a.resource[key]; ok {
+			return stringValue(v)
+		}
+	}
+	return ""
+}
+
+// Match сообщает, что запись - событие аудита.
+func (m *Mapper) Match(resource *logspb.ResourceLogs, record *logspb.LogRecord) bool {
+	return newAttributes(resource.GetResource().GetAttributes(), record.GetAttributes()).get(m.key) == m.value
+}
+
+// Map переводит запись лога в событие аудита. Проверку обязательных полей выполняет
+// конвейер приема. Без атрибута с идентификатором event_id выводится из содержимого
+// записи, поэтому повторная отправка той же записи не создает второе событие.
+func (m *Mapper) Map(resource *logspb.ResourceLogs, record *logspb.LogRecord) *models.AuditEvent {
+	attrs := newAttributes(resource.GetResource().GetAttributes(), record.GetAttributes())
+	attrs.used[m.key] = true
+
+	event := &models.AuditEvent{
+		EventID:   attrs.get(eventIDKeys...),
+		EventType: attrs.get(eventTypeKeys...),
+		Status:    attrs.get(statusKeys...),
+		Actor: models.Actor{
+			ID:        attrs.get(actorIDKeys...),
+			Type:      attrs.get(actorTypeKeys...),
+			Name:      attrs.get(actorNameKeys...),
+			IPAddress: attrs.get(actorIPKeys...),
+		},
+		Entity: models.Entity{
+			ID:   attrs.get(entityIDKeys...),
+			Type: attrs.get(entityTypeKeys...),
+			Name: attrs.get(entityNameKeys...),
+		},
+		Context: models.Context{
+			SourceService: attrs.get(sourceServiceKeys...),
+			RequestID:     attrs.get(requestIDKeys...),
+		},
+	}
+	if event.EventType == "" {
+		event.EventType = record.GetEventName()
+	}
+	if event.Status == "" {
+		event.Status = "SUCCESS"
+		if record.GetSeverityNumber() >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR {
+			event.Status = "FAILURE"
+		}
+	}
+	if event.Actor.Type == "" && event.Actor.ID != "" {
+		event.Actor.Type = "USER"
+	}
+	if level := attrs.get(accessLevelKeys...); level != "" {
+		event.Security = &models.Security{AccessLevel: level}
+	}
+
+	switch ts := record.GetTimeUnixNano(); {
+	case ts != 0:
+		event.Timestamp = time.Unix(0, int64(ts)).UTC()
+	case record.GetObservedTimeUnixNano() != 0:
+		event.Timestamp = time.Unix(0, int64(record.GetObservedTimeUnixNano())).UTC()
+	}
+	if id := record.GetTraceId(); len(id) == 16 && !isZero(id) {
+		event.Context.TraceID = hex.EncodeToString(id)
+	}
+
+	details := make(map[string]any)
+	for key, v := range attrs.record {
+		if !attrs.used[key] {
+			details[key] = anyValue(v)
+		}
+	}
+	if body := record.GetBody(); body != nil {
+		if _, ok := body.GetValue().(*commonpb.AnyValue_StringValue); ok {
+			details["message"] = body.GetStringValue()
+		} else {
+			details["body"] = anyValue(body)
+		}
+	}
+	if len(details) > 0 {
+		event.Details = details
+	}
+
+	if event.EventID == "" {
+		event.EventID = derivedID(resource, record)
+	}
+	return event
+}
+
+// derivedID выводит event_id из ресурса и содержимого записи.
+func derivedID(resource *logspb.ResourceLogs, record *logspb.LogRecord) string {
+	opts := proto.MarshalOptions{Deterministic: true}
+	h := sha1.New()
+	if data, err := opts.Marshal(resource.GetResource()); err == nil {
+		h.Write(data)
+	}
+	if data, err := opts.Marshal(record); err == nil {
+		h.Write(data)
+	}
+	return uuid.NewSHA1(eventNamespace, h.Sum(nil)).String()
+}
+
+// stringValue возвращает значение атрибута строкой.
+func stringValue(v *commonpb.AnyValue) string {
+	switch v := anyValue(v).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// anyValue переводит значение OTLP в значение JSON; байты кодируются base64.
+func anyValue(v *commonpb.AnyValue) any {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return v.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return v.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return v.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]any, 0, len(v.ArrayValue.GetValues()))
+		for _, item := range v.ArrayValue.GetValues() {
+			values = append(values, anyValue(item))
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		values := make(map[string]any, len(v.KvlistValue.GetValues()))
+		for _, kv := range v.KvlistValue.GetValues() {
+			values[kv.GetKey()] = anyValue(kv.GetValue())
+		}
+		return values
+	}
+	return nil
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
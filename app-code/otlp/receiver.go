@@ -0,0 +1,186 @@
+// Package otlp принимает логи OpenTelemetry (OTLP/HTTP и OTLP/gRPC) и превращает
+// записи с атрибутом-признаком события аудита в события, которые проходят обычный конвейер приема.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"witness/auth"
+	"witness/ingest"
+	"witness/models"
+	"witness/tenant"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/grpc/codes"
+
+	// Коллекторы OpenTelemetry по умолчанию сжимают запросы OTLP/gRPC.
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// ErrUnavailable - ни одно событие запроса не удалось подготовить из-за временной ошибки;
+// экспортер может повторить запрос целиком.
+var ErrUnavailable = errors.New("audit events are temporarily not accepted")
+
+// Receiver принимает логи OTLP и реализует службу LogsService OTLP/gRPC.
+type Receiver struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mapper   *Mapper
+	pipeline *ingest.Pipeline
+	buffer   *ingest.Buffer
+	tenants  *tenant.Resolver
+	// stream - поток цепочки хешей для событий, принятых этим экземпляром по OTLP.
+	stream string
+}
+
+// NewReceiver создает приемник. Записи, которые mapper признал событиями аудита,
+// проходят pipeline и сохраняются через buffer в цепочке потока stream (своего у каждого экземпляра Witness).
+func NewReceiver(mapper *Mapper, pipeline *ingest.Pipeline, buffer *ingest.Buffer, tenants *tenant.Resolver, stream string) *Receiver {
+	return &Receiver{mapper: mapper, pipeline: pipeline, buffer: buffer, tenants: tenants, stream: stream}
+}
+
+// Result - итог приема запроса OTLP. Записи без признака события аудита пропускаются
+// и не считаются отклоненными.
+type Result struct {
+	Accepted int
+	Skipped  int
+	// Rejected - события, не прошедшие проверку, и события, не подготовленные из-за
+	// временной ошибки: повтор запроса целиком продублировал бы принятые события.
+	Rejected int
+	Errors   []string
+}
+
+// Consume проводит события аудита из запроса через конвейер приема от имени субъекта из ctx.
+// header - арендатор из заголовка или метаданных witness-tenant. Если буфер записи переполнен
+// или ни одно событие не принято из-за временной ошибки, возвращает ErrUnavailable.
+func (r *Receiver) Consume(ctx context.Context, req *collogspb.ExportLogsServiceRequest, header string) (Result, error) {
+	var res Result
+	if err := r.buffer.Admit(); err != nil {
+		return res, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	meta := ingest.Meta{Tenant: r.tenants.ResolveCaller(ctx, header)}
+	failed := 0
+	for _, resource := range req.GetResourceLogs() {
+		for _, scope := range resource.GetScopeLogs() {
+			for _, record := range scope.GetLogRecords() {
+				if !r.mapper.Match(resource, record) {
+					res.Skipped++
+					continue
+				}
+				data, err := json.Marshal(r.mapper.Map(resource, record))
+				if err != nil {
+					return res, err
+				}
+				event, err := r.pipeline.Process(ctx, r.stream, data, meta)
+				switch {
+				case err == nil:
+					// Пачку могут сохранить вместе с другими событиями: обрыв соединения не должен ее прерывать.
+					r.buffer.Add(context.WithoutCancel(ctx), event)
+					res.Accepted++
+				case errors.Is(err, ingest.ErrMalformed):
+					res.Rejected++
+					res.Errors = append(res.Errors, err.Error())
+				default:
+					slog.Error("failed to process otlp log record", "stream", r.stream, "error", err)
+					failed++
+					res.Rejected++
+					res.Errors = append(res.Errors, err.Error())
+				}
+			}
+		}
+	}
+	if failed > 0 && res.Accepted == 0 {
+		return res, fmt.Errorf("%w: %s", ErrUnavailable, res.Errors[0])
+	}
+	return res, nil
+}
+
+// Response переводит итог в ответ OTLP с частичным успехом.
+func (res Result) Response() *collogspb.ExportLogsServiceResponse {
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if res.Rejected > 0 {
+		resp.PartialSuccess = &collogspb.ExportLogsPartialSuccess{
+			RejectedLogRecords: int64(res.Rejected),
+			ErrorMessage:       strings.Join(res.Errors, "; "),
+		}
+	}
+	return resp
+}
+
+// Export реализует LogsService OTLP/gRPC. Требует роль events:write.
+func (r *Receiver) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	if !auth.FromContext(ctx).HasRole(models.ScopeEventsWrite) {
+		return nil, status.Error(codes.PermissionDenied, "forbidden: role "+models.ScopeEventsWrite+" is required")
+	}
+	var header string
+	if values := metadata.ValueFromIncomingContext(ctx, tenant.Header); len(values) > 0 {
+		header = values[0]
+	}
+	res, err := r.Consume(ctx, req, header)
+	if errors.Is(err, ErrUnavailable) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return res.Response(), nil
+}
+
+// UnmarshalJSON разбирает запрос OTLP/JSON. В отличие от обычного JSON для protobuf,
+// traceId и spanId в OTLP/JSON передаются в hex, а не в base64.
+func UnmarshalJSON(data []byte, req *collogspb.ExportLogsServiceRequest) error {
+	var raw map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	for _, resource := range list(raw, "resourceLogs", "resource_logs") {
+		for _, scope := range list(resource, "scopeLogs", "scope_logs") {
+			for _, record := range list(scope, "logRecords", "log_records") {
+				for _, key := range []string{"traceId", "trace_id", "spanId", "span_id"} {
+					if id, ok := record[key].(string); ok && id != "" {
+						b, err := hex.DecodeString(id)
+						if err != nil {
+							return fmt.Errorf("invalid %s %q: %w", key, id, err)
+						}
+						record[key] = base64.StdEncoding.EncodeToString(b)
+					}
+				}
+			}
+		}
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, req)
+}
+
+// list возвращает массив объектов по первому найденному из keys ключу.
+func list(obj map[string]any, keys ...string) []map[string]any {
+	for _, key := range keys {
+		items, ok := obj[key].([]any)
+		if !ok {
+			continue
+		}
+		objs := make([]map[string]any, 0, len(items))
+		for _, item := range items {
+			if m, ok := item.(map[string]any); ok {
+				objs = append(objs, m)
+			}
+		}
+		return objs
+	}
+	return nil
+}
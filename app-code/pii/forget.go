@@ -0,0 +1,128 @@
+package pii
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"witness/legalhold"
+	"witness/models"
+	"witness/storage"
+
+	"github.com/google/uuid"
+)
+
+// EventForgotten - тип события аудита об удалении ключа актора.
+const EventForgotten = "ACTOR_FORGOTTEN"
+
+var (
+	ErrDisabled       = errors.New("pii encryption is not enabled")
+	ErrInvalidRequest = errors.New("invalid forget request")
+	ErrUnderLegalHold = errors.New("actor is under an active legal hold")
+)
+
+// Shredder выполняет запросы на удаление персональных данных актора.
+type Shredder struct {
+	keys     KeyStore
+	holds    storage.LegalHoldStore
+	events   storage.EventStore
+	recorder legalhold.Recorder
+	now      func() time.Time
+}
+
+// NewShredder создает обработчик запросов на забвение. keys == nil означает, что шифрование выключено.
+// По events проверяются удержания, через recorder записываются события о забвении.
+func NewShredder(keys KeyStore, holds storage.LegalHoldStore, events storage.EventStore, recorder legalhold.Recorder) *Shredder {
+	return &Shredder{keys: keys, holds: holds, events: events, recorder: recorder, now: time.Now}
+}
+
+// Forget удаляет ключ данных актора, после чего его PII нельзя расшифровать.
+// Актор не может быть забыт, пока под каким-либо активным удержанием есть его события:
+// удержание по actor_id, по сущности или по интервалу времени, которому они соответствуют.
+func (s *Shredder) Forget(ctx context.Context, actorID, requestedBy string) (*models.ForgetActorResult, error) {
+	if s.keys == nil {
+		return nil, ErrDisabled
+	}
+	actorID, requestedBy = strings.TrimSpace(actorID), strings.TrimSpace(requestedBy)
+	if actorID == "" || requestedBy == "" {
+		return nil, fmt.Errorf("%w: actorId and requestedBy are required", ErrInvalidRequest)
+	}
+
+	if err := s.checkHolds(ctx, actorID); err != nil {
+		return nil, err
+	}
+
+	if err := s.keys.Delete(actorID); err != nil {
+		return nil, err
+	}
+
+	result := &models.ForgetActorResult{
+		ActorID:     actorID,
+		ForgottenAt: s.now().UTC(),
+	}
+	s.record(ctx, requestedBy, result)
+	return result, nil
+}
+
+// checkHolds возвращает ErrUnderLegalHold, если события актора попадают под активное удержание.
+func (s *Shredder) checkHolds(ctx context.Context, actorID string) error {
+	holds, err := s.holds.ListLegalHolds(ctx, true)
+	if err != nil {
+		return err
+	}
+	for _, hold := range holds {
+		if hold.ActorID != nil {
+			if *hold.ActorID == actorID {
+				return fmt.Errorf("%w: %s", ErrUnderLegalHold, hold.ID)
+			}
+			// Удержание другого актора событий этого актора не касается.
+			continue
+		}
+
+		// Удержание по сущности или интервалу: ищем хотя бы одно событие актора под ним.
+		_, total, err := s.events.Search(ctx, storage.Query{
+			Filter: &models.AuditEventFilter{
+				ActorID:  &actorID,
+				EntityID: hold.EntityID,
+				From:     hold.From,
+				To:       hold.To,
+			},
+			Limit: 1,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to check legal hold %s: %w", hold.ID, err)
+		}
+		if total > 0 {
+			return fmt.Errorf("%w: %s", ErrUnderLegalHold, hold.ID)
+		}
+	}
+	return nil
+}
+
+// record сохраняет событие аудита о забвении от имени субъекта запроса; requestedBy
+// указывает вызывающий, поэтому он остается только в details.
+// Ошибка записи не отменяет удаление ключа.
+func (s *Shredder) record(ctx context.Context, requestedBy string, result *models.ForgetActorResult) {
+	event := &models.AuditEvent{
+		EventID:   uuid.NewString(),
+		Timestamp: result.ForgottenAt,
+		Status:    "SUCCESS",
+		EventType: EventForgotten,
+		Actor:     legalhold.CallerActor(ctx),
+		Entity: models.Entity{
+			ID:   result.ActorID,
+			Type: "ACTOR",
+		},
+		Context: models.Context{
+			SourceService: legalhold.SourceService,
+		},
+		Security: &models.Security{AccessLevel: "HIGH"},
+		Details:  map[string]any{"requested_by": requestedBy},
+	}
+
+	if err := s.recorder.Record(ctx, event); err != nil {
+		slog.Error("failed to record forget audit event", "actor_id", result.ActorID, "error", err)
+	}
+}
@@ -0,0 +1,103 @@
+package pii
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// keySize - размер ключа данных актора (AES-256).
+const keySize = 32
+
+// ErrNoKey возвращается, когда ключа актора нет: он не создавался или удален.
+var ErrNoKey = errors.New("no data key for actor")
+
+// KeyStore хранит ключи данных акторов.
+type KeyStore interface {
+	// Key возвращает ключ актора или ErrNoKey.
+	Key(actorID string) ([]byte, error)
+	// GetOrCreate возвращает ключ актора, создавая его при первом обращении.
+	GetOrCreate(actorID string) ([]byte, error)
+	// Delete безвозвратно удаляет ключ актора. Отсутствие ключа ошибкой не считается.
+	Delete(actorID string) error
+}
+
+// FileKeyStore хранит каждый ключ в отдельном файле каталога. Имя файла - SHA-256
+// идентификатора актора, чтобы идентификаторы не попадали в файловую систему.
+// Ключи не кэшируются: удаление файла сразу действует на все процессы с общим каталогом.
+type FileKeyStore struct {
+	dir string
+}
+
+// NewFileKeyStore создает хранилище ключей в каталоге dir.
+func NewFileKeyStore(dir string) (*FileKeyStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create key store: %w", err)
+	}
+	return &FileKeyStore{dir: dir}, nil
+}
+
+func (s *FileKeyStore) path(actorID string) string {
+	sum := sha256.Sum256([]byte(actorID))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".key")
+}
+
+func (s *FileKeyStore) Key(actorID string) ([]byte, error) {
+	key, err := os.ReadFile(s.path(actorID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNoKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data key: %w", err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("data key file %s is corrupted", s.path(actorID))
+	}
+	return key, nil
+}
+
+func (s *FileKeyStore) GetOrCreate(actorID string) ([]byte, error) {
+	key, err := s.Key(actorID)
+	if !errors.Is(err, ErrNoKey) {
+		return key, err
+	}
+
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	// Ключ пишется во временный файл и связывается с постоянным именем через link:
+	// если другой процесс успел создать ключ раньше, используется его ключ.
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data key: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(key); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write data key: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write data key: %w", err)
+	}
+	if err := os.Link(tmp.Name(), s.path(actorID)); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return s.Key(actorID)
+		}
+		return nil, fmt.Errorf("failed to store data key: %w", err)
+	}
+	return key, nil
+}
+
+func (s *FileKeyStore) Delete(actorID string) error {
+	err := os.Remove(s.path(actorID))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete data key: %w", err)
+	}
+	return nil
+}
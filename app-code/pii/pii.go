@@ -0,0 +1,206 @@
+// Package pii шифрует персональные данные акторов ключами, отдельными для каждого
+// актора (crypto-shredding). Удаление ключа делает данные актора нечитаемыми везде,
+// где они сохранились (индекс, SQL-хранилища, архив), не трогая остальные поля события
+// и не нарушая цепочку хешей: хеш считается по уже зашифрованному событию.
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"witness/models"
+)
+
+// Пути полей актора в событии. Ключи details задаются как "details.<ключ>".
+const (
+	FieldActorName = "actor.name"
+	FieldActorIP   = "actor.ip_address"
+	detailsPrefix  = "details."
+)
+
+// ErrNameFilter - фильтр по имени при включенном шифровании. Имена акторов хранятся только
+// зашифрованными, поэтому фильтр молча не находил бы их; вместо неполного ответа он отклоняется.
+var ErrNameFilter = errors.New("name filter is not available while PII encryption is enabled")
+
+// Encryptor шифрует и расшифровывает PII событий. Без хранилища ключей ничего не делает.
+type Encryptor struct {
+	keys       KeyStore
+	detailKeys []string
+}
+
+// NewEncryptor создает шифратор. keys == nil отключает шифрование; detailKeys -
+// ключи верхнего уровня details, которые шифруются наравне с именем и IP актора.
+func NewEncryptor(keys KeyStore, detailKeys []string) *Encryptor {
+	return &Encryptor{keys: keys, detailKeys: detailKeys}
+}
+
+// Enabled сообщает, включено ли шифрование.
+func (e *Encryptor) Enabled() bool {
+	return e.keys != nil
+}
+
+// CheckFilter возвращает ErrNameFilter, если шифрование включено, а filter ищет по имени.
+func (e *Encryptor) CheckFilter(filter *models.AuditEventFilter) error {
+	if e.keys != nil && filter != nil && filter.Name != nil {
+		return ErrNameFilter
+	}
+	return nil
+}
+
+// Encrypt переносит PII события в event.PII в зашифрованном виде и очищает исходные поля.
+// События без actor.id не шифруются: ключ привязан к актору. event.PII, присланное
+// продюсером, отбрасывается: его значения не зашифрованы ключом актора.
+func (e *Encryptor) Encrypt(event *models.AuditEvent) error {
+	event.PII = nil
+	if e.keys == nil || event.Actor.ID == "" {
+		return nil
+	}
+
+	plain := make(map[string]string)
+	if event.Actor.Name != "" {
+		plain[FieldActorName] = event.Actor.Name
+	}
+	if event.Actor.IPAddress != "" {
+		plain[FieldActorIP] = event.Actor.IPAddress
+	}
+	var details map[string]any
+	for _, k := range e.detailKeys {
+		v, ok := event.Details[k]
+		if !ok {
+			continue
+		}
+		// Значения details шифруются как JSON, чтобы сохранить их тип.
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to marshal details.%s of %s: %w", k, event.EventID, err)
+		}
+		plain[detailsPrefix+k] = string(data)
+		if details == nil {
+			details = make(map[string]any, len(event.Details))
+			for dk, dv := range event.Details {
+				details[dk] = dv
+			}
+		}
+		delete(details, k)
+	}
+	if len(plain) == 0 {
+		return nil
+	}
+
+	key, err := e.keys.GetOrCreate(event.Actor.ID)
+	if err != nil {
+		return err
+	}
+	encrypted := make(map[string]string, len(plain))
+	for field, value := range plain {
+		ct, err := seal(key, []byte(value), aad(event.EventID, field))
+		if err != nil {
+			return err
+		}
+		encrypted[field] = ct
+	}
+
+	event.PII = encrypted
+	event.Actor.Name = ""
+	event.Actor.IPAddress = ""
+	if details != nil {
+		event.Details = details
+	}
+	return nil
+}
+
+// Decrypt возвращает поля PII на место. Если ключ актора удален, поля остаются пустыми.
+// Поля события перезаписываются, details копируется.
+func (e *Encryptor) Decrypt(event *models.AuditEvent) error {
+	if e.keys == nil || len(event.PII) == 0 {
+		return nil
+	}
+
+	key, err := e.keys.Key(event.Actor.ID)
+	if errors.Is(err, ErrNoKey) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	details := make(map[string]any, len(event.Details))
+	for k, v := range event.Details {
+		details[k] = v
+	}
+	for field, ct := range event.PII {
+		value, err := open(key, ct, aad(event.EventID, field))
+		if err != nil {
+			slog.Warn("failed to decrypt pii field", "event_id", event.EventID, "field", field, "error", err)
+			continue
+		}
+		switch {
+		case field == FieldActorName:
+			event.Actor.Name = value
+		case field == FieldActorIP:
+			event.Actor.IPAddress = value
+		case strings.HasPrefix(field, detailsPrefix):
+			var v any
+			if err := json.Unmarshal([]byte(value), &v); err != nil {
+				slog.Warn("failed to decode pii field", "event_id", event.EventID, "field", field, "error", err)
+				continue
+			}
+			details[strings.TrimPrefix(field, detailsPrefix)] = v
+		}
+	}
+	if len(details) > 0 {
+		event.Details = details
+	}
+	return nil
+}
+
+// aad привязывает шифротекст к событию и полю: его нельзя перенести в другое место.
+func aad(eventID, field string) []byte {
+	return []byte(eventID + "\x00" + field)
+}
+
+// seal шифрует AES-256-GCM и возвращает base64(nonce || ciphertext).
+func seal(key, plaintext, additional []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, additional)), nil
+}
+
+func open(key []byte, ciphertext string, additional []byte) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("invalid ciphertext encoding: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("ciphertext is too short")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], additional)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
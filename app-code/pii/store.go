@@ -0,0 +1,66 @@
+package pii
+
+import (
+	"context"
+	"witness/models"
+	"witness/storage"
+)
+
+// Store - хранилище событий для API: события отдаются с расшифрованными PII, а фильтры
+// по зашифрованным полям отклоняются (см. Encryptor.CheckFilter). Запись проходит
+// в исходное хранилище без изменений.
+type Store struct {
+	storage.EventStore
+	enc *Encryptor
+}
+
+// NewStore оборачивает хранилище событий.
+func NewStore(store storage.EventStore, enc *Encryptor) *Store {
+	return &Store{EventStore: store, enc: enc}
+}
+
+func (s *Store) Search(ctx context.Context, q storage.Query) ([]*models.AuditEvent, int64, error) {
+	if err := s.enc.CheckFilter(q.Filter); err != nil {
+		return nil, 0, err
+	}
+	events, total, err := s.EventStore.Search(ctx, q)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, event := range events {
+		if err := s.enc.Decrypt(event); err != nil {
+			return nil, 0, err
+		}
+	}
+	return events, total, nil
+}
+
+func (s *Store) Aggregate(ctx context.Context, filter *models.AuditEventFilter, field string, size int) ([]*models.FacetBucket, error) {
+	if err := s.enc.CheckFilter(filter); err != nil {
+		return nil, err
+	}
+	return s.EventStore.Aggregate(ctx, filter, field, size)
+}
+
+func (s *Store) Scan(ctx context.Context, filter *models.AuditEventFilter, fn func(*models.AuditEvent) error) error {
+	if err := s.enc.CheckFilter(filter); err != nil {
+		return err
+	}
+	return s.EventStore.Scan(ctx, filter, func(event *models.AuditEvent) error {
+		if err := s.enc.Decrypt(event); err != nil {
+			return err
+		}
+		return fn(event)
+	})
+}
+
+func (s *Store) Get(ctx context.Context, eventID string) (*models.AuditEvent, error) {
+	event, err := s.EventStore.Get(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.enc.Decrypt(event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
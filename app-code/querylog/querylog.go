@@ -0,0 +1,359 @@
+// Package querylog записывает событие AUDIT_LOG_QUERY о каждой операции GraphQL:
+// кто обращался к журналу аудита, что запрашивал, сколько получил и как долго ждал.
+// Другие обращения (выгрузки) записываются так же через Recorder.Record.
+//
+// События пишутся пачками через storage.EventStore в отдельное хранилище (индекс
+// audit-queries, схему или файл - см. README), чтобы не смешиваться с событиями
+// продюсеров и не попадать в их цепочки хешей, и сами доступны для поиска.
+package querylog
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"witness/auth"
+	"witness/legalhold"
+	"witness/models"
+	"witness/pii"
+	"witness/redaction"
+	"witness/storage"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Типы событий об обращениях к журналу аудита.
+const (
+	// EventType - операция GraphQL.
+	EventType = "AUDIT_LOG_QUERY"
+	// ExportEventType - выгрузка событий в файл (GET /export).
+	ExportEventType = "AUDIT_LOG_EXPORT"
+)
+
+// maxPending - сколько событий держим в памяти, пока хранилище недоступно.
+// Сверх этого новые события отбрасываются с ошибкой в логе, чтобы не исчерпать память.
+const maxPending = 10000
+
+// Recorder копит события о запросах и пачками сохраняет их в хранилище.
+// Реализует расширение сервера gqlgen (graphql.HandlerExtension).
+type Recorder struct {
+	store    storage.EventStore
+	redactor *redaction.Redactor
+	pii      *pii.Encryptor
+	now      func() time.Time
+
+	mu            sync.Mutex
+	buffer        []*models.AuditEvent
+	maxBufferSize int
+	flushInterval time.Duration
+	full          chan struct{}
+}
+
+var (
+	_ graphql.HandlerExtension     = (*Recorder)(nil)
+	_ graphql.OperationInterceptor = (*Recorder)(nil)
+	_ graphql.ResponseInterceptor  = (*Recorder)(nil)
+)
+
+// NewRecorder создает журнал запросов. Переменные операций проходят через те же
+// правила маскирования, что и details при приеме (без правил сохраняются только их
+// имена и типы), а IP вызывающего шифруется как PII.
+func NewRecorder(store storage.EventStore, redactor *redaction.Redactor, encryptor *pii.Encryptor) *Recorder {
+	return &Recorder{
+		store:         store,
+		redactor:      redactor,
+		pii:           encryptor,
+		now:           time.Now,
+		buffer:        make([]*models.AuditEvent, 0, 100),
+		maxBufferSize: 100,
+		flushInterval: 5 * time.Second,
+		full:          make(chan struct{}, 1),
+	}
+}
+
+// ExtensionName реализует graphql.HandlerExtension.
+func (r *Recorder) ExtensionName() string {
+	return "QueryLog"
+}
+
+// Validate реализует graphql.HandlerExtension.
+func (r *Recorder) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptOperation записывает подписку одним событием при ее завершении:
+// result_count - число отправленных сообщений, latency_ms - длительность подписки.
+func (r *Recorder) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	oc := graphql.GetOperationContext(ctx)
+	if oc.Operation == nil || oc.Operation.Operation != ast.Subscription {
+		return next(ctx)
+	}
+	start := time.Now()
+	responses := next(ctx)
+	var delivered int64
+	var errs int
+	return func(ctx context.Context) *graphql.Response {
+		resp := responses(ctx)
+		if resp == nil {
+			r.add(r.event(ctx, oc, errs, delivered, time.Since(start)))
+			return nil
+		}
+		if len(resp.Errors) > 0 {
+			errs += len(resp.Errors)
+		} else {
+			delivered++
+		}
+		return resp
+	}
+}
+
+// InterceptResponse выполняет запрос или мутацию и записывает событие о ней.
+func (r *Recorder) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+	oc := graphql.GetOperationContext(ctx)
+	if oc.Operation != nil && oc.Operation.Operation == ast.Subscription {
+		return next(ctx)
+	}
+	stats := &stats{}
+	start := time.Now()
+	resp := next(context.WithValue(ctx, statsKey{}, stats))
+	if resp == nil {
+		return resp
+	}
+	r.add(r.event(ctx, oc, len(resp.Errors), stats.results.Load(), time.Since(start)))
+	return resp
+}
+
+// event строит событие о выполненной операции. errs - число ошибок в ответе.
+func (r *Recorder) event(ctx context.Context, oc *graphql.OperationContext, errs int, results int64, latency time.Duration) *models.AuditEvent {
+	operation := oc.OperationName
+	var opType string
+	var fields []string
+	if oc.Operation != nil {
+		opType = string(oc.Operation.Operation)
+		for _, sel := range oc.Operation.SelectionSet {
+			if f, ok := sel.(*ast.Field); ok && !slices.Contains(fields, f.Name) {
+				fields = append(fields, f.Name)
+			}
+		}
+	}
+	if operation == "" {
+		operation = strings.Join(fields, ",")
+	}
+
+	details := map[string]any{
+		"operation":      oc.OperationName,
+		"operation_type": opType,
+		"fields":         fields,
+		"result_count":   results,
+		"latency_ms":     latency.Milliseconds(),
+	}
+	if len(oc.Variables) > 0 {
+		// Без правил маскирования значения переменных не сохраняются: в них бывают
+		// идентификаторы и PII, которые иначе легли бы в журнал открытым текстом.
+		if r.redactor.Enabled() {
+			details["variables"] = oc.Variables
+		} else {
+			details["variable_types"] = variableTypes(oc.Variables)
+		}
+	}
+	if errs > 0 {
+		details["errors"] = errs
+	}
+	entity := models.Entity{ID: operation, Type: "GRAPHQL_OPERATION", Name: operation}
+	return r.newEvent(ctx, EventType, entity, errs == 0, details)
+}
+
+// variableTypes возвращает JSON-типы переменных операции по их именам.
+func variableTypes(vars map[string]any) map[string]string {
+	types := make(map[string]string, len(vars))
+	for name, v := range vars {
+		switch v.(type) {
+		case nil:
+			types[name] = "null"
+		case string:
+			types[name] = "string"
+		case bool:
+			types[name] = "boolean"
+		case map[string]any:
+			types[name] = "object"
+		case []any:
+			types[name] = "array"
+		default:
+			types[name] = "number"
+		}
+	}
+	return types
+}
+
+// Record записывает событие eventType об обращении к журналу аудита в обход GraphQL
+// (например, о выгрузке). Актор, IP и X-Request-ID берутся из ctx, как у операций.
+// У nil Recorder (журнал запросов отключен) ничего не делает.
+func (r *Recorder) Record(ctx context.Context, eventType string, entity models.Entity, success bool, details map[string]any) {
+	if r == nil {
+		return
+	}
+	r.add(r.newEvent(ctx, eventType, entity, success, details))
+}
+
+// newEvent строит событие об обращении субъекта запроса из ctx к объекту entity.
+func (r *Recorder) newEvent(ctx context.Context, eventType string, entity models.Entity, success bool, details map[string]any) *models.AuditEvent {
+	subject, name, actorType, tenant := "anonymous", "", "ANONYMOUS", ""
+	if p := auth.FromContext(ctx); p != nil {
+		subject, name, tenant = p.Subject, p.Name, p.Tenant
+		switch {
+		case strings.HasPrefix(subject, "apikey:"):
+			actorType = "SERVICE"
+		case subject != "anonymous":
+			actorType = "USER"
+		}
+	}
+
+	if name == "" {
+		name = subject
+	}
+	status := "SUCCESS"
+	if !success {
+		status = "FAILURE"
+	}
+
+	client, _ := ctx.Value(clientKey{}).(client)
+	return &models.AuditEvent{
+		EventID:   uuid.NewString(),
+		Timestamp: r.now().UTC(),
+		Status:    status,
+		EventType: eventType,
+		Actor: models.Actor{
+			ID:        subject,
+			Type:      actorType,
+			Name:      name,
+			IPAddress: client.ip,
+		},
+		Entity: entity,
+		Context: models.Context{
+			SourceService: legalhold.SourceService,
+			RequestID:     client.requestID,
+		},
+		Security: &models.Security{AccessLevel: "HIGH"},
+		Details:  details,
+		Tenant:   tenant,
+	}
+}
+
+// add маскирует и шифрует событие и ставит его в очередь на запись.
+func (r *Recorder) add(event *models.AuditEvent) {
+	r.redactor.Redact(event)
+	if err := r.pii.Encrypt(event); err != nil {
+		slog.Error("failed to encrypt pii of query log event", "event_id", event.EventID, "error", err)
+		return
+	}
+
+	r.mu.Lock()
+	if len(r.buffer) >= maxPending {
+		r.mu.Unlock()
+		slog.Error("query log buffer is full, dropping event", "event_id", event.EventID, "actor_id", event.Actor.ID)
+		return
+	}
+	r.buffer = append(r.buffer, event)
+	size := len(r.buffer)
+	r.mu.Unlock()
+
+	if size >= r.maxBufferSize {
+		select {
+		case r.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run периодически сохраняет накопленные события до отмены ctx.
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.flush(context.Background()) // Последняя отправка после отмены контекста
+			return
+		case <-ticker.C:
+			r.flush(ctx)
+		case <-r.full:
+			r.flush(ctx)
+		}
+	}
+}
+
+// flush сохраняет накопленные события. При ошибке события возвращаются в буфер
+// и будут отправлены при следующей попытке.
+func (r *Recorder) flush(ctx context.Context) {
+	r.mu.Lock()
+	if len(r.buffer) == 0 {
+		r.mu.Unlock()
+		return
+	}
+	events := r.buffer
+	r.buffer = make([]*models.AuditEvent, 0, r.maxBufferSize)
+	r.mu.Unlock()
+
+	if err := r.store.IndexBatch(ctx, events); err != nil {
+		slog.Error("failed to flush query log", "count", len(events), "error", err)
+		r.mu.Lock()
+		r.buffer = append(events, r.buffer...)
+		if len(r.buffer) > maxPending {
+			slog.Error("query log buffer is full, dropping events", "count", len(r.buffer)-maxPending)
+			r.buffer = r.buffer[len(r.buffer)-maxPending:]
+		}
+		r.mu.Unlock()
+		return
+	}
+	slog.Info("flushed query log", "count", len(events))
+}
+
+// stats - сведения об операции, которые собирают резолверы.
+type stats struct {
+	results atomic.Int64
+}
+
+type statsKey struct{}
+
+// AddResults добавляет n к числу результатов операции (событий, корзин фасетов),
+// которое попадет в result_count. Вне операции GraphQL ничего не делает.
+func AddResults(ctx context.Context, n int) {
+	if s, ok := ctx.Value(statsKey{}).(*stats); ok {
+		s.results.Add(int64(n))
+	}
+}
+
+// client - сведения о вызывающем из HTTP-запроса.
+type client struct {
+	ip        string
+	requestID string
+}
+
+type clientKey struct{}
+
+// Middleware сохраняет в контексте запроса IP вызывающего и X-Request-ID для событий журнала.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			c.SetRequest(req.WithContext(WithClient(req.Context(), c.RealIP(), req.Header.Get(echo.HeaderXRequestID))))
+			return next(c)
+		}
+	}
+}
+
+// WithClient сохраняет в контексте IP вызывающего и идентификатор запроса для событий
+// журнала. Нужен транспортам помимо Echo (gRPC); для HTTP это делает Middleware.
+func WithClient(ctx context.Context, ip, requestID string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client{ip: ip, requestID: requestID})
+}
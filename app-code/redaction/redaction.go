@@ -0,0 +1,302 @@
+// Package redaction маскирует секреты и персональные данные в details до сохранения события.
+//
+// Правила задаются JSON-файлом:
+//
+//	{"rules": [
+//	  {"name": "secrets", "keys": ["(?i)pass(word)?", "(?i)token"], "action": "mask"},
+//	  {"name": "cards", "values": ["@pan", "@jwt"], "action": "mask"},
+//	  {"name": "emails", "values": ["@email"], "action": "hash", "services": ["billing-service"]}
+//	]}
+//
+// keys - регулярные выражения имен ключей details на любой глубине: значение такого ключа
+// заменяется целиком. values - регулярные выражения для строковых значений: заменяются
+// только совпадения. Встроенные выражения: @pan (номер карты с проверкой Луна), @jwt, @email.
+// services ограничивает правило событиями указанных source_service.
+package redaction
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"witness/models"
+)
+
+// Действия над найденными значениями.
+const (
+	ActionMask = "mask"
+	ActionHash = "hash"
+)
+
+// Mask - значение, которым заменяются маскированные данные.
+const Mask = "[REDACTED]"
+
+// Rule - правило в файле конфигурации.
+type Rule struct {
+	Name     string   `json:"name"`
+	Keys     []string `json:"keys"`
+	Values   []string `json:"values"`
+	Action   string   `json:"action"`
+	Services []string `json:"services"`
+}
+
+type rule struct {
+	name     string
+	keys     []*regexp.Regexp
+	values   []*pattern
+	hash     bool
+	services map[string]bool
+}
+
+// pattern - выражение для значений с необязательной дополнительной проверкой совпадения.
+type pattern struct {
+	re    *regexp.Regexp
+	check func(match string) bool
+}
+
+var builtin = map[string]*pattern{
+	"@pan":   {re: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), check: luhn},
+	"@jwt":   {re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)},
+	"@email": {re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+}
+
+// Redactor применяет правила к событиям. Без правил ничего не делает.
+type Redactor struct {
+	rules   []*rule
+	hashKey []byte
+}
+
+// Load читает правила из файла. Пустой путь дает Redactor без правил.
+// hashKey - ключ HMAC для действия hash; без него используется обычный SHA-256.
+func Load(path, hashKey string) (*Redactor, error) {
+	r := &Redactor{hashKey: []byte(hashKey)}
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction rules: %w", err)
+	}
+	var cfg struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction rules: %w", err)
+	}
+	for i, rc := range cfg.Rules {
+		compiled, err := compile(rc)
+		if err != nil {
+			return nil, fmt.Errorf("redaction rule %d (%s): %w", i, rc.Name, err)
+		}
+		r.rules = append(r.rules, compiled)
+	}
+	return r, nil
+}
+
+func compile(rc Rule) (*rule, error) {
+	c := &rule{name: rc.Name}
+	switch rc.Action {
+	case "", ActionMask:
+	case ActionHash:
+		c.hash = true
+	default:
+		return nil, fmt.Errorf("unknown action %q, expected mask or hash", rc.Action)
+	}
+	for _, k := range rc.Keys {
+		re, err := regexp.Compile(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key pattern: %w", err)
+		}
+		c.keys = append(c.keys, re)
+	}
+	for _, v := range rc.Values {
+		if p, ok := builtin[v]; ok {
+			c.values = append(c.values, p)
+			continue
+		}
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value pattern: %w", err)
+		}
+		c.values = append(c.values, &pattern{re: re})
+	}
+	if len(c.keys) == 0 && len(c.values) == 0 {
+		return nil, fmt.Errorf("rule has neither keys nor values")
+	}
+	if len(rc.Services) > 0 {
+		c.services = make(map[string]bool, len(rc.Services))
+		for _, s := range rc.Services {
+			c.services[s] = true
+		}
+	}
+	return c, nil
+}
+
+// Enabled сообщает, заданы ли правила.
+func (r *Redactor) Enabled() bool {
+	return len(r.rules) > 0
+}
+
+// Redact заменяет найденные значения в details и записывает их количество в event.Redactions.
+func (r *Redactor) Redact(event *models.AuditEvent) {
+	event.Redactions = 0
+	if len(r.rules) == 0 || event.Details == nil {
+		return
+	}
+
+	var rules []*rule
+	for _, rl := range r.rules {
+		if rl.services == nil || rl.services[event.Context.SourceService] {
+			rules = append(rules, rl)
+		}
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	count := 0
+	event.Details = r.redactMap(event.Details, rules, &count)
+	event.Redactions = count
+}
+
+// redactMap возвращает копию map с замененными значениями.
+func (r *Redactor) redactMap(m map[string]any, rules []*rule, count *int) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		if rl := matchKey(rules, k); rl != nil {
+			out[k] = r.replace(rl, v)
+			*count++
+			continue
+		}
+		out[k] = r.redactValue(v, rules, count)
+	}
+	return out
+}
+
+func (r *Redactor) redactValue(v any, rules []*rule, count *int) any {
+	switch val := v.(type) {
+	case map[string]any:
+		return r.redactMap(val, rules, count)
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = r.redactValue(item, rules, count)
+		}
+		return out
+	case string:
+		for _, rl := range rules {
+			for _, p := range rl.values {
+				val = p.re.ReplaceAllStringFunc(val, func(match string) string {
+					if p.check != nil && !p.check(match) {
+						return match
+					}
+					*count++
+					return r.replace(rl, match).(string)
+				})
+			}
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+func matchKey(rules []*rule, key string) *rule {
+	for _, rl := range rules {
+		for _, re := range rl.keys {
+			if re.MatchString(key) {
+				return rl
+			}
+		}
+	}
+	return nil
+}
+
+// replace возвращает замену значения по действию правила. Хеш позволяет
+// сопоставлять одинаковые значения между событиями, не раскрывая их.
+func (r *Redactor) replace(rl *rule, v any) any {
+	if !rl.hash {
+		return Mask
+	}
+	s, ok := v.(string)
+	if !ok {
+		data, _ := json.Marshal(v)
+		s = string(data)
+	}
+	var sum []byte
+	if len(r.hashKey) > 0 {
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(s))
+		sum = mac.Sum(nil)
+	} else {
+		h := sha256.Sum256([]byte(s))
+		sum = h[:]
+	}
+	return "[HASH:" + hex.EncodeToString(sum[:16]) + "]"
+}
+
+// MaskKeys заменяет на Mask значения ключей keys на любой глубине разобранного JSON
+// (map[string]any и []any). Значение меняется на месте и возвращается.
+func MaskKeys(v any, keys []string) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			if slices.Contains(keys, k) {
+				val[k] = Mask
+				continue
+			}
+			val[k] = MaskKeys(item, keys)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = MaskKeys(item, keys)
+		}
+	}
+	return v
+}
+
+// MaskEvent возвращает копию события для субъекта без права видеть PII: IP актора
+// и значения ключей details keys заменены на Mask. Исходное событие не меняется.
+func MaskEvent(event *models.AuditEvent, keys []string) *models.AuditEvent {
+	e := *event
+	if e.Actor.IPAddress != "" {
+		e.Actor.IPAddress = Mask
+	}
+	if len(e.Details) > 0 {
+		// Копия через JSON: details исходного события могут быть общими (live.Broker).
+		var details map[string]any
+		data, _ := json.Marshal(e.Details)
+		if err := json.Unmarshal(data, &details); err != nil {
+			e.Details = nil
+			return &e
+		}
+		e.Details = MaskKeys(details, keys).(map[string]any)
+	}
+	return &e
+}
+
+// luhn проверяет контрольную сумму номера карты, отбрасывая пробелы и дефисы.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,68 @@
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"witness/storage"
+)
+
+// Worker периодически удаляет события старше заданного срока хранения.
+// События, попадающие под активные удержания (legal hold), не удаляются.
+type Worker struct {
+	pruner   storage.Pruner
+	holds    storage.LegalHoldStore
+	maxAge   time.Duration
+	interval time.Duration
+}
+
+// NewWorker создает задачу хранения. maxAge <= 0 отключает удаление.
+func NewWorker(pruner storage.Pruner, holds storage.LegalHoldStore, maxAge, interval time.Duration) *Worker {
+	return &Worker{
+		pruner:   pruner,
+		holds:    holds,
+		maxAge:   maxAge,
+		interval: interval,
+	}
+}
+
+// Run выполняет очистку по таймеру до отмены контекста.
+func (w *Worker) Run(ctx context.Context) {
+	if w.maxAge <= 0 {
+		slog.Info("retention disabled")
+		return
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.RunOnce(ctx); err != nil {
+			slog.Error("retention run failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce удаляет устаревшие события один раз и возвращает их количество.
+func (w *Worker) RunOnce(ctx context.Context) (int64, error) {
+	// Без актуального списка удержаний удалять ничего нельзя.
+	holds, err := w.holds.ListLegalHolds(ctx, true)
+	if err != nil {
+		return 0, err
+	}
+
+	before := time.Now().Add(-w.maxAge)
+	deleted, err := w.pruner.DeleteEventsBefore(ctx, before, holds)
+	if err != nil {
+		return 0, err
+	}
+
+	slog.Info("retention run completed", "before", before, "deleted", deleted, "active_holds", len(holds))
+	return deleted, nil
+}
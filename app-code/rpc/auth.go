@@ -0,0 +1,102 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"witness/auth"
+	"witness/querylog"
+	"witness/rpc/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// NewServer создает сервер gRPC с сервисом service. Вызовы аутентифицируются
+// так же, как запросы к HTTP API: bearer-токен из метаданных authorization проверяет
+// authenticator, а если он nil (аутентификация выключена), субъектом становится anonymous.
+func NewServer(service *Service, authenticator auth.Authenticator, anonymous *auth.Principal, opts ...grpc.ServerOption) *grpc.Server {
+	a := &authInterceptor{authenticator: authenticator, anonymous: anonymous}
+	opts = append(opts, grpc.ChainUnaryInterceptor(a.unary), grpc.ChainStreamInterceptor(a.stream))
+	srv := grpc.NewServer(opts...)
+	pb.RegisterWitnessServiceServer(srv, service)
+	return srv
+}
+
+type authInterceptor struct {
+	authenticator auth.Authenticator
+	anonymous     *auth.Principal
+}
+
+func (a *authInterceptor) unary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authInterceptor) stream(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate возвращает контекст с субъектом вызова и сведениями о клиенте для журнала запросов.
+func (a *authInterceptor) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = querylog.WithClient(ctx, clientIP(ctx), first(md.Get("x-request-id")))
+
+	if a.authenticator == nil {
+		return auth.WithPrincipal(ctx, a.anonymous), nil
+	}
+	token, ok := auth.BearerToken(first(md.Get("authorization")))
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	principal, err := a.authenticator.Authenticate(ctx, token)
+	if errors.Is(err, auth.ErrUnauthenticated) {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	if err != nil {
+		slog.Error("authentication failed", "error", err)
+		return nil, status.Error(codes.Unavailable, "authentication is temporarily unavailable")
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+// serverStream подменяет контекст потока контекстом с субъектом вызова.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// clientIP возвращает IP вызывающего из адреса соединения.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
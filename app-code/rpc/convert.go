@@ -0,0 +1,123 @@
+package rpc
+
+import (
+	"fmt"
+	"time"
+	"witness/models"
+	"witness/rpc/pb"
+
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// toProto переводит событие в сообщение gRPC.
+func toProto(event *models.AuditEvent) (*pb.AuditEvent, error) {
+	msg := &pb.AuditEvent{
+		EventId:   event.EventID,
+		Timestamp: timestamppb.New(event.Timestamp),
+		Status:    event.Status,
+		EventType: event.EventType,
+		Actor: &pb.Actor{
+			Id:        event.Actor.ID,
+			Type:      event.Actor.Type,
+			Name:      event.Actor.Name,
+			IpAddress: event.Actor.IPAddress,
+		},
+		Entity: &pb.Entity{
+			Id:   event.Entity.ID,
+			Type: event.Entity.Type,
+			Name: event.Entity.Name,
+		},
+		Context: &pb.Context{
+			SourceService: event.Context.SourceService,
+			TraceId:       event.Context.TraceID,
+			RequestId:     event.Context.RequestID,
+		},
+		Redactions:      int32(event.Redactions),
+		SignatureStatus: event.SignatureStatus,
+		Tenant:          event.Tenant,
+	}
+	if event.Security != nil {
+		msg.Security = &pb.Security{AccessLevel: event.Security.AccessLevel}
+	}
+	if event.Details != nil {
+		details, err := structpb.NewStruct(event.Details)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert details of %s: %w", event.EventID, err)
+		}
+		msg.Details = details
+	}
+	if link := event.Chain; link != nil {
+		msg.Chain = &pb.ChainLink{
+			Stream:     link.Stream,
+			Seq:        link.Seq,
+			IngestedAt: timestamppb.New(link.IngestedAt),
+			PrevHash:   link.PrevHash,
+			Hash:       link.Hash,
+		}
+	}
+	return msg, nil
+}
+
+// fromProto переводит сообщение продюсера в событие. Поля, которые заполняет
+// Witness (chain, tenant и т.д.), не переносятся: конвейер приема все равно их перезапишет.
+func fromProto(msg *pb.AuditEvent) *models.AuditEvent {
+	event := &models.AuditEvent{
+		EventID:   msg.GetEventId(),
+		Status:    msg.GetStatus(),
+		EventType: msg.GetEventType(),
+		Actor: models.Actor{
+			ID:        msg.GetActor().GetId(),
+			Type:      msg.GetActor().GetType(),
+			Name:      msg.GetActor().GetName(),
+			IPAddress: msg.GetActor().GetIpAddress(),
+		},
+		Entity: models.Entity{
+			ID:   msg.GetEntity().GetId(),
+			Type: msg.GetEntity().GetType(),
+			Name: msg.GetEntity().GetName(),
+		},
+		Context: models.Context{
+			SourceService: msg.GetContext().GetSourceService(),
+			TraceID:       msg.GetContext().GetTraceId(),
+			RequestID:     msg.GetContext().GetRequestId(),
+		},
+	}
+	// Без timestamp событие не пройдет проверку конвейера, а не получит 1970 год.
+	if msg.GetTimestamp() != nil {
+		event.Timestamp = msg.GetTimestamp().AsTime()
+	}
+	if msg.GetSecurity() != nil {
+		event.Security = &models.Security{AccessLevel: msg.GetSecurity().GetAccessLevel()}
+	}
+	if msg.GetDetails() != nil {
+		event.Details = msg.GetDetails().AsMap()
+	}
+	return event
+}
+
+// filterFromProto переводит фильтр поиска; пустой фильтр дает nil.
+func filterFromProto(msg *pb.AuditEventFilter) *models.AuditEventFilter {
+	if msg == nil {
+		return nil
+	}
+	return &models.AuditEventFilter{
+		Status:              msg.Status,
+		EventType:           msg.EventType,
+		ActorID:             msg.ActorId,
+		EntityID:            msg.EntityId,
+		SecurityAccessLevel: msg.SecurityAccessLevel,
+		Name:                msg.Name,
+		SignatureStatus:     msg.SignatureStatus,
+		From:                optionalTime(msg.GetFrom()),
+		To:                  optionalTime(msg.GetTo()),
+	}
+}
+
+func optionalTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
@@ -0,0 +1,1224 @@
+// gRPC API Witness: прием событий потоком и поиск по журналу аудита.
+// Поля и их смысл совпадают с GraphQL (schema.graphqls) и JSON-форматом событий Kafka.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: witness.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type IngestStatus int32
+
+const (
+	IngestStatus_INGEST_STATUS_UNSPECIFIED IngestStatus = 0
+	// Событие подготовлено и будет сохранено вместе с ближайшей пачкой.
+	IngestStatus_INGEST_STATUS_ACCEPTED IngestStatus = 1
+	// Событие не разобрано или не прошло проверку. Повторять бесполезно.
+	IngestStatus_INGEST_STATUS_REJECTED IngestStatus = 2
+	// Временная ошибка, событие можно отправить снова.
+	IngestStatus_INGEST_STATUS_FAILED IngestStatus = 3
+)
+
+// Enum value maps for IngestStatus.
+var (
+	IngestStatus_name = map[int32]string{
+		0: "INGEST_STATUS_UNSPECIFIED",
+		1: "INGEST_STATUS_ACCEPTED",
+		2: "INGEST_STATUS_REJECTED",
+		3: "INGEST_STATUS_FAILED",
+	}
+	IngestStatus_value = map[string]int32{
+		"INGEST_STATUS_UNSPECIFIED": 0,
+		"INGEST_STATUS_ACCEPTED":    1,
+		"INGEST_STATUS_REJECTED":    2,
+		"INGEST_STATUS_FAILED":      3,
+	}
+)
+
+func (x IngestStatus) Enum() *IngestStatus {
+	p := new(IngestStatus)
+	*p = x
+	return p
+}
+
+func (x IngestStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (IngestStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_witness_proto_enumTypes[0].Descriptor()
+}
+
+func (IngestStatus) Type() protoreflect.EnumType {
+	return &file_witness_proto_enumTypes[0]
+}
+
+func (x IngestStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use IngestStatus.Descriptor instead.
+func (IngestStatus) EnumDescriptor() ([]byte, []int) {
+	return file_witness_proto_rawDescGZIP(), []int{0}
+}
+
+type Actor struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	IpAddress     string                 `protobuf:"bytes,4,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Actor) Reset() {
+	*x = Actor{}
+	mi := &file_witness_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Actor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Actor) ProtoMessage() {}
+
+func (x *Actor) ProtoReflect() protoreflect.Message {
+	mi := &file_witness_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Actor.ProtoReflect.Descriptor instead.
+func (*Actor) Descriptor() ([]byte, []int) {
+	return file_witness_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Actor) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Actor) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Actor) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Actor) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+type Entity struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Entity) Reset() {
+	*x = Entity{}
+	mi := &file_witness_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Entity) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Entity) ProtoMessage() {}
+
+func (x *Entity) ProtoReflect() protoreflect.Message {
+	mi := &file_witness_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Entity.ProtoReflect.Descriptor instead.
+func (*Entity) Descriptor() ([]byte, []int) {
+	return file_witness_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Entity) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Entity) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Entity) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type Context struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SourceService string                 `protobuf:"bytes,1,opt,name=source_service,json=sourceService,proto3" json:"source_service,omitempty"`
+	TraceId       string                 `protobuf:"bytes,2,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	RequestId     string                 `protobuf:"bytes,3,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Context) Reset() {
+	*x = Context{}
+	mi := &file_witness_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Context) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Context) ProtoMessage() {}
+
+func (x *Context) ProtoReflect() protoreflect.Message {
+	mi := &file_witness_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Context.ProtoReflect.Descriptor instead.
+func (*Context) Descriptor() ([]byte, []int) {
+	return file_witness_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Context) GetSourceService() string {
+	if x != nil {
+		return x.SourceService
+	}
+	return ""
+}
+
+func (x *Context) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+func (x *Context) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+type Security struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AccessLevel   string                 `protobuf:"bytes,1,opt,name=access_level,json=accessLevel,proto3" json:"access_level,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Security) Reset() {
+	*x = Security{}
+	mi := &file_witness_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Security) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Security) ProtoMessage() {}
+
+func (x *Security) ProtoReflect() protoreflect.Message {
+	mi := &file_witness_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Security.ProtoReflect.Descriptor instead.
+func (*Security) Descriptor() ([]byte, []int) {
+	return file_witness_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Security) GetAccessLevel() string {
+	if x != nil {
+		return x.AccessLevel
+	}
+	return ""
+}
+
+// ChainLink - звено цепочки хешей, которое Witness назначает событию при приеме.
+type ChainLink struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stream        string                 `protobuf:"bytes,1,opt,name=stream,proto3" json:"stream,omitempty"`
+	Seq           int64                  `protobuf:"varint,2,opt,name=seq,proto3" json:"seq,omitempty"`
+	IngestedAt    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=ingested_at,json=ingestedAt,proto3" json:"ingested_at,omitempty"`
+	PrevHash      string                 `protobuf:"bytes,4,opt,name=prev_hash,json=prevHash,proto3" json:"prev_hash,omitempty"`
+	Hash          string                 `protobuf:"bytes,5,opt,name=hash,proto3" json:"hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChainLink) Reset() {
+	*x = ChainLink{}
+	mi := &file_witness_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChainLink) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChainLink) ProtoMessage() {}
+
+func (x *ChainLink) ProtoReflect() protoreflect.Message {
+	mi := &file_witness_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChainLink.ProtoReflect.Descriptor instead.
+func (*ChainLink) Descriptor() ([]byte, []int) {
+	return file_witness_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ChainLink) GetStream() string {
+	if x != nil {
+		return x.Stream
+	}
+	return ""
+}
+
+func (x *ChainLink) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *ChainLink) GetIngestedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.IngestedAt
+	}
+	return nil
+}
+
+func (x *ChainLink) GetPrevHash() string {
+	if x != nil {
+		return x.PrevHash
+	}
+	return ""
+}
+
+func (x *ChainLink) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+type AuditEvent struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	EventId   string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Status    string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	EventType string                 `protobuf:"bytes,4,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	Actor     *Actor                 `protobuf:"bytes,5,opt,name=actor,proto3" json:"actor,omitempty"`
+	Entity    *Entity                `protobuf:"bytes,6,opt,name=entity,proto3" json:"entity,omitempty"`
+	Context   *Context               `protobuf:"bytes,7,opt,name=context,proto3" json:"context,omitempty"`
+	Security  *Security              `protobuf:"bytes,8,opt,name=security,proto3" json:"security,omitempty"`
+	Details   *structpb.Struct       `protobuf:"bytes,9,opt,name=details,proto3" json:"details,omitempty"`
+	// Поля ниже заполняет Witness при приеме; значения продюсера игнорируются.
+	Redactions      int32      `protobuf:"varint,10,opt,name=redactions,proto3" json:"redactions,omitempty"`
+	SignatureStatus string     `protobuf:"bytes,11,opt,name=signature_status,json=signatureStatus,proto3" json:"signature_status,omitempty"`
+	Tenant          string     `protobuf:"bytes,12,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	Chain           *ChainLink `protobuf:"bytes,13,opt,name=chain,proto3" json:"chain,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *AuditEvent) Reset() {
+	*x = AuditEvent{}
+	mi := &file_witness_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditEvent) ProtoMessage() {}
+
+func (x *AuditEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_witness_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditEvent.ProtoReflect.Descriptor instead.
+func (*AuditEvent) Descriptor() ([]byte, []int) {
+	return file_witness_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AuditEvent) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *AuditEvent) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetActor() *Actor {
+	if x != nil {
+		return x.Actor
+	}
+	return nil
+}
+
+func (x *AuditEvent) GetEntity() *Entity {
+	if x != nil {
+		return x.Entity
+	}
+	return nil
+}
+
+func (x *AuditEvent) GetContext() *Context {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *AuditEvent) GetSecurity() *Security {
+	if x != nil {
+		return x.Security
+	}
+	return nil
+}
+
+func (x *AuditEvent) GetDetails() *structpb.Struct {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+func (x *AuditEvent) GetRedactions() int32 {
+	if x != nil {
+		return x.Redactions
+	}
+	return 0
+}
+
+func (x *AuditEvent) GetSignatureStatus() string {
+	if x != nil {
+		return x.SignatureStatus
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetChain() *ChainLink {
+	if x != nil {
+		return x.Chain
+	}
+	return nil
+}
+
+type IngestRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*IngestRequest_Event
+	//	*IngestRequest_Json
+	Payload isIngestRequest_Payload `protobuf_oneof:"payload"`
+	// signature - подпись продюсера (base64) для json, если ее нет в конверте.
+	Signature     string `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IngestRequest) Reset() {
+	*x = IngestRequest{}
+	mi := &file_witness_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IngestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestRequest) ProtoMessage() {}
+
+func (x *IngestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_witness_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestRequest.ProtoReflect.Descriptor instead.
+func (*IngestRequest) Descriptor() ([]byte, []int) {
+	return file_witness_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *IngestRequest) GetPayload() isIngestRequest_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *IngestRequest) GetEvent() *AuditEvent {
+	if x != nil {
+		if x, ok := x.Payload.(*IngestRequest_Event); ok {
+			return x.Event
+		}
+	}
+	return nil
+}
+
+func (x *IngestRequest) GetJson() []byte {
+	if x != nil {
+		if x, ok := x.Payload.(*IngestRequest_Json); ok {
+			return x.Json
+		}
+	}
+	return nil
+}
+
+func (x *IngestRequest) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+type isIngestRequest_Payload interface {
+	isIngestRequest_Payload()
+}
+
+type IngestRequest_Event struct {
+	Event *AuditEvent `protobuf:"bytes,1,opt,name=event,proto3,oneof"`
+}
+
+type IngestRequest_Json struct {
+	// json - событие или конверт с подписью в JSON, как в сообщении Kafka. Подпись
+	// продюсера проверяется по точным байтам JSON, поэтому подписанные события передаются так.
+	Json []byte `protobuf:"bytes,2,opt,name=json,proto3,oneof"`
+}
+
+func (*IngestRequest_Event) isIngestRequest_Payload() {}
+
+func (*IngestRequest_Json) isIngestRequest_Payload() {}
+
+type IngestResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// index - номер сообщения в потоке, с нуля.
+	Index         int64        `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	EventId       string       `protobuf:"bytes,2,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	Status        IngestStatus `protobuf:"varint,3,opt,name=status,proto3,enum=witness.v1.IngestStatus" json:"status,omitempty"`
+	Error         string       `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IngestResult) Reset() {
+	*x = IngestResult{}
+	mi := &file_witness_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IngestResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestResult) ProtoMessage() {}
+
+func (x *IngestResult) ProtoReflect() protoreflect.Message {
+	mi := &file_witness_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestResult.ProtoReflect.Descriptor instead.
+func (*IngestResult) Descriptor() ([]byte, []int) {
+	return file_witness_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *IngestResult) GetIndex() int64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *IngestResult) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *IngestResult) GetStatus() IngestStatus {
+	if x != nil {
+		return x.Status
+	}
+	return IngestStatus_INGEST_STATUS_UNSPECIFIED
+}
+
+func (x *IngestResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type IngestResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Accepted      int64                  `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Rejected      int64                  `protobuf:"varint,2,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	Failed        int64                  `protobuf:"varint,3,opt,name=failed,proto3" json:"failed,omitempty"`
+	Results       []*IngestResult        `protobuf:"bytes,4,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IngestResponse) Reset() {
+	*x = IngestResponse{}
+	mi := &file_witness_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IngestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestResponse) ProtoMessage() {}
+
+func (x *IngestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_witness_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestResponse.ProtoReflect.Descriptor instead.
+func (*IngestResponse) Descriptor() ([]byte, []int) {
+	return file_witness_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *IngestResponse) GetAccepted() int64 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
+func (x *IngestResponse) GetRejected() int64 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+func (x *IngestResponse) GetFailed() int64 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *IngestResponse) GetResults() []*IngestResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+// AuditEventFilter - фильтр поиска, как AuditEventFilter в GraphQL. Незаданные поля не фильтруют.
+type AuditEventFilter struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Status              *string                `protobuf:"bytes,1,opt,name=status,proto3,oneof" json:"status,omitempty"`
+	EventType           *string                `protobuf:"bytes,2,opt,name=event_type,json=eventType,proto3,oneof" json:"event_type,omitempty"`
+	ActorId             *string                `protobuf:"bytes,3,opt,name=actor_id,json=actorId,proto3,oneof" json:"actor_id,omitempty"`
+	EntityId            *string                `protobuf:"bytes,4,opt,name=entity_id,json=entityId,proto3,oneof" json:"entity_id,omitempty"`
+	SecurityAccessLevel *string                `protobuf:"bytes,5,opt,name=security_access_level,json=securityAccessLevel,proto3,oneof" json:"security_access_level,omitempty"`
+	// name - полнотекстовый поиск по actor.name и entity.name: все слова должны встретиться.
+	Name            *string                `protobuf:"bytes,6,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	SignatureStatus *string                `protobuf:"bytes,7,opt,name=signature_status,json=signatureStatus,proto3,oneof" json:"signature_status,omitempty"`
+	From            *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=from,proto3" json:"from,omitempty"`
+	To              *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=to,proto3" json:"to,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *AuditEventFilter) Reset() {
+	*x = AuditEventFilter{}
+	mi := &file_witness_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditEventFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditEventFilter) ProtoMessage() {}
+
+func (x *AuditEventFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_witness_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditEventFilter.ProtoReflect.Descriptor instead.
+func (*AuditEventFilter) Descriptor() ([]byte, []int) {
+	return file_witness_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AuditEventFilter) GetStatus() string {
+	if x != nil && x.Status != nil {
+		return *x.Status
+	}
+	return ""
+}
+
+func (x *AuditEventFilter) GetEventType() string {
+	if x != nil && x.EventType != nil {
+		return *x.EventType
+	}
+	return ""
+}
+
+func (x *AuditEventFilter) GetActorId() string {
+	if x != nil && x.ActorId != nil {
+		return *x.ActorId
+	}
+	return ""
+}
+
+func (x *AuditEventFilter) GetEntityId() string {
+	if x != nil && x.EntityId != nil {
+		return *x.EntityId
+	}
+	return ""
+}
+
+func (x *AuditEventFilter) GetSecurityAccessLevel() string {
+	if x != nil && x.SecurityAccessLevel != nil {
+		return *x.SecurityAccessLevel
+	}
+	return ""
+}
+
+func (x *AuditEventFilter) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *AuditEventFilter) GetSignatureStatus() string {
+	if x != nil && x.SignatureStatus != nil {
+		return *x.SignatureStatus
+	}
+	return ""
+}
+
+func (x *AuditEventFilter) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *AuditEventFilter) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+type SearchRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *AuditEventFilter      `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// limit - размер страницы, по умолчанию 20.
+	Limit         *int32 `protobuf:"varint,2,opt,name=limit,proto3,oneof" json:"limit,omitempty"`
+	Offset        int32  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_witness_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_witness_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_witness_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SearchRequest) GetFilter() *AuditEventFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetLimit() int32 {
+	if x != nil && x.Limit != nil {
+		return *x.Limit
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type SearchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*AuditEvent          `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_witness_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_witness_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_witness_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SearchResponse) GetEvents() []*AuditEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type GetEventRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventId       string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetEventRequest) Reset() {
+	*x = GetEventRequest{}
+	mi := &file_witness_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetEventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetEventRequest) ProtoMessage() {}
+
+func (x *GetEventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_witness_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetEventRequest.ProtoReflect.Descriptor instead.
+func (*GetEventRequest) Descriptor() ([]byte, []int) {
+	return file_witness_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetEventRequest) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+var File_witness_proto protoreflect.FileDescriptor
+
+const file_witness_proto_rawDesc = "" +
+	"\n" +
+	"\rwitness.proto\x12\n" +
+	"witness.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"^\n" +
+	"\x05Actor\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x04 \x01(\tR\tipAddress\"@\n" +
+	"\x06Entity\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\"j\n" +
+	"\aContext\x12%\n" +
+	"\x0esource_service\x18\x01 \x01(\tR\rsourceService\x12\x19\n" +
+	"\btrace_id\x18\x02 \x01(\tR\atraceId\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x03 \x01(\tR\trequestId\"-\n" +
+	"\bSecurity\x12!\n" +
+	"\faccess_level\x18\x01 \x01(\tR\vaccessLevel\"\xa3\x01\n" +
+	"\tChainLink\x12\x16\n" +
+	"\x06stream\x18\x01 \x01(\tR\x06stream\x12\x10\n" +
+	"\x03seq\x18\x02 \x01(\x03R\x03seq\x12;\n" +
+	"\vingested_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"ingestedAt\x12\x1b\n" +
+	"\tprev_hash\x18\x04 \x01(\tR\bprevHash\x12\x12\n" +
+	"\x04hash\x18\x05 \x01(\tR\x04hash\"\x91\x04\n" +
+	"\n" +
+	"AuditEvent\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x128\n" +
+	"\ttimestamp\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x04 \x01(\tR\teventType\x12'\n" +
+	"\x05actor\x18\x05 \x01(\v2\x11.witness.v1.ActorR\x05actor\x12*\n" +
+	"\x06entity\x18\x06 \x01(\v2\x12.witness.v1.EntityR\x06entity\x12-\n" +
+	"\acontext\x18\a \x01(\v2\x13.witness.v1.ContextR\acontext\x120\n" +
+	"\bsecurity\x18\b \x01(\v2\x14.witness.v1.SecurityR\bsecurity\x121\n" +
+	"\adetails\x18\t \x01(\v2\x17.google.protobuf.StructR\adetails\x12\x1e\n" +
+	"\n" +
+	"redactions\x18\n" +
+	" \x01(\x05R\n" +
+	"redactions\x12)\n" +
+	"\x10signature_status\x18\v \x01(\tR\x0fsignatureStatus\x12\x16\n" +
+	"\x06tenant\x18\f \x01(\tR\x06tenant\x12+\n" +
+	"\x05chain\x18\r \x01(\v2\x15.witness.v1.ChainLinkR\x05chain\"~\n" +
+	"\rIngestRequest\x12.\n" +
+	"\x05event\x18\x01 \x01(\v2\x16.witness.v1.AuditEventH\x00R\x05event\x12\x14\n" +
+	"\x04json\x18\x02 \x01(\fH\x00R\x04json\x12\x1c\n" +
+	"\tsignature\x18\x03 \x01(\tR\tsignatureB\t\n" +
+	"\apayload\"\x87\x01\n" +
+	"\fIngestResult\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x03R\x05index\x12\x19\n" +
+	"\bevent_id\x18\x02 \x01(\tR\aeventId\x120\n" +
+	"\x06status\x18\x03 \x01(\x0e2\x18.witness.v1.IngestStatusR\x06status\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\x94\x01\n" +
+	"\x0eIngestResponse\x12\x1a\n" +
+	"\baccepted\x18\x01 \x01(\x03R\baccepted\x12\x1a\n" +
+	"\brejected\x18\x02 \x01(\x03R\brejected\x12\x16\n" +
+	"\x06failed\x18\x03 \x01(\x03R\x06failed\x122\n" +
+	"\aresults\x18\x04 \x03(\v2\x18.witness.v1.IngestResultR\aresults\"\xe0\x03\n" +
+	"\x10AuditEventFilter\x12\x1b\n" +
+	"\x06status\x18\x01 \x01(\tH\x00R\x06status\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"event_type\x18\x02 \x01(\tH\x01R\teventType\x88\x01\x01\x12\x1e\n" +
+	"\bactor_id\x18\x03 \x01(\tH\x02R\aactorId\x88\x01\x01\x12 \n" +
+	"\tentity_id\x18\x04 \x01(\tH\x03R\bentityId\x88\x01\x01\x127\n" +
+	"\x15security_access_level\x18\x05 \x01(\tH\x04R\x13securityAccessLevel\x88\x01\x01\x12\x17\n" +
+	"\x04name\x18\x06 \x01(\tH\x05R\x04name\x88\x01\x01\x12.\n" +
+	"\x10signature_status\x18\a \x01(\tH\x06R\x0fsignatureStatus\x88\x01\x01\x12.\n" +
+	"\x04from\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x02toB\t\n" +
+	"\a_statusB\r\n" +
+	"\v_event_typeB\v\n" +
+	"\t_actor_idB\f\n" +
+	"\n" +
+	"_entity_idB\x18\n" +
+	"\x16_security_access_levelB\a\n" +
+	"\x05_nameB\x13\n" +
+	"\x11_signature_status\"\x82\x01\n" +
+	"\rSearchRequest\x124\n" +
+	"\x06filter\x18\x01 \x01(\v2\x1c.witness.v1.AuditEventFilterR\x06filter\x12\x19\n" +
+	"\x05limit\x18\x02 \x01(\x05H\x00R\x05limit\x88\x01\x01\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offsetB\b\n" +
+	"\x06_limit\"V\n" +
+	"\x0eSearchResponse\x12.\n" +
+	"\x06events\x18\x01 \x03(\v2\x16.witness.v1.AuditEventR\x06events\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x03R\x05total\",\n" +
+	"\x0fGetEventRequest\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId*\x7f\n" +
+	"\fIngestStatus\x12\x1d\n" +
+	"\x19INGEST_STATUS_UNSPECIFIED\x10\x00\x12\x1a\n" +
+	"\x16INGEST_STATUS_ACCEPTED\x10\x01\x12\x1a\n" +
+	"\x16INGEST_STATUS_REJECTED\x10\x02\x12\x18\n" +
+	"\x14INGEST_STATUS_FAILED\x10\x032\xd5\x01\n" +
+	"\x0eWitnessService\x12A\n" +
+	"\x06Ingest\x12\x19.witness.v1.IngestRequest\x1a\x1a.witness.v1.IngestResponse(\x01\x12?\n" +
+	"\x06Search\x12\x19.witness.v1.SearchRequest\x1a\x1a.witness.v1.SearchResponse\x12?\n" +
+	"\bGetEvent\x12\x1b.witness.v1.GetEventRequest\x1a\x16.witness.v1.AuditEventB\x10Z\x0ewitness/rpc/pbb\x06proto3"
+
+var (
+	file_witness_proto_rawDescOnce sync.Once
+	file_witness_proto_rawDescData []byte
+)
+
+func file_witness_proto_rawDescGZIP() []byte {
+	file_witness_proto_rawDescOnce.Do(func() {
+		file_witness_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_witness_proto_rawDesc), len(file_witness_proto_rawDesc)))
+	})
+	return file_witness_proto_rawDescData
+}
+
+var file_witness_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_witness_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_witness_proto_goTypes = []any{
+	(IngestStatus)(0),             // 0: witness.v1.IngestStatus
+	(*Actor)(nil),                 // 1: witness.v1.Actor
+	(*Entity)(nil),                // 2: witness.v1.Entity
+	(*Context)(nil),               // 3: witness.v1.Context
+	(*Security)(nil),              // 4: witness.v1.Security
+	(*ChainLink)(nil),             // 5: witness.v1.ChainLink
+	(*AuditEvent)(nil),            // 6: witness.v1.AuditEvent
+	(*IngestRequest)(nil),         // 7: witness.v1.IngestRequest
+	(*IngestResult)(nil),          // 8: witness.v1.IngestResult
+	(*IngestResponse)(nil),        // 9: witness.v1.IngestResponse
+	(*AuditEventFilter)(nil),      // 10: witness.v1.AuditEventFilter
+	(*SearchRequest)(nil),         // 11: witness.v1.SearchRequest
+	(*SearchResponse)(nil),        // 12: witness.v1.SearchResponse
+	(*GetEventRequest)(nil),       // 13: witness.v1.GetEventRequest
+	(*timestamppb.Timestamp)(nil), // 14: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),       // 15: google.protobuf.Struct
+}
+var file_witness_proto_depIdxs = []int32{
+	14, // 0: witness.v1.ChainLink.ingested_at:type_name -> google.protobuf.Timestamp
+	14, // 1: witness.v1.AuditEvent.timestamp:type_name -> google.protobuf.Timestamp
+	1,  // 2: witness.v1.AuditEvent.actor:type_name -> witness.v1.Actor
+	2,  // 3: witness.v1.AuditEvent.entity:type_name -> witness.v1.Entity
+	3,  // 4: witness.v1.AuditEvent.context:type_name -> witness.v1.Context
+	4,  // 5: witness.v1.AuditEvent.security:type_name -> witness.v1.Security
+	15, // 6: witness.v1.AuditEvent.details:type_name -> google.protobuf.Struct
+	5,  // 7: witness.v1.AuditEvent.chain:type_name -> witness.v1.ChainLink
+	6,  // 8: witness.v1.IngestRequest.event:type_name -> witness.v1.AuditEvent
+	0,  // 9: witness.v1.IngestResult.status:type_name -> witness.v1.IngestStatus
+	8,  // 10: witness.v1.IngestResponse.results:type_name -> witness.v1.IngestResult
+	14, // 11: witness.v1.AuditEventFilter.from:type_name -> google.protobuf.Timestamp
+	14, // 12: witness.v1.AuditEventFilter.to:type_name -> google.protobuf.Timestamp
+	10, // 13: witness.v1.SearchRequest.filter:type_name -> witness.v1.AuditEventFilter
+	6,  // 14: witness.v1.SearchResponse.events:type_name -> witness.v1.AuditEvent
+	7,  // 15: witness.v1.WitnessService.Ingest:input_type -> witness.v1.IngestRequest
+	11, // 16: witness.v1.WitnessService.Search:input_type -> witness.v1.SearchRequest
+	13, // 17: witness.v1.WitnessService.GetEvent:input_type -> witness.v1.GetEventRequest
+	9,  // 18: witness.v1.WitnessService.Ingest:output_type -> witness.v1.IngestResponse
+	12, // 19: witness.v1.WitnessService.Search:output_type -> witness.v1.SearchResponse
+	6,  // 20: witness.v1.WitnessService.GetEvent:output_type -> witness.v1.AuditEvent
+	18, // [18:21] is the sub-list for method output_type
+	15, // [15:18] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
+}
+
+func init() { file_witness_proto_init() }
+func file_witness_proto_init() {
+	if File_witness_proto != nil {
+		return
+	}
+	file_witness_proto_msgTypes[6].OneofWrappers = []any{
+		(*IngestRequest_Event)(nil),
+		(*IngestRequest_Json)(nil),
+	}
+	file_witness_proto_msgTypes[9].OneofWrappers = []any{}
+	file_witness_proto_msgTypes[10].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_witness_proto_rawDesc), len(file_witness_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_witness_proto_goTypes,
+		DependencyIndexes: file_witness_proto_depIdxs,
+		EnumInfos:         file_witness_proto_enumTypes,
+		MessageInfos:      file_witness_proto_msgTypes,
+	}.Build()
+	File_witness_proto = out.File
+	file_witness_proto_goTypes = nil
+	file_witness_proto_depIdxs = nil
+}
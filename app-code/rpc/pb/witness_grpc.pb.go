@@ -0,0 +1,206 @@
+// gRPC API Witness: прием событий потоком и поиск по журналу аудита.
+// Поля и их смысл совпадают с GraphQL (schema.graphqls) и JSON-форматом событий Kafka.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: witness.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	WitnessService_Ingest_FullMethodName   = "/witness.v1.WitnessService/Ingest"
+	WitnessService_Search_FullMethodName   = "/witness.v1.WitnessService/Search"
+	WitnessService_GetEvent_FullMethodName = "/witness.v1.WitnessService/GetEvent"
+)
+
+// WitnessServiceClient is the client API for WitnessService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// WitnessService требует метаданные authorization: Bearer <token> (JWT или ключ API),
+// как HTTP API. Ingest - роль events:write, Search и GetEvent - events:read.
+type WitnessServiceClient interface {
+	// Ingest принимает поток событий и по его завершении возвращает результат по каждому.
+	Ingest(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[IngestRequest, IngestResponse], error)
+	// Search ищет события, от новых к старым.
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// GetEvent возвращает событие по event_id; NOT_FOUND, если его нет или оно чужого арендатора.
+	GetEvent(ctx context.Context, in *GetEventRequest, opts ...grpc.CallOption) (*AuditEvent, error)
+}
+
+type witnessServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWitnessServiceClient(cc grpc.ClientConnInterface) WitnessServiceClient {
+	return &witnessServiceClient{cc}
+}
+
+func (c *witnessServiceClient) Ingest(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[IngestRequest, IngestResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &WitnessService_ServiceDesc.Streams[0], WitnessService_Ingest_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[IngestRequest, IngestResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WitnessService_IngestClient = grpc.ClientStreamingClient[IngestRequest, IngestResponse]
+
+func (c *witnessServiceClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, WitnessService_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *witnessServiceClient) GetEvent(ctx context.Context, in *GetEventRequest, opts ...grpc.CallOption) (*AuditEvent, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuditEvent)
+	err := c.cc.Invoke(ctx, WitnessService_GetEvent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WitnessServiceServer is the server API for WitnessService service.
+// All implementations must embed UnimplementedWitnessServiceServer
+// for forward compatibility.
+//
+// WitnessService требует метаданные authorization: Bearer <token> (JWT или ключ API),
+// как HTTP API. Ingest - роль events:write, Search и GetEvent - events:read.
+type WitnessServiceServer interface {
+	// Ingest принимает поток событий и по его завершении возвращает результат по каждому.
+	Ingest(grpc.ClientStreamingServer[IngestRequest, IngestResponse]) error
+	// Search ищет события, от новых к старым.
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	// GetEvent возвращает событие по event_id; NOT_FOUND, если его нет или оно чужого арендатора.
+	GetEvent(context.Context, *GetEventRequest) (*AuditEvent, error)
+	mustEmbedUnimplementedWitnessServiceServer()
+}
+
+// UnimplementedWitnessServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedWitnessServiceServer struct{}
+
+func (UnimplementedWitnessServiceServer) Ingest(grpc.ClientStreamingServer[IngestRequest, IngestResponse]) error {
+	return status.Error(codes.Unimplemented, "method Ingest not implemented")
+}
+func (UnimplementedWitnessServiceServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedWitnessServiceServer) GetEvent(context.Context, *GetEventRequest) (*AuditEvent, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetEvent not implemented")
+}
+func (UnimplementedWitnessServiceServer) mustEmbedUnimplementedWitnessServiceServer() {}
+func (UnimplementedWitnessServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeWitnessServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WitnessServiceServer will
+// result in compilation errors.
+type UnsafeWitnessServiceServer interface {
+	mustEmbedUnimplementedWitnessServiceServer()
+}
+
+func RegisterWitnessServiceServer(s grpc.ServiceRegistrar, srv WitnessServiceServer) {
+	// If the following call panics, it indicates UnimplementedWitnessServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&WitnessService_ServiceDesc, srv)
+}
+
+func _WitnessService_Ingest_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(WitnessServiceServer).Ingest(&grpc.GenericServerStream[IngestRequest, IngestResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WitnessService_IngestServer = grpc.ClientStreamingServer[IngestRequest, IngestResponse]
+
+func _WitnessService_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WitnessServiceServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WitnessService_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WitnessServiceServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WitnessService_GetEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WitnessServiceServer).GetEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WitnessService_GetEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WitnessServiceServer).GetEvent(ctx, req.(*GetEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// WitnessService_ServiceDesc is the grpc.ServiceDesc for WitnessService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WitnessService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "witness.v1.WitnessService",
+	HandlerType: (*WitnessServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Search",
+			Handler:    _WitnessService_Search_Handler,
+		},
+		{
+			MethodName: "GetEvent",
+			Handler:    _WitnessService_GetEvent_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Ingest",
+			Handler:       _WitnessService_Ingest_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "witness.proto",
+}
@@ -0,0 +1,232 @@
+// Package rpc реализует gRPC API Witness (witness.proto): прием событий потоком
+// через тот же конвейер, что и у Kafka, и поиск через то же хранилище, что у GraphQL.
+package rpc
+
+//go:generate buf generate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+	"witness/auth"
+	"witness/export"
+	"witness/ingest"
+	"witness/models"
+	"witness/pii"
+	"witness/querylog"
+	"witness/rpc/pb"
+	"witness/storage"
+	"witness/tenant"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Service реализует pb.WitnessServiceServer.
+type Service struct {
+	pb.UnimplementedWitnessServiceServer
+
+	pipeline *ingest.Pipeline
+	buffer   *ingest.Buffer
+	tenants  *tenant.Resolver
+	// stream - поток цепочки хешей для событий, принятых этим экземпляром по gRPC.
+	stream string
+
+	store    storage.EventStore
+	queryLog *querylog.Recorder
+	// maskKeys - ключи details, скрываемые от субъектов без роли pii_reader.
+	maskKeys []string
+}
+
+// NewService создает сервис. Принятые события проходят pipeline и сохраняются
+// через buffer в цепочке потока stream (своего у каждого экземпляра Witness).
+// store - хранилище для API (с ограничением арендатором и расшифровкой PII);
+// Search и GetEvent записываются в журнал queryLog (nil - журнал отключен).
+func NewService(pipeline *ingest.Pipeline, buffer *ingest.Buffer, tenants *tenant.Resolver, stream string,
+	store storage.EventStore, queryLog *querylog.Recorder, maskKeys []string) *Service {
+	return &Service{
+		pipeline: pipeline,
+		buffer:   buffer,
+		tenants:  tenants,
+		stream:   stream,
+		store:    store,
+		queryLog: queryLog,
+		maskKeys: maskKeys,
+	}
+}
+
+// Ingest принимает поток событий. Каждое событие готовится и ставится в буфер записи
+// по мере получения; результат по всем событиям возвращается, когда клиент закроет поток.
+func (s *Service) Ingest(stream grpc.ClientStreamingServer[pb.IngestRequest, pb.IngestResponse]) error {
+	ctx := stream.Context()
+	if err := requireRole(ctx, models.ScopeEventsWrite); err != nil {
+		return err
+	}
+	if err := s.buffer.Admit(); err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	meta := ingest.Meta{Tenant: s.tenants.ResolveCaller(ctx, first(metadata.ValueFromIncomingContext(ctx, tenant.Header)))}
+
+	res := &pb.IngestResponse{}
+	for index := int64(0); ; index++ {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(res)
+		}
+		if err != nil {
+			return err
+		}
+
+		result := &pb.IngestResult{Index: index}
+		// Буфер переполнился посреди потока: уже принятые события остаются, остальные
+		// получают FAILED и могут быть отправлены повторно.
+		var event *models.AuditEvent
+		err = s.buffer.Admit()
+		if err == nil {
+			event, err = s.process(ctx, req, meta)
+		}
+		switch {
+		case err == nil:
+			result.EventId, result.Status = event.EventID, pb.IngestStatus_INGEST_STATUS_ACCEPTED
+			// Пачку могут сохранить вместе с другими событиями: обрыв потока не должен ее прерывать.
+			s.buffer.Add(context.WithoutCancel(ctx), event)
+			res.Accepted++
+		case errors.Is(err, ingest.ErrMalformed):
+			result.Status, result.Error = pb.IngestStatus_INGEST_STATUS_REJECTED, err.Error()
+			res.Rejected++
+		default:
+			slog.Error("failed to process ingested event", "stream", s.stream, "index", index, "error", err)
+			result.Status, result.Error = pb.IngestStatus_INGEST_STATUS_FAILED, err.Error()
+			res.Failed++
+		}
+		res.Results = append(res.Results, result)
+	}
+}
+
+// process проводит сообщение через конвейер приема. Событие в виде protobuf
+// переводится в JSON; подпись продюсера проверяется только у события в JSON.
+func (s *Service) process(ctx context.Context, req *pb.IngestRequest, meta ingest.Meta) (*models.AuditEvent, error) {
+	var value []byte
+	switch payload := req.GetPayload().(type) {
+	case *pb.IngestRequest_Json:
+		value = payload.Json
+		meta.Signature = req.GetSignature()
+	case *pb.IngestRequest_Event:
+		data, err := json.Marshal(fromProto(payload.Event))
+		if err != nil {
+			return nil, err
+		}
+		value = data
+	default:
+		return nil, fmt.Errorf("%w: event or json is required", ingest.ErrMalformed)
+	}
+	return s.pipeline.Process(ctx, s.stream, value, meta)
+}
+
+// Search ищет события, от новых к старым, как searchEvents в GraphQL.
+func (s *Service) Search(ctx context.Context, req *pb.SearchRequest) (*pb.SearchResponse, error) {
+	if err := requireRole(ctx, models.ScopeEventsRead); err != nil {
+		return nil, err
+	}
+	limit := 20
+	if req.Limit != nil {
+		limit = int(req.GetLimit())
+	}
+	if limit < 0 || req.GetOffset() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit and offset must not be negative")
+	}
+
+	start := time.Now()
+	events, total, err := s.store.Search(ctx, storage.Query{
+		Filter: filterFromProto(req.GetFilter()),
+		Limit:  limit,
+		Offset: int(req.GetOffset()),
+	})
+	s.record(ctx, "", len(events), start, err)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	res := &pb.SearchResponse{Total: total, Events: make([]*pb.AuditEvent, 0, len(events))}
+	for _, event := range events {
+		msg, err := toProto(export.View(ctx, event, s.maskKeys))
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		res.Events = append(res.Events, msg)
+	}
+	return res, nil
+}
+
+// GetEvent возвращает событие по event_id.
+func (s *Service) GetEvent(ctx context.Context, req *pb.GetEventRequest) (*pb.AuditEvent, error) {
+	if err := requireRole(ctx, models.ScopeEventsRead); err != nil {
+		return nil, err
+	}
+	if req.GetEventId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "event_id is required")
+	}
+
+	start := time.Now()
+	event, err := s.store.Get(ctx, req.GetEventId())
+	if errors.Is(err, storage.ErrNotFound) {
+		s.record(ctx, req.GetEventId(), 0, start, nil)
+		return nil, status.Error(codes.NotFound, "event not found")
+	}
+	s.record(ctx, req.GetEventId(), 1, start, err)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	msg, err := toProto(export.View(ctx, event, s.maskKeys))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return msg, nil
+}
+
+// record записывает вызов в журнал запросов с теми же полями details, что у REST API.
+func (s *Service) record(ctx context.Context, id string, results int, start time.Time, err error) {
+	method, _ := grpc.Method(ctx)
+	details := map[string]any{
+		"operation":      method,
+		"operation_type": "grpc",
+		"result_count":   results,
+		"latency_ms":     time.Since(start).Milliseconds(),
+	}
+	if id != "" {
+		details["id"] = id
+	}
+	if err != nil {
+		details["errors"] = 1
+	}
+	entity := models.Entity{ID: method, Type: "GRPC_REQUEST", Name: method}
+	s.queryLog.Record(ctx, querylog.EventType, entity, err == nil, details)
+}
+
+// requireRole отвечает PERMISSION_DENIED, если у субъекта вызова нет роли.
+func requireRole(ctx context.Context, role string) error {
+	if !auth.FromContext(ctx).HasRole(role) {
+		return status.Error(codes.PermissionDenied, "forbidden: role "+role+" is required")
+	}
+	return nil
+}
+
+// storeError переводит ошибку хранилища в статус gRPC: субъект без арендатора получает
+// PERMISSION_DENIED, фильтр по имени при шифровании PII - INVALID_ARGUMENT.
+func storeError(err error) error {
+	switch {
+	case errors.Is(err, tenant.ErrNoTenant):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, pii.ErrNameFilter):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	slog.Error("grpc storage request failed", "error", err)
+	return status.Error(codes.Internal, "storage request failed")
+}
@@ -0,0 +1,493 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+	"time"
+	"witness/auth"
+	"witness/ingest"
+	"witness/integrity"
+	"witness/live"
+	"witness/models"
+	"witness/pii"
+	"witness/redaction"
+	"witness/rpc"
+	"witness/rpc/pb"
+	"witness/signing"
+	"witness/storage"
+	"witness/storage/memory"
+	"witness/tenant"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const testStream = "grpc/test"
+
+// tokens - субъекты, которых тестовый способ аутентификации выдает по токену.
+var tokens = map[string]*auth.Principal{
+	"writer":  {Subject: "billing-service", Roles: []string{models.ScopeEventsWrite}, Tenant: "acme"},
+	"shared":  {Subject: "shared-service", Roles: []string{models.ScopeEventsWrite}},
+	"rival":   {Subject: "globex-service", Roles: []string{models.ScopeEventsWrite}, Tenant: "globex"},
+	"reader":  {Subject: "reader", Roles: []string{models.ScopeEventsRead}, Tenant: "acme"},
+	"pii":     {Subject: "dpo", Roles: []string{models.ScopeEventsRead, auth.RolePIIReader}, Tenant: "acme"},
+	"globex":  {Subject: "globex-reader", Roles: []string{models.ScopeEventsRead}, Tenant: "globex"},
+	"orphan":  {Subject: "orphan", Roles: []string{models.ScopeEventsRead}},
+	"nothing": {Subject: "nobody", Tenant: "acme"},
+}
+
+type tokenAuth struct{}
+
+func (tokenAuth) Authenticate(_ context.Context, token string) (*auth.Principal, error) {
+	if p, ok := tokens[token]; ok {
+		return p, nil
+	}
+	return nil, auth.ErrUnauthenticated
+}
+
+// brokenKeys - хранилище ключей PII, которое всегда недоступно.
+type brokenKeys struct{}
+
+var errKeysUnavailable = errors.New("key store unavailable")
+
+func (brokenKeys) Key(string) ([]byte, error)         { return nil, errKeysUnavailable }
+func (brokenKeys) GetOrCreate(string) ([]byte, error) { return nil, errKeysUnavailable }
+func (brokenKeys) Delete(string) error                { return errKeysUnavailable }
+
+// testServer - сервис gRPC поверх хранилища в памяти в режиме нескольких арендаторов,
+// доступный клиенту через bufconn.
+type testServer struct {
+	client pb.WitnessServiceClient
+	store  storage.Backend
+	buffer *ingest.Buffer
+}
+
+func newTestServer(t *testing.T, keys pii.KeyStore) *testServer {
+	t.Helper()
+	store := memory.New()
+	signatures, err := signing.LoadRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redactor, err := redaction.Load("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptor := pii.NewEncryptor(keys, nil)
+	pipeline := ingest.NewPipeline(signatures, redactor, encryptor, integrity.NewChain(store))
+	buffer := ingest.NewBuffer(store, live.NewBroker())
+	service := rpc.NewService(pipeline, buffer, tenant.NewResolver(nil, "default"), testStream,
+		pii.NewStore(tenant.NewStore(store, true), encryptor), nil, []string{"email"})
+
+	lis := bufconn.Listen(1 << 20)
+	srv := rpc.NewServer(service, tokenAuth{}, nil)
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testServer{client: pb.NewWitnessServiceClient(conn), store: store, buffer: buffer}
+}
+
+// as возвращает контекст вызова с bearer-токеном и дополнительными метаданными kv.
+func as(token string, kv ...string) context.Context {
+	ctx := context.Background()
+	if token != "" {
+		kv = append(kv, "authorization", "Bearer "+token)
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+func wantCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Errorf("got %v, want %s", err, code)
+	}
+}
+
+var t0 = time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+func protoEvent(id string) *pb.AuditEvent {
+	details, _ := structpb.NewStruct(map[string]any{"email": "alice@example.com"})
+	return &pb.AuditEvent{
+		EventId:   id,
+		Timestamp: timestamppb.New(t0),
+		Status:    "SUCCESS",
+		EventType: "LOGIN",
+		Actor:     &pb.Actor{Id: "alice", Type: "USER", Name: "Alice Smith"},
+		Entity:    &pb.Entity{Id: "doc-1", Type: "DOCUMENT", Name: "Quarterly report"},
+		Context:   &pb.Context{SourceService: "auth"},
+		Details:   details,
+		// Поля, которые назначает Witness, от продюсера не принимаются.
+		Tenant: "globex",
+		Chain:  &pb.ChainLink{Stream: "forged", Seq: 100},
+	}
+}
+
+func eventRequest(id string) *pb.IngestRequest {
+	return &pb.IngestRequest{Payload: &pb.IngestRequest_Event{Event: protoEvent(id)}}
+}
+
+func jsonRequest(data string) *pb.IngestRequest {
+	return &pb.IngestRequest{Payload: &pb.IngestRequest_Json{Json: []byte(data)}}
+}
+
+// ingest отправляет запросы одним потоком и возвращает ответ сервиса.
+func (s *testServer) ingest(ctx context.Context, reqs ...*pb.IngestRequest) (*pb.IngestResponse, error) {
+	stream, err := s.client.Ingest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, req := range reqs {
+		if err := stream.Send(req); err != nil {
+			// Сервер закрыл поток; причину вернет CloseAndRecv.
+			break
+		}
+	}
+	return stream.CloseAndRecv()
+}
+
+func TestIngest(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	res, err := s.ingest(as("writer", tenant.Header, "globex"),
+		eventRequest("e1"),
+		jsonRequest(`{"event_id": "e2", "timestamp": "2024-07-01T12:01:00Z", "status": "FAILURE", "event_type": "LOGIN", "actor": {"id": "bob"}}`),
+		jsonRequest(`{"event_id": `),
+		&pb.IngestRequest{Payload: &pb.IngestRequest_Event{Event: &pb.AuditEvent{EventType: "LOGIN"}}},
+		&pb.IngestRequest{},
+		// Повторная доставка принимается и не создает второго звена цепочки.
+		eventRequest("e1"),
+	)
+	if err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	if res.GetAccepted() != 3 || res.GetRejected() != 3 || res.GetFailed() != 0 {
+		t.Errorf("got accepted %d, rejected %d, failed %d; want 3, 3, 0", res.GetAccepted(), res.GetRejected(), res.GetFailed())
+	}
+	want := []struct {
+		eventID string
+		status  pb.IngestStatus
+	}{
+		{"e1", pb.IngestStatus_INGEST_STATUS_ACCEPTED},
+		{"e2", pb.IngestStatus_INGEST_STATUS_ACCEPTED},
+		{"", pb.IngestStatus_INGEST_STATUS_REJECTED},
+		{"", pb.IngestStatus_INGEST_STATUS_REJECTED},
+		{"", pb.IngestStatus_INGEST_STATUS_REJECTED},
+		{"e1", pb.IngestStatus_INGEST_STATUS_ACCEPTED},
+	}
+	if len(res.GetResults()) != len(want) {
+		t.Fatalf("got %d results, want %d", len(res.GetResults()), len(want))
+	}
+	for i, r := range res.GetResults() {
+		if r.GetIndex() != int64(i) || r.GetEventId() != want[i].eventID || r.GetStatus() != want[i].status {
+			t.Errorf("result %d: got index %d, event %q, status %s", i, r.GetIndex(), r.GetEventId(), r.GetStatus())
+		}
+		if (r.GetStatus() == pb.IngestStatus_INGEST_STATUS_REJECTED) != (r.GetError() != "") {
+			t.Errorf("result %d: status %s with error %q", i, r.GetStatus(), r.GetError())
+		}
+	}
+
+	if err := s.buffer.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	events, total, err := s.store.Search(ctx, storage.Query{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 {
+		t.Fatalf("got %d stored events, want 2", total)
+	}
+	for _, e := range events {
+		// Арендатор субъекта главнее заголовка, звено цепочки назначает Witness.
+		if e.Tenant != "acme" {
+			t.Errorf("%s: got tenant %q, want acme", e.EventID, e.Tenant)
+		}
+		if e.Chain == nil || e.Chain.Stream != testStream || e.Chain.Seq > 2 {
+			t.Errorf("%s: got chain %+v", e.EventID, e.Chain)
+		}
+	}
+}
+
+func TestIngestTenantHeader(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	// У субъекта без арендатора арендатора задает заголовок, иначе - арендатор по умолчанию.
+	if _, err := s.ingest(as("shared", tenant.Header, "globex"), eventRequest("e1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ingest(as("shared"), eventRequest("e2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.buffer.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]string{"e1": "globex", "e2": "default"} {
+		e, err := s.store.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Tenant != want {
+			t.Errorf("%s: got tenant %q, want %q", id, e.Tenant, want)
+		}
+	}
+}
+
+func TestIngestEventIDOfAnotherTenant(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	// e1 сохранено, e2 принято, но еще в буфере: ни то, ни другое не повторная доставка для globex.
+	if _, err := s.ingest(as("writer"), eventRequest("e1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.buffer.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ingest(as("writer"), eventRequest("e2")); err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.ingest(as("rival"), eventRequest("e1"), eventRequest("e2"), eventRequest("g1"))
+	if err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	if res.GetAccepted() != 1 || res.GetRejected() != 2 {
+		t.Errorf("got accepted %d, rejected %d; want 1, 2", res.GetAccepted(), res.GetRejected())
+	}
+	if err := s.buffer.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]string{"e1": "acme", "e2": "acme", "g1": "globex"} {
+		e, err := s.store.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Tenant != want {
+			t.Errorf("%s: got tenant %q, want %q", id, e.Tenant, want)
+		}
+	}
+}
+
+func TestIngestFailed(t *testing.T) {
+	s := newTestServer(t, brokenKeys{})
+
+	// PII события без actor.id не шифруется, поэтому недоступность ключей его не затрагивает.
+	anonymous := protoEvent("e2")
+	anonymous.Actor = nil
+	res, err := s.ingest(as("writer"), eventRequest("e1"), &pb.IngestRequest{Payload: &pb.IngestRequest_Event{Event: anonymous}})
+	if err != nil {
+		t.Fatalf("Ingest: %v", err)
+	}
+	if res.GetAccepted() != 1 || res.GetFailed() != 1 {
+		t.Errorf("got accepted %d, failed %d; want 1, 1", res.GetAccepted(), res.GetFailed())
+	}
+	results := res.GetResults()
+	if len(results) != 2 || results[0].GetStatus() != pb.IngestStatus_INGEST_STATUS_FAILED || results[0].GetError() == "" ||
+		results[1].GetStatus() != pb.IngestStatus_INGEST_STATUS_ACCEPTED {
+		t.Errorf("got results %v", results)
+	}
+}
+
+func TestIngestAccess(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	_, err := s.ingest(as("reader"), eventRequest("e1"))
+	wantCode(t, err, codes.PermissionDenied)
+	_, err = s.ingest(as(""), eventRequest("e1"))
+	wantCode(t, err, codes.Unauthenticated)
+	_, err = s.ingest(as("stolen"), eventRequest("e1"))
+	wantCode(t, err, codes.Unauthenticated)
+
+	if n := s.buffer.Len(); n != 0 {
+		t.Errorf("%d events buffered from rejected calls", n)
+	}
+}
+
+// seed сохраняет события e1..e5 арендатора acme (e5 - самое новое) и g1 арендатора globex.
+func seed(t *testing.T, store storage.EventStore) {
+	t.Helper()
+	var batch []*models.AuditEvent
+	for i, id := range []string{"e1", "e2", "e3", "e4", "e5", "g1"} {
+		batch = append(batch, &models.AuditEvent{
+			EventID:   id,
+			Timestamp: t0.Add(time.Duration(i) * time.Minute),
+			Status:    []string{"SUCCESS", "FAILURE"}[i%2],
+			EventType: "LOGIN",
+			Actor:     models.Actor{ID: "alice", Type: "USER", Name: "Alice Smith", IPAddress: "10.0.0.1"},
+			Entity:    models.Entity{ID: "doc-1", Type: "DOCUMENT", Name: "Quarterly report"},
+			Context:   models.Context{SourceService: "auth"},
+			Details:   map[string]any{"email": "alice@example.com"},
+			Tenant:    map[bool]string{true: "globex", false: "acme"}[id == "g1"],
+			Chain:     &models.ChainLink{Stream: "kafka/audit", Seq: int64(i + 1), IngestedAt: t0},
+		})
+	}
+	if err := store.IndexBatch(context.Background(), batch); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func eventIDs(events []*pb.AuditEvent) []string {
+	var ids []string
+	for _, e := range events {
+		ids = append(ids, e.GetEventId())
+	}
+	return ids
+}
+
+func TestSearchPaging(t *testing.T) {
+	s := newTestServer(t, nil)
+	seed(t, s.store)
+
+	var got []string
+	for offset := int32(0); ; offset += 2 {
+		res, err := s.client.Search(as("reader"), &pb.SearchRequest{Limit: proto.Int32(2), Offset: offset})
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		if res.GetTotal() != 5 {
+			t.Fatalf("offset %d: got total %d, want 5", offset, res.GetTotal())
+		}
+		if len(res.GetEvents()) == 0 {
+			break
+		}
+		got = append(got, eventIDs(res.GetEvents())...)
+	}
+	if want := []string{"e5", "e4", "e3", "e2", "e1"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Без limit - страница по умолчанию; limit 0 - только total.
+	res, err := s.client.Search(as("reader"), &pb.SearchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.GetEvents()) != 5 {
+		t.Errorf("default limit: got %d events, want 5", len(res.GetEvents()))
+	}
+	res, err = s.client.Search(as("reader"), &pb.SearchRequest{Limit: proto.Int32(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.GetEvents()) != 0 || res.GetTotal() != 5 {
+		t.Errorf("limit 0: got %d events, total %d", len(res.GetEvents()), res.GetTotal())
+	}
+
+	_, err = s.client.Search(as("reader"), &pb.SearchRequest{Limit: proto.Int32(-1)})
+	wantCode(t, err, codes.InvalidArgument)
+	_, err = s.client.Search(as("reader"), &pb.SearchRequest{Offset: -1})
+	wantCode(t, err, codes.InvalidArgument)
+}
+
+func TestSearchFilter(t *testing.T) {
+	s := newTestServer(t, nil)
+	seed(t, s.store)
+
+	failure := "FAILURE"
+	res, err := s.client.Search(as("reader"), &pb.SearchRequest{Filter: &pb.AuditEventFilter{
+		Status: &failure,
+		From:   timestamppb.New(t0.Add(2 * time.Minute)),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventIDs(res.GetEvents()); !slices.Equal(got, []string{"e4"}) || res.GetTotal() != 1 {
+		t.Errorf("got %v (total %d), want [e4]", got, res.GetTotal())
+	}
+}
+
+func TestSearchNameWithEncryption(t *testing.T) {
+	keys, err := pii.NewFileKeyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer(t, keys)
+	seed(t, s.store)
+
+	// Имена акторов зашифрованы: поиск по имени отклоняется, а не теряет совпадения.
+	name := "Alice"
+	_, err = s.client.Search(as("reader"), &pb.SearchRequest{Filter: &pb.AuditEventFilter{Name: &name}})
+	wantCode(t, err, codes.InvalidArgument)
+
+	if _, err := s.client.Search(as("reader"), &pb.SearchRequest{}); err != nil {
+		t.Errorf("Search without name: %v", err)
+	}
+}
+
+func TestSearchTenantScope(t *testing.T) {
+	s := newTestServer(t, nil)
+	seed(t, s.store)
+
+	res, err := s.client.Search(as("globex"), &pb.SearchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventIDs(res.GetEvents()); !slices.Equal(got, []string{"g1"}) || res.GetTotal() != 1 {
+		t.Errorf("got %v (total %d), want only g1", got, res.GetTotal())
+	}
+
+	_, err = s.client.Search(as("orphan"), &pb.SearchRequest{})
+	wantCode(t, err, codes.PermissionDenied)
+	_, err = s.client.Search(as("nothing"), &pb.SearchRequest{})
+	wantCode(t, err, codes.PermissionDenied)
+	_, err = s.client.Search(as("writer"), &pb.SearchRequest{})
+	wantCode(t, err, codes.PermissionDenied)
+	_, err = s.client.Search(as(""), &pb.SearchRequest{})
+	wantCode(t, err, codes.Unauthenticated)
+}
+
+func TestGetEvent(t *testing.T) {
+	s := newTestServer(t, nil)
+	seed(t, s.store)
+
+	e, err := s.client.GetEvent(as("reader"), &pb.GetEventRequest{EventId: "e3"})
+	if err != nil {
+		t.Fatalf("GetEvent: %v", err)
+	}
+	if e.GetEventId() != "e3" || e.GetTenant() != "acme" || e.GetChain().GetSeq() != 3 || !e.GetTimestamp().AsTime().Equal(t0.Add(2*time.Minute)) {
+		t.Errorf("got event %v", e)
+	}
+	if e.GetActor().GetIpAddress() != redaction.Mask || e.GetDetails().AsMap()["email"] != redaction.Mask {
+		t.Errorf("PII is not masked without pii_reader: %v", e)
+	}
+
+	e, err = s.client.GetEvent(as("pii"), &pb.GetEventRequest{EventId: "e3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.GetActor().GetIpAddress() != "10.0.0.1" || e.GetDetails().AsMap()["email"] != "alice@example.com" {
+		t.Errorf("PII is masked for pii_reader: %v", e)
+	}
+
+	// Событие другого арендатора неотличимо от отсутствующего.
+	_, err = s.client.GetEvent(as("reader"), &pb.GetEventRequest{EventId: "g1"})
+	wantCode(t, err, codes.NotFound)
+	_, err = s.client.GetEvent(as("reader"), &pb.GetEventRequest{EventId: "missing"})
+	wantCode(t, err, codes.NotFound)
+	_, err = s.client.GetEvent(as("reader"), &pb.GetEventRequest{})
+	wantCode(t, err, codes.InvalidArgument)
+	_, err = s.client.GetEvent(as("orphan"), &pb.GetEventRequest{EventId: "e3"})
+	wantCode(t, err, codes.PermissionDenied)
+	_, err = s.client.GetEvent(as("nothing"), &pb.GetEventRequest{EventId: "e3"})
+	wantCode(t, err, codes.PermissionDenied)
+}
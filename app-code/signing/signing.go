@@ -0,0 +1,91 @@
+// Package signing проверяет подписи, которыми продюсеры подтверждают авторство событий.
+//
+// Продюсер подписывает ключом Ed25519 точные байты события (JSON) и передает подпись
+// в base64 либо в заголовке сообщения (Header), либо в конверте:
+//
+//	{"event": {...}, "signature": "<base64>"}
+//
+// Подпись проверяется открытыми ключами source_service события из реестра.
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"witness/models"
+)
+
+// Header - заголовок сообщения Kafka с подписью события.
+const Header = "witness-signature"
+
+// Registry - открытые ключи продюсеров по source_service. У сервиса может быть
+// несколько ключей, чтобы менять их без остановки.
+type Registry struct {
+	keys map[string][]ed25519.PublicKey
+}
+
+// LoadRegistry читает реестр из JSON-файла вида {"<source_service>": ["<base64 public key>", ...]}.
+// Пустой путь дает пустой реестр: все события считаются неподтвержденными.
+func LoadRegistry(path string) (*Registry, error) {
+	r := &Registry{keys: make(map[string][]ed25519.PublicKey)}
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read producer keys: %w", err)
+	}
+	var raw map[string][]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse producer keys: %w", err)
+	}
+	for service, keys := range raw {
+		for i, k := range keys {
+			pub, err := base64.StdEncoding.DecodeString(k)
+			if err != nil || len(pub) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("producer keys: key %d of %s is not a base64 Ed25519 public key", i, service)
+			}
+			r.keys[service] = append(r.keys[service], ed25519.PublicKey(pub))
+		}
+	}
+	return r, nil
+}
+
+// Verify возвращает статус подписи события сервиса service над payload.
+func (r *Registry) Verify(service string, payload []byte, signature string) string {
+	keys := r.keys[service]
+	if signature == "" || len(keys) == 0 {
+		return models.SignatureUnverified
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return models.SignatureInvalid
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, payload, sig) {
+			return models.SignatureVerified
+		}
+	}
+	return models.SignatureInvalid
+}
+
+// Unwrap извлекает событие и подпись из конверта. Если сообщение не конверт,
+// возвращает его целиком и пустую подпись.
+func Unwrap(value []byte) (payload []byte, signature string) {
+	var envelope struct {
+		Event     json.RawMessage `json:"event"`
+		Signature string          `json:"signature"`
+	}
+	if err := json.Unmarshal(value, &envelope); err != nil {
+		return value, ""
+	}
+	event := bytes.TrimSpace(envelope.Event)
+	if len(event) == 0 || event[0] != '{' {
+		return value, ""
+	}
+	return event, envelope.Signature
+}
@@ -0,0 +1,436 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+	"witness/models"
+	"witness/storage"
+)
+
+// Store - хранилище в памяти процесса. Подходит для локального запуска
+// без кластера и для тестов; данные теряются при перезапуске.
+type Store struct {
+	mu     sync.RWMutex
+	events map[string]*models.AuditEvent
+	holds  map[string]*models.LegalHold
+	keys   map[string]*models.APIKey
+	jobs   map[string]*models.ExportJob
+	// checkpoints упорядочены по From.
+	checkpoints []*models.Checkpoint
+	leaves      map[string][]models.CheckpointLeaf
+	prunes      []*models.ChainPrune
+}
+
+var (
+	_ storage.EventStore      = (*Store)(nil)
+	_ storage.LegalHoldStore  = (*Store)(nil)
+	_ storage.Pruner          = (*Store)(nil)
+	_ storage.ChainStore      = (*Store)(nil)
+	_ storage.CheckpointStore = (*Store)(nil)
+	_ storage.APIKeyStore     = (*Store)(nil)
+	_ storage.ExportJobStore  = (*Store)(nil)
+)
+
+// New создает пустое хранилище.
+func New() *Store {
+	return &Store{
+		events: make(map[string]*models.AuditEvent),
+		holds:  make(map[string]*models.LegalHold),
+		keys:   make(map[string]*models.APIKey),
+		jobs:   make(map[string]*models.ExportJob),
+		leaves: make(map[string][]models.CheckpointLeaf),
+	}
+}
+
+func (s *Store) IndexBatch(_ context.Context, events []*models.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, event := range events {
+		e := *event
+		if e.Chain != nil {
+			link := *e.Chain
+			e.Chain = &link
+		}
+		s.events[e.EventID] = &e
+	}
+	return nil
+}
+
+func (s *Store) Search(_ context.Context, q storage.Query) ([]*models.AuditEvent, int64, error) {
+	matched := s.match(q.Filter)
+	sort.Slice(matched, func(i, j int) bool { return storage.Less(matched[i], matched[j]) })
+
+	total := int64(len(matched))
+	if q.Offset >= len(matched) {
+		return []*models.AuditEvent{}, total, nil
+	}
+	end := len(matched)
+	if q.Limit >= 0 && q.Offset+q.Limit < end {
+		end = q.Offset + q.Limit
+	}
+	return matched[q.Offset:end], total, nil
+}
+
+func (s *Store) Scan(ctx context.Context, filter *models.AuditEventFilter, fn func(*models.AuditEvent) error) error {
+	matched := s.match(filter)
+	sort.Slice(matched, func(i, j int) bool { return storage.Less(matched[i], matched[j]) })
+	for _, event := range matched {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) Get(_ context.Context, eventID string) (*models.AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	event, ok := s.events[eventID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	e := *event
+	return &e, nil
+}
+
+func (s *Store) Aggregate(_ context.Context, filter *models.AuditEventFilter, field string, size int) ([]*models.FacetBucket, error) {
+	if err := storage.ValidateFacetField(field); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, event := range s.match(filter) {
+		if value := storage.FieldValue(event, field); value != "" {
+			counts[value]++
+		}
+	}
+
+	buckets := make([]*models.FacetBucket, 0, len(counts))
+	for key, count := range counts {
+		buckets = append(buckets, &models.FacetBucket{Key: key, Count: count})
+	}
+	// Как и terms-агрегация OpenSearch: по убыванию количества, затем по ключу.
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Count != buckets[j].Count {
+			return buckets[i].Count > buckets[j].Count
+		}
+		return buckets[i].Key < buckets[j].Key
+	})
+	if size >= 0 && len(buckets) > size {
+		buckets = buckets[:size]
+	}
+	return buckets, nil
+}
+
+func (s *Store) DeleteEventsBefore(_ context.Context, before time.Time, holds []*models.LegalHold) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var links []*models.ChainLink
+	var deleted int64
+	for id, event := range s.events {
+		if !event.Timestamp.Before(before) || models.IsHeld(event, holds) {
+			continue
+		}
+		delete(s.events, id)
+		links = append(links, event.Chain)
+		deleted++
+	}
+	s.prunes = append(s.prunes, storage.Prunes(links, models.PruneRetention, time.Now())...)
+	return deleted, nil
+}
+
+func (s *Store) ChainHead(_ context.Context, stream string) (*models.ChainLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var head *models.ChainLink
+	for _, event := range s.events {
+		if event.Chain == nil || event.Chain.Stream != stream {
+			continue
+		}
+		if head == nil || event.Chain.Seq > head.Seq {
+			head = event.Chain
+		}
+	}
+	if head == nil {
+		return nil, storage.ErrNotFound
+	}
+	link := *head
+	return &link, nil
+}
+
+func (s *Store) ChainEvent(_ context.Context, stream string, seq int64) (*models.AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, event := range s.events {
+		if event.Chain != nil && event.Chain.Stream == stream && event.Chain.Seq == seq {
+			e := *event
+			return &e, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (s *Store) ScanChain(_ context.Context, from, to time.Time, fn func(*models.AuditEvent) error) error {
+	s.mu.RLock()
+	chained := make([]*models.AuditEvent, 0)
+	for _, event := range s.events {
+		if storage.InChainRange(event, from, to) {
+			e := *event
+			chained = append(chained, &e)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(chained, func(i, j int) bool { return storage.ChainLess(chained[i], chained[j]) })
+	for _, event := range chained {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) ChainPrunes(_ context.Context, stream string) ([]*models.ChainPrune, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prunes := make([]*models.ChainPrune, 0)
+	for _, prune := range s.prunes {
+		if prune.Stream == stream {
+			p := *prune
+			prunes = append(prunes, &p)
+		}
+	}
+	sort.Slice(prunes, func(i, j int) bool { return prunes[i].FirstSeq < prunes[j].FirstSeq })
+	return prunes, nil
+}
+
+func (s *Store) SaveLegalHold(_ context.Context, hold *models.LegalHold) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := *hold
+	s.holds[h.ID] = &h
+	return nil
+}
+
+func (s *Store) GetLegalHold(_ context.Context, id string) (*models.LegalHold, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hold, ok := s.holds[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	h := *hold
+	return &h, nil
+}
+
+func (s *Store) ListLegalHolds(_ context.Context, activeOnly bool) ([]*models.LegalHold, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	holds := make([]*models.LegalHold, 0, len(s.holds))
+	for _, hold := range s.holds {
+		if activeOnly && !hold.IsActive() {
+			continue
+		}
+		h := *hold
+		holds = append(holds, &h)
+	}
+	sort.Slice(holds, func(i, j int) bool { return holds[i].CreatedAt.After(holds[j].CreatedAt) })
+	return holds, nil
+}
+
+func (s *Store) SaveAPIKey(_ context.Context, key *models.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key.ID] = copyAPIKey(key)
+	return nil
+}
+
+func (s *Store) GetAPIKey(_ context.Context, id string) (*models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return copyAPIKey(key), nil
+}
+
+func (s *Store) ListAPIKeys(_ context.Context) ([]*models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]*models.APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, copyAPIKey(key))
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+func (s *Store) TouchAPIKey(_ context.Context, id string, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	key.LastUsedAt = &usedAt
+	return nil
+}
+
+// copyAPIKey копирует ключ вместе со списком областей, чтобы вызывающий не менял хранимый ключ.
+func copyAPIKey(key *models.APIKey) *models.APIKey {
+	k := *key
+	k.Scopes = append([]string(nil), key.Scopes...)
+	return &k
+}
+
+func (s *Store) SaveExportJob(_ context.Context, job *models.ExportJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.ID] = copyExportJob(job)
+	return nil
+}
+
+func (s *Store) GetExportJob(_ context.Context, id string) (*models.ExportJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return copyExportJob(job), nil
+}
+
+func (s *Store) ListExportJobs(_ context.Context, unfinishedOnly bool) ([]*models.ExportJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := make([]*models.ExportJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		if unfinishedOnly && job.IsFinished() {
+			continue
+		}
+		jobs = append(jobs, copyExportJob(job))
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs, nil
+}
+
+// copyExportJob копирует задание вместе со списками, чтобы вызывающий не менял хранимое задание.
+func copyExportJob(job *models.ExportJob) *models.ExportJob {
+	j := *job
+	j.Columns = append([]string(nil), job.Columns...)
+	j.Roles = append([]string(nil), job.Roles...)
+	return &j
+}
+
+func (s *Store) ClaimExportJob(_ context.Context, id, owner string, now, leaseUntil time.Time) (*models.ExportJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	if !storage.Claimable(job, now) {
+		return nil, storage.ErrClaimed
+	}
+	job.Status, job.Owner, job.LeaseUntil = models.ExportRunning, owner, &leaseUntil
+	return copyExportJob(job), nil
+}
+
+func (s *Store) SaveCheckpoint(_ context.Context, cp *models.Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := *cp
+	for i, existing := range s.checkpoints {
+		if existing.ID == c.ID {
+			s.checkpoints[i] = &c
+			return nil
+		}
+	}
+	s.checkpoints = append(s.checkpoints, &c)
+	sort.Slice(s.checkpoints, func(i, j int) bool { return s.checkpoints[i].From.Before(s.checkpoints[j].From) })
+	return nil
+}
+
+func (s *Store) LatestCheckpoint(_ context.Context) (*models.Checkpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var latest *models.Checkpoint
+	for _, cp := range s.checkpoints {
+		if latest == nil || cp.To.After(latest.To) {
+			latest = cp
+		}
+	}
+	if latest == nil {
+		return nil, storage.ErrNotFound
+	}
+	c := *latest
+	return &c, nil
+}
+
+func (s *Store) CheckpointAt(_ context.Context, t time.Time) (*models.Checkpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, cp := range s.checkpoints {
+		if !t.Before(cp.From) && t.Before(cp.To) {
+			c := *cp
+			return &c, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (s *Store) SaveCheckpointLeaves(_ context.Context, checkpointID string, leaves []models.CheckpointLeaf) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.leaves[checkpointID] = append([]models.CheckpointLeaf(nil), leaves...)
+	return nil
+}
+
+func (s *Store) CheckpointLeaves(_ context.Context, checkpointID string) ([]models.CheckpointLeaf, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]models.CheckpointLeaf(nil), s.leaves[checkpointID]...), nil
+}
+
+// match возвращает копии событий, подходящих под фильтр.
+func (s *Store) match(filter *models.AuditEventFilter) []*models.AuditEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched := make([]*models.AuditEvent, 0)
+	for _, event := range s.events {
+		if storage.Match(filter, event) {
+			e := *event
+			matched = append(matched, &e)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,186 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"witness/models"
+	"witness/storage"
+	"witness/storage/memory"
+	"witness/storage/storagetest"
+)
+
+var t0 = time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+func newEvent(id string, ts time.Time, status string) *models.AuditEvent {
+	return &models.AuditEvent{
+		EventID:   id,
+		Timestamp: ts,
+		Status:    status,
+		EventType: "LOGIN",
+		Actor:     models.Actor{ID: "alice", Type: "USER", Name: "Alice Smith"},
+		Entity:    models.Entity{ID: "doc-1", Type: "DOCUMENT", Name: "Quarterly report"},
+		Context:   models.Context{SourceService: "auth"},
+		Details:   map[string]any{"attempt": "1"},
+	}
+}
+
+func TestIndexBatchOverwritesByEventID(t *testing.T) {
+	ctx := context.Background()
+	var store storage.EventStore = memory.New()
+
+	if err := store.IndexBatch(ctx, []*models.AuditEvent{newEvent("e1", t0, "SUCCESS")}); err != nil {
+		t.Fatal(err)
+	}
+	// Повтор с другим временем и статусом заменяет событие, а не добавляет второе.
+	if err := store.IndexBatch(ctx, []*models.AuditEvent{newEvent("e1", t0.Add(time.Hour), "FAILURE")}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, total, err := store.Search(ctx, storage.Query{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(events) != 1 {
+		t.Fatalf("got %d events (total %d), want 1", len(events), total)
+	}
+	got, err := store.Get(ctx, "e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "FAILURE" || !got.Timestamp.Equal(t0.Add(time.Hour)) {
+		t.Errorf("got status %s at %s, want the second write", got.Status, got.Timestamp)
+	}
+}
+
+func TestIndexBatchCopiesEvents(t *testing.T) {
+	ctx := context.Background()
+	var store storage.EventStore = memory.New()
+
+	event := newEvent("e1", t0, "SUCCESS")
+	event.Chain = &models.ChainLink{Stream: "s", Seq: 1}
+	if err := store.IndexBatch(ctx, []*models.AuditEvent{event}); err != nil {
+		t.Fatal(err)
+	}
+	event.Status = "FAILURE"
+	event.Chain.Seq = 2
+
+	got, err := store.Get(ctx, "e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "SUCCESS" || got.Chain.Seq != 1 {
+		t.Errorf("stored event changed with the caller's copy: status %s, seq %d", got.Status, got.Chain.Seq)
+	}
+}
+
+func TestGetUnknown(t *testing.T) {
+	var store storage.EventStore = memory.New()
+	if _, err := store.Get(context.Background(), "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestSearchOrderAndPaging(t *testing.T) {
+	ctx := context.Background()
+	var store storage.EventStore = memory.New()
+
+	// e2 и e3 с одним временем: при равенстве порядок - по убыванию event_id.
+	batch := []*models.AuditEvent{
+		newEvent("e1", t0, "SUCCESS"),
+		newEvent("e2", t0.Add(time.Minute), "SUCCESS"),
+		newEvent("e3", t0.Add(time.Minute), "SUCCESS"),
+		newEvent("e4", t0.Add(2*time.Minute), "SUCCESS"),
+	}
+	if err := store.IndexBatch(ctx, batch); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"e4", "e3", "e2", "e1"}
+	var got []string
+	for offset := 0; offset < len(want); offset += 3 {
+		page, total, err := store.Search(ctx, storage.Query{Limit: 3, Offset: offset})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != int64(len(want)) {
+			t.Fatalf("offset %d: got total %d, want %d", offset, total, len(want))
+		}
+		for _, e := range page {
+			got = append(got, e.EventID)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	page, total, err := store.Search(ctx, storage.Query{Limit: 3, Offset: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 0 || total != int64(len(want)) {
+		t.Errorf("offset past the end: got %d events (total %d)", len(page), total)
+	}
+}
+
+func TestSearchFilter(t *testing.T) {
+	ctx := context.Background()
+	var store storage.EventStore = memory.New()
+
+	failed := newEvent("e2", t0.Add(time.Minute), "FAILURE")
+	failed.Actor.ID = "bob"
+	if err := store.IndexBatch(ctx, []*models.AuditEvent{newEvent("e1", t0, "SUCCESS"), failed}); err != nil {
+		t.Fatal(err)
+	}
+
+	status, actor := "FAILURE", "bob"
+	from := t0.Add(time.Minute)
+	for name, filter := range map[string]*models.AuditEventFilter{
+		"status": {Status: &status},
+		"actor":  {ActorID: &actor},
+		"from":   {From: &from},
+	} {
+		events, total, err := store.Search(ctx, storage.Query{Filter: filter, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 1 || len(events) != 1 || events[0].EventID != "e2" {
+			t.Errorf("%s: got %d events (total %d), want e2", name, len(events), total)
+		}
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	ctx := context.Background()
+	var store storage.EventStore = memory.New()
+
+	batch := []*models.AuditEvent{
+		newEvent("e1", t0, "SUCCESS"),
+		newEvent("e2", t0, "FAILURE"),
+		newEvent("e3", t0, "FAILURE"),
+	}
+	if err := store.IndexBatch(ctx, batch); err != nil {
+		t.Fatal(err)
+	}
+
+	buckets, err := store.Aggregate(ctx, nil, "status", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 2 || buckets[0].Key != "FAILURE" || buckets[0].Count != 2 || buckets[1].Key != "SUCCESS" {
+		t.Errorf("got buckets %+v", buckets)
+	}
+	if _, err := store.Aggregate(ctx, nil, "details.attempt", 10); err == nil {
+		t.Error("aggregation by a field outside FacetFields must fail")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Backend { return memory.New() })
+}
@@ -0,0 +1,836 @@
+// Package postgres - хранилище событий в PostgreSQL для небольших инсталляций.
+// События лежат в таблице, секционированной по месяцам; details хранится в JSONB.
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"witness/models"
+	"witness/storage"
+	"witness/storage/sqlutil"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS audit_events (
+    event_id       text        NOT NULL,
+    ts             timestamptz NOT NULL,
+    status         text        NOT NULL,
+    event_type     text        NOT NULL,
+    actor_id       text        NOT NULL,
+    actor_type     text        NOT NULL,
+    actor_name     text        NOT NULL,
+    entity_id      text        NOT NULL,
+    entity_type    text        NOT NULL,
+    entity_name    text        NOT NULL,
+    source_service text        NOT NULL,
+    access_level   text        NOT NULL,
+    doc            jsonb       NOT NULL,
+    details        jsonb,
+    PRIMARY KEY (event_id, ts)
+) PARTITION BY RANGE (ts);
+
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS name_tokens text[] NOT NULL DEFAULT '{}';
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS signature_status text NOT NULL DEFAULT '';
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS audit_events_ts_idx ON audit_events (ts DESC, event_id DESC);
+CREATE INDEX IF NOT EXISTS audit_events_event_id_idx ON audit_events (event_id);
+CREATE INDEX IF NOT EXISTS audit_events_actor_idx ON audit_events (actor_id, ts DESC);
+CREATE INDEX IF NOT EXISTS audit_events_entity_idx ON audit_events (entity_id, ts DESC);
+CREATE INDEX IF NOT EXISTS audit_events_type_idx ON audit_events (event_type, ts DESC);
+CREATE INDEX IF NOT EXISTS audit_events_tenant_idx ON audit_events (tenant, ts DESC);
+CREATE INDEX IF NOT EXISTS audit_events_details_idx ON audit_events USING gin (details jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS audit_events_name_idx ON audit_events USING gin (name_tokens);
+CREATE INDEX IF NOT EXISTS audit_events_chain_idx ON audit_events ((doc->'chain'->>'stream'), ((doc->'chain'->>'seq')::bigint));
+
+CREATE TABLE IF NOT EXISTS legal_holds (
+    id         text        PRIMARY KEY,
+    status     text        NOT NULL,
+    created_at timestamptz NOT NULL,
+    doc        jsonb       NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id         text        PRIMARY KEY,
+    created_at timestamptz NOT NULL,
+    doc        jsonb       NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id         text        PRIMARY KEY,
+    status     text        NOT NULL,
+    created_at timestamptz NOT NULL,
+    doc        jsonb       NOT NULL
+);
+
+ALTER TABLE export_jobs ADD COLUMN IF NOT EXISTS lease_until timestamptz;
+
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+    id      text        PRIMARY KEY,
+    from_ts timestamptz NOT NULL,
+    to_ts   timestamptz NOT NULL,
+    doc     jsonb       NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_checkpoints_range_idx ON audit_checkpoints (from_ts, to_ts);
+
+CREATE TABLE IF NOT EXISTS audit_checkpoint_leaves (
+    checkpoint_id text   NOT NULL,
+    idx           bigint NOT NULL,
+    event_id      text   NOT NULL,
+    leaf_hash     text   NOT NULL,
+    PRIMARY KEY (checkpoint_id, idx)
+);
+
+CREATE TABLE IF NOT EXISTS audit_chain_prunes (
+    stream    text   NOT NULL,
+    first_seq bigint NOT NULL,
+    last_seq  bigint NOT NULL,
+    doc       jsonb  NOT NULL,
+    PRIMARY KEY (stream, first_seq)
+);
+`
+
+// Store - хранилище событий в PostgreSQL.
+type Store struct {
+	pool *pgxpool.Pool
+
+	// partitions - месяцы, для которых секция уже создана этим процессом.
+	partitions sync.Map
+}
+
+var _ storage.Backend = (*Store)(nil)
+
+// Open подключается к базе по DSN и создает схему, если ее нет.
+func Open(ctx context.Context, dsn string) (*Store, error) {
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres dsn: %w", err)
+	}
+	return open(ctx, cfg, schema)
+}
+
+// OpenSchema подключается к базе по DSN и держит таблицы в отдельной схеме PostgreSQL
+// name, создавая ее при необходимости. Так в одной базе живут независимые хранилища
+// (например, журнал запросов к API рядом с основным).
+func OpenSchema(ctx context.Context, dsn, name string) (*Store, error) {
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid postgres dsn: %w", err)
+	}
+	ident := pgx.Identifier{name}.Sanitize()
+	cfg.ConnConfig.RuntimeParams["search_path"] = ident
+	return open(ctx, cfg, "CREATE SCHEMA IF NOT EXISTS "+ident+";"+schema)
+}
+
+// open подключается к базе и выполняет DDL - инструкции через точку с запятой.
+func open(ctx context.Context, cfg *pgxpool.Config, ddl string) (*Store, error) {
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping postgres: %w", err)
+	}
+	for _, stmt := range strings.Split(ddl, ";") {
+		if strings.TrimSpace(stmt) == "" {
+			continue
+		}
+		if _, err := pool.Exec(ctx, stmt); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("failed to create postgres schema: %w", err)
+		}
+	}
+	return &Store{pool: pool}, nil
+}
+
+// Close закрывает пул соединений.
+func (s *Store) Close() {
+	s.pool.Close()
+}
+
+// ensurePartition создает месячную секцию для времени t, если ее еще нет.
+func (s *Store) ensurePartition(ctx context.Context, t time.Time) error {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	name := fmt.Sprintf("audit_events_y%04dm%02d", start.Year(), start.Month())
+	if _, ok := s.partitions.Load(name); ok {
+		return nil
+	}
+
+	// Имя и границы секции вычисляются из времени, поэтому подстановка безопасна.
+	ddl := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF audit_events FOR VALUES FROM ('%s') TO ('%s')`,
+		name, start.Format(time.RFC3339), start.AddDate(0, 1, 0).Format(time.RFC3339))
+	if _, err := s.pool.Exec(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to create partition %s: %w", name, err)
+	}
+	s.partitions.Store(name, struct{}{})
+	return nil
+}
+
+// IndexBatch сохраняет события. Первичный ключ секционированной таблицы обязан включать ts,
+// поэтому единственность event_id обеспечивается в транзакции: событие с тем же event_id,
+// но другим временем удаляется перед вставкой. Транзакционная advisory-блокировка по event_id
+// не дает двум одновременным записям одного события разминуться; события блокируются в порядке
+// event_id, чтобы встречные пачки не ждали друг друга.
+func (s *Store) IndexBatch(ctx context.Context, events []*models.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	// Стабильная сортировка сохраняет порядок повторов внутри пачки: побеждает последний.
+	events = slices.Clone(events)
+	slices.SortStableFunc(events, func(a, b *models.AuditEvent) int {
+		return strings.Compare(a.EventID, b.EventID)
+	})
+
+	batch := &pgx.Batch{}
+	for _, event := range events {
+		if err := s.ensurePartition(ctx, event.Timestamp); err != nil {
+			return err
+		}
+		row, err := sqlutil.ToRow(event)
+		if err != nil {
+			return err
+		}
+		batch.Queue(`SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, row.EventID)
+		batch.Queue(`DELETE FROM audit_events WHERE event_id = $1 AND ts <> $2`, row.EventID, row.Timestamp)
+		batch.Queue(`
+INSERT INTO audit_events (event_id, ts, status, event_type, actor_id, actor_type, actor_name,
+    entity_id, entity_type, entity_name, source_service, access_level, signature_status, tenant, name_tokens, doc, details)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+ON CONFLICT (event_id, ts) DO UPDATE SET
+    status = EXCLUDED.status, event_type = EXCLUDED.event_type,
+    actor_id = EXCLUDED.actor_id, actor_type = EXCLUDED.actor_type, actor_name = EXCLUDED.actor_name,
+    entity_id = EXCLUDED.entity_id, entity_type = EXCLUDED.entity_type, entity_name = EXCLUDED.entity_name,
+    source_service = EXCLUDED.source_service, access_level = EXCLUDED.access_level,
+    signature_status = EXCLUDED.signature_status, tenant = EXCLUDED.tenant,
+    name_tokens = EXCLUDED.name_tokens, doc = EXCLUDED.doc, details = EXCLUDED.details`,
+			row.EventID, row.Timestamp, row.Status, row.EventType, row.ActorID, row.ActorType, row.ActorName,
+			row.EntityID, row.EntityType, row.EntityName, row.SourceService, row.AccessLevel, row.SignatureStatus, row.Tenant, row.NameTokens,
+			string(row.Doc), string(row.Details))
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to insert events: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) Search(ctx context.Context, q storage.Query) ([]*models.AuditEvent, int64, error) {
+	b := newBuilder(q.Filter)
+	where := b.Where()
+
+	var total int64
+	if err := s.pool.QueryRow(ctx, "SELECT count(*) FROM audit_events"+where, b.Args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	sql := "SELECT doc, details FROM audit_events" + where +
+		" ORDER BY ts DESC, event_id DESC LIMIT " + b.Arg(q.Limit) + " OFFSET " + b.Arg(q.Offset)
+	rows, err := s.pool.Query(ctx, sql, b.Args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*models.AuditEvent, 0, q.Limit)
+	for rows.Next() {
+		var doc, details []byte
+		if err := rows.Scan(&doc, &details); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan event: %w", err)
+		}
+		event, err := sqlutil.FromDoc(doc, details)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read events: %w", err)
+	}
+	return events, total, nil
+}
+
+// scanPageSize - размер страницы при чтении событий курсором.
+const scanPageSize = 1000
+
+// Scan читает события страницами по ключу (ts, event_id), а не по OFFSET: каждая
+// страница - дешевый проход по индексу audit_events_ts_idx.
+func (s *Store) Scan(ctx context.Context, filter *models.AuditEventFilter, fn func(*models.AuditEvent) error) error {
+	var afterTS time.Time
+	var afterID string
+	for first := true; ; first = false {
+		b := newBuilder(filter)
+		if !first {
+			b.Add("(ts, event_id) < (" + b.Arg(afterTS) + ", " + b.Arg(afterID) + ")")
+		}
+		sql := "SELECT ts, event_id, doc, details FROM audit_events" + b.Where() +
+			" ORDER BY ts DESC, event_id DESC LIMIT " + b.Arg(scanPageSize)
+		rows, err := s.pool.Query(ctx, sql, b.Args...)
+		if err != nil {
+			return fmt.Errorf("failed to scan events: %w", err)
+		}
+
+		// Страницу читаем целиком, чтобы не держать соединение, пока fn отдает события клиенту.
+		page := make([]*models.AuditEvent, 0, scanPageSize)
+		for rows.Next() {
+			var doc, details []byte
+			if err := rows.Scan(&afterTS, &afterID, &doc, &details); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan event: %w", err)
+			}
+			event, err := sqlutil.FromDoc(doc, details)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			page = append(page, event)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read events: %w", err)
+		}
+
+		for _, event := range page {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+		if len(page) < scanPageSize {
+			return nil
+		}
+	}
+}
+
+func (s *Store) Get(ctx context.Context, eventID string) (*models.AuditEvent, error) {
+	var doc, details []byte
+	err := s.pool.QueryRow(ctx, "SELECT doc, details FROM audit_events WHERE event_id = $1 LIMIT 1", eventID).Scan(&doc, &details)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+	return sqlutil.FromDoc(doc, details)
+}
+
+func (s *Store) Aggregate(ctx context.Context, filter *models.AuditEventFilter, field string, size int) ([]*models.FacetBucket, error) {
+	if err := storage.ValidateFacetField(field); err != nil {
+		return nil, err
+	}
+	column := sqlutil.Columns[field]
+
+	b := newBuilder(filter)
+	b.Add(column + " <> ''")
+	sql := "SELECT " + column + ", count(*) FROM audit_events" + b.Where() +
+		" GROUP BY " + column + " ORDER BY count(*) DESC, " + column + " LIMIT " + b.Arg(size)
+
+	rows, err := s.pool.Query(ctx, sql, b.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate events: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make([]*models.FacetBucket, 0)
+	for rows.Next() {
+		var bucket models.FacetBucket
+		if err := rows.Scan(&bucket.Key, &bucket.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan facet: %w", err)
+		}
+		buckets = append(buckets, &bucket)
+	}
+	return buckets, rows.Err()
+}
+
+func (s *Store) DeleteEventsBefore(ctx context.Context, before time.Time, holds []*models.LegalHold) (int64, error) {
+	b := sqlutil.NewBuilder(sqlutil.Dollar)
+	b.Add("ts < " + b.Arg(before))
+	b.ExcludeHolds(holds, tsArg)
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, "DELETE FROM audit_events"+b.Where()+" RETURNING doc->'chain'", b.Args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete events: %w", err)
+	}
+	var (
+		links   []*models.ChainLink
+		deleted int64
+	)
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan deleted event: %w", err)
+		}
+		deleted++
+		if doc == nil {
+			continue
+		}
+		var link models.ChainLink
+		if err := json.Unmarshal(doc, &link); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to unmarshal chain link: %w", err)
+		}
+		links = append(links, &link)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to delete events: %w", err)
+	}
+
+	for _, prune := range storage.Prunes(links, models.PruneRetention, time.Now()) {
+		doc, err := json.Marshal(prune)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal chain prune: %w", err)
+		}
+		_, err = tx.Exec(ctx, `
+INSERT INTO audit_chain_prunes (stream, first_seq, last_seq, doc) VALUES ($1, $2, $3, $4)
+ON CONFLICT (stream, first_seq) DO UPDATE SET last_seq = EXCLUDED.last_seq, doc = EXCLUDED.doc`,
+			prune.Stream, prune.FirstSeq, prune.LastSeq, string(doc))
+		if err != nil {
+			return 0, fmt.Errorf("failed to save chain prune: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return deleted, nil
+}
+
+func (s *Store) ChainPrunes(ctx context.Context, stream string) ([]*models.ChainPrune, error) {
+	rows, err := s.pool.Query(ctx,
+		"SELECT doc FROM audit_chain_prunes WHERE stream = $1 ORDER BY first_seq", stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chain prunes: %w", err)
+	}
+	defer rows.Close()
+
+	prunes := make([]*models.ChainPrune, 0)
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, fmt.Errorf("failed to scan chain prune: %w", err)
+		}
+		var prune models.ChainPrune
+		if err := json.Unmarshal(doc, &prune); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal chain prune: %w", err)
+		}
+		prunes = append(prunes, &prune)
+	}
+	return prunes, rows.Err()
+}
+
+// Запросы ChainHead и ChainEvent используют выражения audit_events_chain_idx дословно:
+// doc->'chain'->>'stream' и (doc->'chain'->>'seq')::bigint, иначе планировщик не применит индекс.
+func (s *Store) ChainHead(ctx context.Context, stream string) (*models.ChainLink, error) {
+	var doc []byte
+	err := s.pool.QueryRow(ctx, `
+SELECT doc->'chain' FROM audit_events WHERE doc->'chain'->>'stream' = $1
+ORDER BY (doc->'chain'->>'seq')::bigint DESC LIMIT 1`, stream).Scan(&doc)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain head: %w", err)
+	}
+
+	var link models.ChainLink
+	if err := json.Unmarshal(doc, &link); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chain link: %w", err)
+	}
+	return &link, nil
+}
+
+func (s *Store) ChainEvent(ctx context.Context, stream string, seq int64) (*models.AuditEvent, error) {
+	var doc, details []byte
+	err := s.pool.QueryRow(ctx, `
+SELECT doc, details FROM audit_events
+WHERE doc->'chain'->>'stream' = $1 AND (doc->'chain'->>'seq')::bigint = $2 LIMIT 1`, stream, seq).Scan(&doc, &details)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain event: %w", err)
+	}
+	return sqlutil.FromDoc(doc, details)
+}
+
+func (s *Store) ScanChain(ctx context.Context, from, to time.Time, fn func(*models.AuditEvent) error) error {
+	// timestamptz хранит микросекунды, поэтому в SQL границы расширены,
+	// а точная проверка интервала выполняется по самому событию.
+	rows, err := s.pool.Query(ctx, `
+SELECT doc, details FROM audit_events
+WHERE (doc->'chain'->>'ingested_at')::timestamptz BETWEEN $1 AND $2
+ORDER BY doc->'chain'->>'stream', (doc->'chain'->>'seq')::bigint`,
+		from.Add(-time.Millisecond), to.Add(time.Millisecond))
+	if err != nil {
+		return fmt.Errorf("failed to scan chain: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var doc, details []byte
+		if err := rows.Scan(&doc, &details); err != nil {
+			return fmt.Errorf("failed to scan event: %w", err)
+		}
+		event, err := sqlutil.FromDoc(doc, details)
+		if err != nil {
+			return err
+		}
+		if !storage.InChainRange(event, from, to) {
+			continue
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read chain: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) SaveLegalHold(ctx context.Context, hold *models.LegalHold) error {
+	doc, err := json.Marshal(hold)
+	if err != nil {
+		return fmt.Errorf("failed to marshal legal hold: %w", err)
+	}
+	_, err = s.pool.Exec(ctx, `
+INSERT INTO legal_holds (id, status, created_at, doc) VALUES ($1, $2, $3, $4)
+ON CONFLICT (id) DO UPDATE SET status = EXCLUDED.status, doc = EXCLUDED.doc`,
+		hold.ID, hold.Status, hold.CreatedAt, string(doc))
+	if err != nil {
+		return fmt.Errorf("failed to save legal hold: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetLegalHold(ctx context.Context, id string) (*models.LegalHold, error) {
+	var doc []byte
+	err := s.pool.QueryRow(ctx, "SELECT doc FROM legal_holds WHERE id = $1", id).Scan(&doc)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get legal hold: %w", err)
+	}
+
+	var hold models.LegalHold
+	if err := json.Unmarshal(doc, &hold); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal legal hold: %w", err)
+	}
+	return &hold, nil
+}
+
+func (s *Store) ListLegalHolds(ctx context.Context, activeOnly bool) ([]*models.LegalHold, error) {
+	sql := "SELECT doc FROM legal_holds"
+	args := []any{}
+	if activeOnly {
+		sql += " WHERE status = $1"
+		args = append(args, models.LegalHoldActive)
+	}
+	sql += " ORDER BY created_at DESC"
+
+	rows, err := s.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+	defer rows.Close()
+
+	holds := make([]*models.LegalHold, 0)
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, fmt.Errorf("failed to scan legal hold: %w", err)
+		}
+		var hold models.LegalHold
+		if err := json.Unmarshal(doc, &hold); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal legal hold: %w", err)
+		}
+		holds = append(holds, &hold)
+	}
+	return holds, rows.Err()
+}
+
+func (s *Store) SaveAPIKey(ctx context.Context, key *models.APIKey) error {
+	doc, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal api key: %w", err)
+	}
+	_, err = s.pool.Exec(ctx, `
+INSERT INTO api_keys (id, created_at, doc) VALUES ($1, $2, $3)
+ON CONFLICT (id) DO UPDATE SET doc = EXCLUDED.doc`,
+		key.ID, key.CreatedAt, string(doc))
+	if err != nil {
+		return fmt.Errorf("failed to save api key: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetAPIKey(ctx context.Context, id string) (*models.APIKey, error) {
+	var doc []byte
+	err := s.pool.QueryRow(ctx, "SELECT doc FROM api_keys WHERE id = $1", id).Scan(&doc)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	var key models.APIKey
+	if err := json.Unmarshal(doc, &key); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal api key: %w", err)
+	}
+	return &key, nil
+}
+
+func (s *Store) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	rows, err := s.pool.Query(ctx, "SELECT doc FROM api_keys ORDER BY created_at DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*models.APIKey, 0)
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		var key models.APIKey
+		if err := json.Unmarshal(doc, &key); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal api key: %w", err)
+		}
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *Store) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	// Меняем только last_used_at, чтобы не затереть одновременный отзыв ключа.
+	tag, err := s.pool.Exec(ctx, "UPDATE api_keys SET doc = jsonb_set(doc, '{last_used_at}', to_jsonb($2::text)) WHERE id = $1",
+		id, usedAt.Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("failed to touch api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) SaveExportJob(ctx context.Context, job *models.ExportJob) error {
+	doc, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal export job: %w", err)
+	}
+	_, err = s.pool.Exec(ctx, `
+INSERT INTO export_jobs (id, status, created_at, lease_until, doc) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (id) DO UPDATE SET status = EXCLUDED.status, lease_until = EXCLUDED.lease_until, doc = EXCLUDED.doc`,
+		job.ID, job.Status, job.CreatedAt, job.LeaseUntil, string(doc))
+	if err != nil {
+		return fmt.Errorf("failed to save export job: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) ClaimExportJob(ctx context.Context, id, owner string, now, leaseUntil time.Time) (*models.ExportJob, error) {
+	job, err := s.GetExportJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !storage.Claimable(job, now) {
+		return nil, storage.ErrClaimed
+	}
+	job.Status, job.Owner, job.LeaseUntil = models.ExportRunning, owner, &leaseUntil
+	doc, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal export job: %w", err)
+	}
+
+	// Условие повторяет storage.Claimable: между чтением и записью задание мог забрать другой экземпляр.
+	tag, err := s.pool.Exec(ctx, `
+UPDATE export_jobs SET status = $1, lease_until = $2, doc = $3
+WHERE id = $4 AND (status = $5 OR (status = $1 AND (lease_until IS NULL OR lease_until <= $6)))`,
+		models.ExportRunning, leaseUntil, string(doc), id, models.ExportPending, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim export job: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, storage.ErrClaimed
+	}
+	return job, nil
+}
+
+func (s *Store) GetExportJob(ctx context.Context, id string) (*models.ExportJob, error) {
+	var doc []byte
+	err := s.pool.QueryRow(ctx, "SELECT doc FROM export_jobs WHERE id = $1", id).Scan(&doc)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export job: %w", err)
+	}
+
+	var job models.ExportJob
+	if err := json.Unmarshal(doc, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal export job: %w", err)
+	}
+	return &job, nil
+}
+
+func (s *Store) ListExportJobs(ctx context.Context, unfinishedOnly bool) ([]*models.ExportJob, error) {
+	query := "SELECT doc FROM export_jobs"
+	args := []any{}
+	if unfinishedOnly {
+		query += " WHERE status IN ($1, $2)"
+		args = append(args, models.ExportPending, models.ExportRunning)
+	}
+	query += " ORDER BY created_at DESC"
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list export jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]*models.ExportJob, 0)
+	for rows.Next() {
+		var doc []byte
+		if err := rows.Scan(&doc); err != nil {
+			return nil, fmt.Errorf("failed to scan export job: %w", err)
+		}
+		var job models.ExportJob
+		if err := json.Unmarshal(doc, &job); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal export job: %w", err)
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, rows.Err()
+}
+
+func (s *Store) SaveCheckpoint(ctx context.Context, cp *models.Checkpoint) error {
+	doc, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+	_, err = s.pool.Exec(ctx, `
+INSERT INTO audit_checkpoints (id, from_ts, to_ts, doc) VALUES ($1, $2, $3, $4)
+ON CONFLICT (id) DO UPDATE SET from_ts = EXCLUDED.from_ts, to_ts = EXCLUDED.to_ts, doc = EXCLUDED.doc`,
+		cp.ID, cp.From, cp.To, string(doc))
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) LatestCheckpoint(ctx context.Context) (*models.Checkpoint, error) {
+	return s.checkpoint(ctx, "SELECT doc FROM audit_checkpoints ORDER BY to_ts DESC LIMIT 1")
+}
+
+func (s *Store) CheckpointAt(ctx context.Context, t time.Time) (*models.Checkpoint, error) {
+	return s.checkpoint(ctx, "SELECT doc FROM audit_checkpoints WHERE from_ts <= $1 AND to_ts > $1 LIMIT 1", t)
+}
+
+func (s *Store) SaveCheckpointLeaves(ctx context.Context, checkpointID string, leaves []models.CheckpointLeaf) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM audit_checkpoint_leaves WHERE checkpoint_id = $1", checkpointID); err != nil {
+		return fmt.Errorf("failed to delete checkpoint leaves: %w", err)
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"audit_checkpoint_leaves"},
+		[]string{"checkpoint_id", "idx", "event_id", "leaf_hash"},
+		pgx.CopyFromSlice(len(leaves), func(i int) ([]any, error) {
+			return []any{checkpointID, int64(i), leaves[i].EventID, leaves[i].LeafHash}, nil
+		}))
+	if err != nil {
+		return fmt.Errorf("failed to insert checkpoint leaves: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) CheckpointLeaves(ctx context.Context, checkpointID string) ([]models.CheckpointLeaf, error) {
+	rows, err := s.pool.Query(ctx,
+		"SELECT event_id, leaf_hash FROM audit_checkpoint_leaves WHERE checkpoint_id = $1 ORDER BY idx", checkpointID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query checkpoint leaves: %w", err)
+	}
+	defer rows.Close()
+
+	var leaves []models.CheckpointLeaf
+	for rows.Next() {
+		var leaf models.CheckpointLeaf
+		if err := rows.Scan(&leaf.EventID, &leaf.LeafHash); err != nil {
+			return nil, fmt.Errorf("failed to scan checkpoint leaf: %w", err)
+		}
+		leaves = append(leaves, leaf)
+	}
+	return leaves, rows.Err()
+}
+
+// checkpoint читает одну контрольную точку запросом, возвращающим doc.
+func (s *Store) checkpoint(ctx context.Context, sql string, args ...any) (*models.Checkpoint, error) {
+	var doc []byte
+	err := s.pool.QueryRow(ctx, sql, args...).Scan(&doc)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+
+	var cp models.Checkpoint
+	if err := json.Unmarshal(doc, &cp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// newBuilder строит условия фильтра. Фильтр по имени - вхождение всех слов
+// запроса в name_tokens, что совпадает с семантикой storage.Match.
+func newBuilder(filter *models.AuditEventFilter) *sqlutil.Builder {
+	b := sqlutil.NewBuilder(sqlutil.Dollar)
+	b.Filter(filter, tsArg)
+	if filter != nil && filter.Name != nil {
+		b.Add("name_tokens @> " + b.Arg(storage.Tokenize(*filter.Name)) + "::text[]")
+	}
+	return b
+}
+
+// tsArg передает время в pgx как есть: драйвер сам приводит его к timestamptz.
+func tsArg(t time.Time) any {
+	return t
+}
@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"witness/storage"
+	"witness/storage/storagetest"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TestConformance работает с базой из WITNESS_TEST_POSTGRES_DSN: каждый подтест получает
+// свою схему, которая удаляется после него.
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv("WITNESS_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("WITNESS_TEST_POSTGRES_DSN is not set")
+	}
+	storagetest.Run(t, func(t *testing.T) storage.Backend {
+		ctx := context.Background()
+		name := fmt.Sprintf("witness_test_%s", strings.ToLower(strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())))
+		store, err := OpenSchema(ctx, dsn, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			defer store.Close()
+			if _, err := store.pool.Exec(ctx, "DROP SCHEMA "+pgx.Identifier{name}.Sanitize()+" CASCADE"); err != nil {
+				t.Errorf("failed to drop schema %s: %v", name, err)
+			}
+		})
+		return store
+	})
+}
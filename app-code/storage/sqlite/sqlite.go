@@ -0,0 +1,868 @@
+// Package sqlite - встроенное хранилище событий на SQLite (чистый Go, без CGO)
+// для локальной разработки и установок на одном узле. Поиск по именам актора
+// и сущности идет через полнотекстовый индекс FTS5.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"witness/models"
+	"witness/storage"
+	"witness/storage/sqlutil"
+
+	_ "modernc.org/sqlite"
+)
+
+// Время хранится в наносекундах Unix, чтобы не терять точность и сортировать как число.
+const schema = `
+CREATE TABLE IF NOT EXISTS audit_events (
+    event_id       TEXT    PRIMARY KEY,
+    ts             INTEGER NOT NULL,
+    status         TEXT    NOT NULL,
+    event_type     TEXT    NOT NULL,
+    actor_id       TEXT    NOT NULL,
+    actor_type     TEXT    NOT NULL,
+    actor_name     TEXT    NOT NULL,
+    entity_id      TEXT    NOT NULL,
+    entity_type    TEXT    NOT NULL,
+    entity_name    TEXT    NOT NULL,
+    source_service TEXT    NOT NULL,
+    access_level   TEXT    NOT NULL,
+    doc            TEXT    NOT NULL,
+    details        TEXT
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_ts_idx ON audit_events (ts DESC, event_id DESC);
+CREATE INDEX IF NOT EXISTS audit_events_actor_idx ON audit_events (actor_id, ts DESC);
+CREATE INDEX IF NOT EXISTS audit_events_entity_idx ON audit_events (entity_id, ts DESC);
+CREATE INDEX IF NOT EXISTS audit_events_type_idx ON audit_events (event_type, ts DESC);
+CREATE INDEX IF NOT EXISTS audit_events_chain_idx ON audit_events
+    (json_extract(doc, '$.chain.stream'), json_extract(doc, '$.chain.seq'));
+
+CREATE VIRTUAL TABLE IF NOT EXISTS audit_events_fts USING fts5 (
+    actor_name, entity_name,
+    content = 'audit_events', content_rowid = 'rowid',
+    tokenize = 'unicode61 remove_diacritics 0'
+);
+
+CREATE TRIGGER IF NOT EXISTS audit_events_fts_insert AFTER INSERT ON audit_events BEGIN
+    INSERT INTO audit_events_fts (rowid, actor_name, entity_name)
+    VALUES (new.rowid, new.actor_name, new.entity_name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_events_fts_delete AFTER DELETE ON audit_events BEGIN
+    INSERT INTO audit_events_fts (audit_events_fts, rowid, actor_name, entity_name)
+    VALUES ('delete', old.rowid, old.actor_name, old.entity_name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_events_fts_update AFTER UPDATE ON audit_events BEGIN
+    INSERT INTO audit_events_fts (audit_events_fts, rowid, actor_name, entity_name)
+    VALUES ('delete', old.rowid, old.actor_name, old.entity_name);
+    INSERT INTO audit_events_fts (rowid, actor_name, entity_name)
+    VALUES (new.rowid, new.actor_name, new.entity_name);
+END;
+
+CREATE TABLE IF NOT EXISTS legal_holds (
+    id         TEXT    PRIMARY KEY,
+    status     TEXT    NOT NULL,
+    created_at INTEGER NOT NULL,
+    doc        TEXT    NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id         TEXT    PRIMARY KEY,
+    created_at INTEGER NOT NULL,
+    doc        TEXT    NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id         TEXT    PRIMARY KEY,
+    status     TEXT    NOT NULL,
+    created_at INTEGER NOT NULL,
+    doc        TEXT    NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+    id      TEXT    PRIMARY KEY,
+    from_ts INTEGER NOT NULL,
+    to_ts   INTEGER NOT NULL,
+    doc     TEXT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_checkpoints_range_idx ON audit_checkpoints (from_ts, to_ts);
+
+CREATE TABLE IF NOT EXISTS audit_checkpoint_leaves (
+    checkpoint_id TEXT    NOT NULL,
+    idx           INTEGER NOT NULL,
+    event_id      TEXT    NOT NULL,
+    leaf_hash     TEXT    NOT NULL,
+    PRIMARY KEY (checkpoint_id, idx)
+);
+
+CREATE TABLE IF NOT EXISTS audit_chain_prunes (
+    stream    TEXT    NOT NULL,
+    first_seq INTEGER NOT NULL,
+    last_seq  INTEGER NOT NULL,
+    doc       TEXT    NOT NULL,
+    PRIMARY KEY (stream, first_seq)
+);
+`
+
+// Store - хранилище событий в файле SQLite.
+type Store struct {
+	db *sql.DB
+}
+
+var _ storage.Backend = (*Store)(nil)
+
+// Open открывает (или создает) базу по пути к файлу и готовит схему.
+func Open(ctx context.Context, path string) (*Store, error) {
+	dsn := "file:" + path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	// SQLite допускает одного писателя; одно соединение избавляет от SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+	}
+	if err := addColumns(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// addedColumns - колонки audit_events, появившиеся после первой версии схемы.
+var addedColumns = []struct{ table, name, definition string }{
+	{"audit_events", "signature_status", "TEXT NOT NULL DEFAULT ''"},
+	{"audit_events", "tenant", "TEXT NOT NULL DEFAULT ''"},
+	{"export_jobs", "lease_until", "INTEGER"},
+}
+
+// addColumns добавляет недостающие колонки (и индексы по ним) в существующую базу:
+// в SQLite нет ALTER TABLE ... ADD COLUMN IF NOT EXISTS.
+func addColumns(ctx context.Context, db *sql.DB) error {
+	for _, col := range addedColumns {
+		var n int
+		err := db.QueryRowContext(ctx,
+			"SELECT count(*) FROM pragma_table_info(?1) WHERE name = ?2", col.table, col.name).Scan(&n)
+		if err != nil {
+			return fmt.Errorf("failed to inspect sqlite schema: %w", err)
+		}
+		if n > 0 {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, "ALTER TABLE "+col.table+" ADD COLUMN "+col.name+" "+col.definition); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", col.name, err)
+		}
+	}
+	// Индексы по добавленным колонкам создаются здесь: в старой базе схема выполняется до их появления.
+	if _, err := db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS audit_events_tenant_idx ON audit_events (tenant, ts DESC)"); err != nil {
+		return fmt.Errorf("failed to create tenant index: %w", err)
+	}
+	return nil
+}
+
+// Close закрывает базу.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) IndexBatch(ctx context.Context, events []*models.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+INSERT INTO audit_events (event_id, ts, status, event_type, actor_id, actor_type, actor_name,
+    entity_id, entity_type, entity_name, source_service, access_level, signature_status, tenant, doc, details)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14, ?15, ?16)
+ON CONFLICT (event_id) DO UPDATE SET
+    ts = excluded.ts, status = excluded.status, event_type = excluded.event_type,
+    actor_id = excluded.actor_id, actor_type = excluded.actor_type, actor_name = excluded.actor_name,
+    entity_id = excluded.entity_id, entity_type = excluded.entity_type, entity_name = excluded.entity_name,
+    source_service = excluded.source_service, access_level = excluded.access_level,
+    signature_status = excluded.signature_status, tenant = excluded.tenant,
+    doc = excluded.doc, details = excluded.details`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, event := range events {
+		row, err := sqlutil.ToRow(event)
+		if err != nil {
+			return err
+		}
+		_, err = stmt.ExecContext(ctx,
+			row.EventID, row.Timestamp.UnixNano(), row.Status, row.EventType, row.ActorID, row.ActorType, row.ActorName,
+			row.EntityID, row.EntityType, row.EntityName, row.SourceService, row.AccessLevel, row.SignatureStatus, row.Tenant,
+			string(row.Doc), string(row.Details))
+		if err != nil {
+			return fmt.Errorf("failed to insert event %s: %w", row.EventID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit events: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) Search(ctx context.Context, q storage.Query) ([]*models.AuditEvent, int64, error) {
+	b := newBuilder(q.Filter)
+	where := b.Where()
+
+	var total int64
+	if err := s.db.QueryRowContext(ctx, "SELECT count(*) FROM audit_events"+where, b.Args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	query := "SELECT doc, details FROM audit_events" + where +
+		" ORDER BY ts DESC, event_id DESC LIMIT " + b.Arg(q.Limit) + " OFFSET " + b.Arg(q.Offset)
+	rows, err := s.db.QueryContext(ctx, query, b.Args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*models.AuditEvent, 0, q.Limit)
+	for rows.Next() {
+		var doc string
+		var details sql.NullString
+		if err := rows.Scan(&doc, &details); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan event: %w", err)
+		}
+		event, err := sqlutil.FromDoc([]byte(doc), []byte(details.String))
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read events: %w", err)
+	}
+	return events, total, nil
+}
+
+// scanPageSize - размер страницы при чтении событий курсором.
+const scanPageSize = 1000
+
+// Scan читает события страницами по ключу (ts, event_id), а не по OFFSET. Между
+// страницами соединение свободно, поэтому долгая выгрузка не блокирует запись.
+func (s *Store) Scan(ctx context.Context, filter *models.AuditEventFilter, fn func(*models.AuditEvent) error) error {
+	var afterTS int64
+	var afterID string
+	for first := true; ; first = false {
+		b := newBuilder(filter)
+		if !first {
+			b.Add("(ts, event_id) < (" + b.Arg(afterTS) + ", " + b.Arg(afterID) + ")")
+		}
+		query := "SELECT ts, event_id, doc, details FROM audit_events" + b.Where() +
+			" ORDER BY ts DESC, event_id DESC LIMIT " + b.Arg(scanPageSize)
+		rows, err := s.db.QueryContext(ctx, query, b.Args...)
+		if err != nil {
+			return fmt.Errorf("failed to scan events: %w", err)
+		}
+
+		page := make([]*models.AuditEvent, 0, scanPageSize)
+		for rows.Next() {
+			var doc string
+			var details sql.NullString
+			if err := rows.Scan(&afterTS, &afterID, &doc, &details); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan event: %w", err)
+			}
+			event, err := sqlutil.FromDoc([]byte(doc), []byte(details.String))
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			page = append(page, event)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read events: %w", err)
+		}
+
+		for _, event := range page {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+		if len(page) < scanPageSize {
+			return nil
+		}
+	}
+}
+
+func (s *Store) Get(ctx context.Context, eventID string) (*models.AuditEvent, error) {
+	var doc string
+	var details sql.NullString
+	err := s.db.QueryRowContext(ctx, "SELECT doc, details FROM audit_events WHERE event_id = ?1", eventID).Scan(&doc, &details)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event: %w", err)
+	}
+	return sqlutil.FromDoc([]byte(doc), []byte(details.String))
+}
+
+func (s *Store) Aggregate(ctx context.Context, filter *models.AuditEventFilter, field string, size int) ([]*models.FacetBucket, error) {
+	if err := storage.ValidateFacetField(field); err != nil {
+		return nil, err
+	}
+	column := sqlutil.Columns[field]
+
+	b := newBuilder(filter)
+	b.Add(column + " <> ''")
+	query := "SELECT " + column + ", count(*) FROM audit_events" + b.Where() +
+		" GROUP BY " + column + " ORDER BY count(*) DESC, " + column + " LIMIT " + b.Arg(size)
+
+	rows, err := s.db.QueryContext(ctx, query, b.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate events: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make([]*models.FacetBucket, 0)
+	for rows.Next() {
+		var bucket models.FacetBucket
+		if err := rows.Scan(&bucket.Key, &bucket.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan facet: %w", err)
+		}
+		buckets = append(buckets, &bucket)
+	}
+	return buckets, rows.Err()
+}
+
+func (s *Store) DeleteEventsBefore(ctx context.Context, before time.Time, holds []*models.LegalHold) (int64, error) {
+	b := sqlutil.NewBuilder(sqlutil.Question)
+	b.Add("ts < " + b.Arg(before.UnixNano()))
+	b.ExcludeHolds(holds, tsArg)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		"DELETE FROM audit_events"+b.Where()+" RETURNING json_extract(doc, '$.chain')", b.Args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete events: %w", err)
+	}
+	var (
+		links   []*models.ChainLink
+		deleted int64
+	)
+	for rows.Next() {
+		var doc sql.NullString
+		if err := rows.Scan(&doc); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan deleted event: %w", err)
+		}
+		deleted++
+		if !doc.Valid {
+			continue
+		}
+		var link models.ChainLink
+		if err := json.Unmarshal([]byte(doc.String), &link); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to unmarshal chain link: %w", err)
+		}
+		links = append(links, &link)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to delete events: %w", err)
+	}
+
+	for _, prune := range storage.Prunes(links, models.PruneRetention, time.Now()) {
+		doc, err := json.Marshal(prune)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal chain prune: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `
+INSERT INTO audit_chain_prunes (stream, first_seq, last_seq, doc) VALUES (?1, ?2, ?3, ?4)
+ON CONFLICT (stream, first_seq) DO UPDATE SET last_seq = excluded.last_seq, doc = excluded.doc`,
+			prune.Stream, prune.FirstSeq, prune.LastSeq, string(doc))
+		if err != nil {
+			return 0, fmt.Errorf("failed to save chain prune: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return deleted, nil
+}
+
+func (s *Store) ChainPrunes(ctx context.Context, stream string) ([]*models.ChainPrune, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT doc FROM audit_chain_prunes WHERE stream = ?1 ORDER BY first_seq", stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chain prunes: %w", err)
+	}
+	defer rows.Close()
+
+	prunes := make([]*models.ChainPrune, 0)
+	for rows.Next() {
+		var doc string
+		if err := rows.Scan(&doc); err != nil {
+			return nil, fmt.Errorf("failed to scan chain prune: %w", err)
+		}
+		var prune models.ChainPrune
+		if err := json.Unmarshal([]byte(doc), &prune); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal chain prune: %w", err)
+		}
+		prunes = append(prunes, &prune)
+	}
+	return prunes, rows.Err()
+}
+
+func (s *Store) ChainHead(ctx context.Context, stream string) (*models.ChainLink, error) {
+	var doc string
+	err := s.db.QueryRowContext(ctx, `
+SELECT json_extract(doc, '$.chain') FROM audit_events WHERE json_extract(doc, '$.chain.stream') = ?1
+ORDER BY json_extract(doc, '$.chain.seq') DESC LIMIT 1`, stream).Scan(&doc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain head: %w", err)
+	}
+
+	var link models.ChainLink
+	if err := json.Unmarshal([]byte(doc), &link); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chain link: %w", err)
+	}
+	return &link, nil
+}
+
+func (s *Store) ChainEvent(ctx context.Context, stream string, seq int64) (*models.AuditEvent, error) {
+	var doc string
+	var details sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+SELECT doc, details FROM audit_events
+WHERE json_extract(doc, '$.chain.stream') = ?1 AND json_extract(doc, '$.chain.seq') = ?2`, stream, seq).Scan(&doc, &details)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain event: %w", err)
+	}
+	return sqlutil.FromDoc([]byte(doc), []byte(details.String))
+}
+
+// chainPageSize - размер страницы при обходе цепочек.
+const chainPageSize = 1000
+
+func (s *Store) ScanChain(ctx context.Context, from, to time.Time, fn func(*models.AuditEvent) error) error {
+	// Читаем страницами и закрываем курсор до вызова fn: соединение с базой одно,
+	// и fn может само обращаться к хранилищу.
+	var lastStream string
+	var lastSeq int64 = -1
+	for {
+		page, err := s.chainPage(ctx, from, to, lastStream, lastSeq)
+		if err != nil {
+			return err
+		}
+		for _, event := range page {
+			if !storage.InChainRange(event, from, to) {
+				continue
+			}
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+		if len(page) < chainPageSize {
+			return nil
+		}
+		last := page[len(page)-1]
+		lastStream, lastSeq = last.Chain.Stream, last.Chain.Seq
+	}
+}
+
+// chainPage читает следующую страницу событий цепочек после (stream, seq).
+// unixepoch дает время с точностью до миллисекунд, поэтому границы расширены.
+func (s *Store) chainPage(ctx context.Context, from, to time.Time, stream string, seq int64) ([]*models.AuditEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT doc, details FROM audit_events
+WHERE unixepoch(json_extract(doc, '$.chain.ingested_at'), 'subsec') BETWEEN ?1 AND ?2
+  AND (json_extract(doc, '$.chain.stream'), json_extract(doc, '$.chain.seq')) > (?3, ?4)
+ORDER BY json_extract(doc, '$.chain.stream'), json_extract(doc, '$.chain.seq')
+LIMIT ?5`,
+		from.Unix()-1, to.Unix()+1, stream, seq, chainPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan chain: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*models.AuditEvent, 0, chainPageSize)
+	for rows.Next() {
+		var doc string
+		var details sql.NullString
+		if err := rows.Scan(&doc, &details); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		event, err := sqlutil.FromDoc([]byte(doc), []byte(details.String))
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read chain: %w", err)
+	}
+	return events, nil
+}
+
+func (s *Store) SaveLegalHold(ctx context.Context, hold *models.LegalHold) error {
+	doc, err := json.Marshal(hold)
+	if err != nil {
+		return fmt.Errorf("failed to marshal legal hold: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO legal_holds (id, status, created_at, doc) VALUES (?1, ?2, ?3, ?4)
+ON CONFLICT (id) DO UPDATE SET status = excluded.status, doc = excluded.doc`,
+		hold.ID, hold.Status, hold.CreatedAt.UnixNano(), string(doc))
+	if err != nil {
+		return fmt.Errorf("failed to save legal hold: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetLegalHold(ctx context.Context, id string) (*models.LegalHold, error) {
+	var doc string
+	err := s.db.QueryRowContext(ctx, "SELECT doc FROM legal_holds WHERE id = ?1", id).Scan(&doc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get legal hold: %w", err)
+	}
+
+	var hold models.LegalHold
+	if err := json.Unmarshal([]byte(doc), &hold); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal legal hold: %w", err)
+	}
+	return &hold, nil
+}
+
+func (s *Store) ListLegalHolds(ctx context.Context, activeOnly bool) ([]*models.LegalHold, error) {
+	query := "SELECT doc FROM legal_holds"
+	args := []any{}
+	if activeOnly {
+		query += " WHERE status = ?1"
+		args = append(args, models.LegalHoldActive)
+	}
+	query += " ORDER BY created_at DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+	defer rows.Close()
+
+	holds := make([]*models.LegalHold, 0)
+	for rows.Next() {
+		var doc string
+		if err := rows.Scan(&doc); err != nil {
+			return nil, fmt.Errorf("failed to scan legal hold: %w", err)
+		}
+		var hold models.LegalHold
+		if err := json.Unmarshal([]byte(doc), &hold); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal legal hold: %w", err)
+		}
+		holds = append(holds, &hold)
+	}
+	return holds, rows.Err()
+}
+
+func (s *Store) SaveAPIKey(ctx context.Context, key *models.APIKey) error {
+	doc, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal api key: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO api_keys (id, created_at, doc) VALUES (?1, ?2, ?3)
+ON CONFLICT (id) DO UPDATE SET doc = excluded.doc`,
+		key.ID, key.CreatedAt.UnixNano(), string(doc))
+	if err != nil {
+		return fmt.Errorf("failed to save api key: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetAPIKey(ctx context.Context, id string) (*models.APIKey, error) {
+	var doc string
+	err := s.db.QueryRowContext(ctx, "SELECT doc FROM api_keys WHERE id = ?1", id).Scan(&doc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	var key models.APIKey
+	if err := json.Unmarshal([]byte(doc), &key); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal api key: %w", err)
+	}
+	return &key, nil
+}
+
+func (s *Store) ListAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT doc FROM api_keys ORDER BY created_at DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*models.APIKey, 0)
+	for rows.Next() {
+		var doc string
+		if err := rows.Scan(&doc); err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		var key models.APIKey
+		if err := json.Unmarshal([]byte(doc), &key); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal api key: %w", err)
+		}
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *Store) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	// Меняем только last_used_at, чтобы не затереть одновременный отзыв ключа.
+	res, err := s.db.ExecContext(ctx, "UPDATE api_keys SET doc = json_set(doc, '$.last_used_at', ?2) WHERE id = ?1",
+		id, usedAt.Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("failed to touch api key: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) SaveExportJob(ctx context.Context, job *models.ExportJob) error {
+	doc, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal export job: %w", err)
+	}
+	var lease any
+	if job.LeaseUntil != nil {
+		lease = job.LeaseUntil.UnixNano()
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO export_jobs (id, status, created_at, lease_until, doc) VALUES (?1, ?2, ?3, ?4, ?5)
+ON CONFLICT (id) DO UPDATE SET status = excluded.status, lease_until = excluded.lease_until, doc = excluded.doc`,
+		job.ID, job.Status, job.CreatedAt.UnixNano(), lease, string(doc))
+	if err != nil {
+		return fmt.Errorf("failed to save export job: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) ClaimExportJob(ctx context.Context, id, owner string, now, leaseUntil time.Time) (*models.ExportJob, error) {
+	job, err := s.GetExportJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !storage.Claimable(job, now) {
+		return nil, storage.ErrClaimed
+	}
+	job.Status, job.Owner, job.LeaseUntil = models.ExportRunning, owner, &leaseUntil
+	doc, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal export job: %w", err)
+	}
+
+	// Условие повторяет storage.Claimable: между чтением и записью задание мог забрать другой экземпляр.
+	res, err := s.db.ExecContext(ctx, `
+UPDATE export_jobs SET status = ?1, lease_until = ?2, doc = ?3
+WHERE id = ?4 AND (status = ?5 OR (status = ?1 AND (lease_until IS NULL OR lease_until <= ?6)))`,
+		models.ExportRunning, leaseUntil.UnixNano(), string(doc), id, models.ExportPending, now.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim export job: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, storage.ErrClaimed
+	}
+	return job, nil
+}
+
+func (s *Store) GetExportJob(ctx context.Context, id string) (*models.ExportJob, error) {
+	var doc string
+	err := s.db.QueryRowContext(ctx, "SELECT doc FROM export_jobs WHERE id = ?1", id).Scan(&doc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export job: %w", err)
+	}
+
+	var job models.ExportJob
+	if err := json.Unmarshal([]byte(doc), &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal export job: %w", err)
+	}
+	return &job, nil
+}
+
+func (s *Store) ListExportJobs(ctx context.Context, unfinishedOnly bool) ([]*models.ExportJob, error) {
+	query := "SELECT doc FROM export_jobs"
+	args := []any{}
+	if unfinishedOnly {
+		query += " WHERE status IN (?1, ?2)"
+		args = append(args, models.ExportPending, models.ExportRunning)
+	}
+	query += " ORDER BY created_at DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list export jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]*models.ExportJob, 0)
+	for rows.Next() {
+		var doc string
+		if err := rows.Scan(&doc); err != nil {
+			return nil, fmt.Errorf("failed to scan export job: %w", err)
+		}
+		var job models.ExportJob
+		if err := json.Unmarshal([]byte(doc), &job); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal export job: %w", err)
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, rows.Err()
+}
+
+func (s *Store) SaveCheckpoint(ctx context.Context, cp *models.Checkpoint) error {
+	doc, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO audit_checkpoints (id, from_ts, to_ts, doc) VALUES (?1, ?2, ?3, ?4)
+ON CONFLICT (id) DO UPDATE SET from_ts = excluded.from_ts, to_ts = excluded.to_ts, doc = excluded.doc`,
+		cp.ID, cp.From.UnixNano(), cp.To.UnixNano(), string(doc))
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) LatestCheckpoint(ctx context.Context) (*models.Checkpoint, error) {
+	return s.checkpoint(ctx, "SELECT doc FROM audit_checkpoints ORDER BY to_ts DESC LIMIT 1")
+}
+
+func (s *Store) CheckpointAt(ctx context.Context, t time.Time) (*models.Checkpoint, error) {
+	return s.checkpoint(ctx, "SELECT doc FROM audit_checkpoints WHERE from_ts <= ?1 AND to_ts > ?1 LIMIT 1", t.UnixNano())
+}
+
+func (s *Store) SaveCheckpointLeaves(ctx context.Context, checkpointID string, leaves []models.CheckpointLeaf) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM audit_checkpoint_leaves WHERE checkpoint_id = ?1", checkpointID); err != nil {
+		return fmt.Errorf("failed to delete checkpoint leaves: %w", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+INSERT INTO audit_checkpoint_leaves (checkpoint_id, idx, event_id, leaf_hash) VALUES (?1, ?2, ?3, ?4)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, leaf := range leaves {
+		if _, err := stmt.ExecContext(ctx, checkpointID, i, leaf.EventID, leaf.LeafHash); err != nil {
+			return fmt.Errorf("failed to insert checkpoint leaf %d: %w", i, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit checkpoint leaves: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) CheckpointLeaves(ctx context.Context, checkpointID string) ([]models.CheckpointLeaf, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT event_id, leaf_hash FROM audit_checkpoint_leaves WHERE checkpoint_id = ?1 ORDER BY idx", checkpointID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query checkpoint leaves: %w", err)
+	}
+	defer rows.Close()
+
+	var leaves []models.CheckpointLeaf
+	for rows.Next() {
+		var leaf models.CheckpointLeaf
+		if err := rows.Scan(&leaf.EventID, &leaf.LeafHash); err != nil {
+			return nil, fmt.Errorf("failed to scan checkpoint leaf: %w", err)
+		}
+		leaves = append(leaves, leaf)
+	}
+	return leaves, rows.Err()
+}
+
+// checkpoint читает одну контрольную точку запросом, возвращающим doc.
+func (s *Store) checkpoint(ctx context.Context, query string, args ...any) (*models.Checkpoint, error) {
+	var doc string
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&doc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+
+	var cp models.Checkpoint
+	if err := json.Unmarshal([]byte(doc), &cp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// newBuilder строит условия фильтра. Фильтр по имени идет через FTS5:
+// каждое слово берется в кавычки, и FTS5 требует вхождения всех слов.
+func newBuilder(filter *models.AuditEventFilter) *sqlutil.Builder {
+	b := sqlutil.NewBuilder(sqlutil.Question)
+	b.Filter(filter, tsArg)
+	if filter != nil && filter.Name != nil {
+		words := storage.Tokenize(*filter.Name)
+		if len(words) > 0 {
+			for i, w := range words {
+				words[i] = `"` + w + `"`
+			}
+			b.Add("rowid IN (SELECT rowid FROM audit_events_fts WHERE audit_events_fts MATCH " +
+				b.Arg(strings.Join(words, " ")) + ")")
+		}
+	}
+	return b
+}
+
+func tsArg(t time.Time) any {
+	return t.UnixNano()
+}
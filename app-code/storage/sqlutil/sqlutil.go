@@ -0,0 +1,189 @@
+// Package sqlutil содержит общие для SQL-хранилищ части: раскладку события
+// по колонкам и построение условий WHERE по фильтру с той же семантикой, что storage.Match.
+package sqlutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"witness/models"
+	"witness/storage"
+)
+
+// Columns сопоставляет поля документа (как в storage.FacetFields) с колонками таблицы событий.
+var Columns = map[string]string{
+	"status":                 "status",
+	"event_type":             "event_type",
+	"actor.id":               "actor_id",
+	"actor.type":             "actor_type",
+	"entity.id":              "entity_id",
+	"entity.type":            "entity_type",
+	"context.source_service": "source_service",
+	"security.access_level":  "access_level",
+	"signature_status":       "signature_status",
+	"tenant":                 "tenant",
+}
+
+// Placeholder возвращает обозначение n-го параметра запроса (нумерация с 1).
+type Placeholder func(n int) string
+
+// Dollar - параметры PostgreSQL: $1, $2, ...
+func Dollar(n int) string { return fmt.Sprintf("$%d", n) }
+
+// Question - позиционные параметры SQLite: ?1, ?2, ...
+func Question(n int) string { return fmt.Sprintf("?%d", n) }
+
+// Builder накапливает условия и аргументы запроса.
+type Builder struct {
+	ph    Placeholder
+	conds []string
+	Args  []any
+}
+
+// NewBuilder создает построитель условий с заданным стилем параметров.
+func NewBuilder(ph Placeholder) *Builder {
+	return &Builder{ph: ph}
+}
+
+// Arg добавляет аргумент и возвращает его обозначение в запросе.
+func (b *Builder) Arg(v any) string {
+	b.Args = append(b.Args, v)
+	return b.ph(len(b.Args))
+}
+
+// Add добавляет произвольное условие.
+func (b *Builder) Add(cond string) {
+	b.conds = append(b.conds, cond)
+}
+
+// Filter добавляет условия фильтра событий. tsArg преобразует время в значение параметра.
+func (b *Builder) Filter(filter *models.AuditEventFilter, tsArg func(time.Time) any) {
+	terms := storage.FilterTerms(filter)
+	fields := make([]string, 0, len(terms))
+	for field := range terms {
+		fields = append(fields, field)
+	}
+	// Стабильный порядок условий упрощает чтение логов и кэширование планов.
+	sort.Strings(fields)
+	for _, field := range fields {
+		b.Add(Columns[field] + " = " + b.Arg(terms[field]))
+	}
+
+	if filter != nil && filter.From != nil {
+		b.Add("ts >= " + b.Arg(tsArg(*filter.From)))
+	}
+	if filter != nil && filter.To != nil {
+		b.Add("ts <= " + b.Arg(tsArg(*filter.To)))
+	}
+}
+
+// ExcludeHolds добавляет условие, исключающее события под активными удержаниями.
+func (b *Builder) ExcludeHolds(holds []*models.LegalHold, tsArg func(time.Time) any) {
+	for _, hold := range holds {
+		if !hold.IsActive() {
+			continue
+		}
+		var parts []string
+		if hold.ActorID != nil {
+			parts = append(parts, "actor_id = "+b.Arg(*hold.ActorID))
+		}
+		if hold.EntityID != nil {
+			parts = append(parts, "entity_id = "+b.Arg(*hold.EntityID))
+		}
+		if hold.From != nil {
+			parts = append(parts, "ts >= "+b.Arg(tsArg(*hold.From)))
+		}
+		if hold.To != nil {
+			parts = append(parts, "ts <= "+b.Arg(tsArg(*hold.To)))
+		}
+		if len(parts) > 0 {
+			b.Add("NOT (" + strings.Join(parts, " AND ") + ")")
+		}
+	}
+}
+
+// Where возвращает выражение WHERE (или пустую строку, если условий нет).
+func (b *Builder) Where() string {
+	if len(b.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.conds, " AND ")
+}
+
+// Row - событие, разложенное по колонкам таблицы.
+type Row struct {
+	EventID         string
+	Timestamp       time.Time
+	Status          string
+	EventType       string
+	ActorID         string
+	ActorType       string
+	ActorName       string
+	EntityID        string
+	EntityType      string
+	EntityName      string
+	SourceService   string
+	AccessLevel     string
+	SignatureStatus string
+	Tenant          string
+	// NameTokens - слова actor.name и entity.name (storage.Tokenize) для фильтра по имени.
+	NameTokens []string
+	// Doc - событие целиком без details; хранится для точного восстановления
+	// (включая наносекунды времени и поля, не вынесенные в колонки).
+	Doc []byte
+	// Details - details отдельным JSON-документом.
+	Details []byte
+}
+
+// ToRow раскладывает событие по колонкам.
+func ToRow(event *models.AuditEvent) (*Row, error) {
+	e := *event
+	details, err := json.Marshal(e.Details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal details of %s: %w", e.EventID, err)
+	}
+	e.Details = nil
+	doc, err := json.Marshal(&e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event %s: %w", e.EventID, err)
+	}
+
+	row := &Row{
+		EventID:         e.EventID,
+		Timestamp:       e.Timestamp,
+		Status:          e.Status,
+		EventType:       e.EventType,
+		ActorID:         e.Actor.ID,
+		ActorType:       e.Actor.Type,
+		ActorName:       e.Actor.Name,
+		EntityID:        e.Entity.ID,
+		EntityType:      e.Entity.Type,
+		EntityName:      e.Entity.Name,
+		SourceService:   e.Context.SourceService,
+		SignatureStatus: e.SignatureStatus,
+		Tenant:          e.Tenant,
+		NameTokens:      storage.Tokenize(e.Actor.Name + " " + e.Entity.Name),
+		Doc:             doc,
+		Details:         details,
+	}
+	if e.Security != nil {
+		row.AccessLevel = e.Security.AccessLevel
+	}
+	return row, nil
+}
+
+// FromDoc восстанавливает событие из сохраненного документа и details.
+func FromDoc(doc, details []byte) (*models.AuditEvent, error) {
+	var event models.AuditEvent
+	if err := json.Unmarshal(doc, &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+	if len(details) > 0 {
+		if err := json.Unmarshal(details, &event.Details); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal details of %s: %w", event.EventID, err)
+		}
+	}
+	return &event, nil
+}
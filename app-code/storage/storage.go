@@ -0,0 +1,332 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"witness/models"
+)
+
+// ErrNotFound возвращается, когда запрошенный объект отсутствует в хранилище.
+var ErrNotFound = errors.New("not found")
+
+// ErrClaimed возвращается, когда задание уже выполняет другой экземпляр (см. ExportJobStore.ClaimExportJob).
+var ErrClaimed = errors.New("export job is claimed by another instance")
+
+// EventStore - хранилище событий аудита. Реализации обязаны одинаково
+// интерпретировать фильтр, сортировку и пагинацию (см. Query и Match).
+type EventStore interface {
+	// IndexBatch сохраняет пачку событий. Повторная запись с тем же event_id перезаписывает событие.
+	IndexBatch(ctx context.Context, events []*models.AuditEvent) error
+	// Search возвращает страницу событий, отсортированных по убыванию времени, и общее число совпадений.
+	Search(ctx context.Context, query Query) ([]*models.AuditEvent, int64, error)
+	// Scan передает в fn все события, подходящие под фильтр, в порядке Search. В отличие
+	// от Search объем не ограничен окном пагинации: реализации читают по курсору.
+	// Ошибка fn прерывает чтение и возвращается.
+	Scan(ctx context.Context, filter *models.AuditEventFilter, fn func(*models.AuditEvent) error) error
+	// Get возвращает событие по event_id или ErrNotFound.
+	Get(ctx context.Context, eventID string) (*models.AuditEvent, error)
+	// Aggregate считает количество событий по значениям поля (см. FacetFields).
+	Aggregate(ctx context.Context, filter *models.AuditEventFilter, field string, size int) ([]*models.FacetBucket, error)
+}
+
+// LegalHoldStore хранит юридические удержания.
+type LegalHoldStore interface {
+	SaveLegalHold(ctx context.Context, hold *models.LegalHold) error
+	GetLegalHold(ctx context.Context, id string) (*models.LegalHold, error)
+	ListLegalHolds(ctx context.Context, activeOnly bool) ([]*models.LegalHold, error)
+}
+
+// APIKeyStore хранит ключи API.
+type APIKeyStore interface {
+	// SaveAPIKey создает или перезаписывает ключ.
+	SaveAPIKey(ctx context.Context, key *models.APIKey) error
+	// GetAPIKey возвращает ключ по идентификатору или ErrNotFound.
+	GetAPIKey(ctx context.Context, id string) (*models.APIKey, error)
+	// ListAPIKeys возвращает все ключи, новые первыми.
+	ListAPIKeys(ctx context.Context) ([]*models.APIKey, error)
+	// TouchAPIKey записывает время последнего использования ключа, не трогая остальные поля.
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+}
+
+// ExportJobStore хранит задания выгрузки, чтобы они переживали перезапуск.
+type ExportJobStore interface {
+	// SaveExportJob создает или перезаписывает задание.
+	SaveExportJob(ctx context.Context, job *models.ExportJob) error
+	// GetExportJob возвращает задание по идентификатору или ErrNotFound.
+	GetExportJob(ctx context.Context, id string) (*models.ExportJob, error)
+	// ListExportJobs возвращает задания, новые первыми; при unfinishedOnly - только
+	// ожидающие и выполняющиеся.
+	ListExportJobs(ctx context.Context, unfinishedOnly bool) ([]*models.ExportJob, error)
+	// ClaimExportJob атомарно переводит задание в RUNNING и закрепляет его за owner до leaseUntil,
+	// если оно ожидает выполнения или его аренда истекла к now. Иначе возвращает ErrClaimed
+	// (или ErrNotFound); завершенное задание тоже не забирается.
+	ClaimExportJob(ctx context.Context, id, owner string, now, leaseUntil time.Time) (*models.ExportJob, error)
+}
+
+// Claimable сообщает, что задание можно забрать на выполнение в момент now:
+// оно ожидает или выполняется с истекшей (или не назначенной) арендой.
+func Claimable(job *models.ExportJob, now time.Time) bool {
+	switch job.Status {
+	case models.ExportPending:
+		return true
+	case models.ExportRunning:
+		return job.LeaseUntil == nil || !now.Before(*job.LeaseUntil)
+	}
+	return false
+}
+
+// Pruner удаляет устаревшие события, не трогая события под активными удержаниями.
+type Pruner interface {
+	// DeleteEventsBefore удаляет события старше before и вместе с ними записывает
+	// отметки об удаленных номерах цепочек (models.ChainPrune с причиной PruneRetention).
+	DeleteEventsBefore(ctx context.Context, before time.Time, holds []*models.LegalHold) (int64, error)
+}
+
+// ChainStore дает доступ к цепочкам хешей событий (см. пакет integrity).
+type ChainStore interface {
+	// ChainHead возвращает последнее звено потока или ErrNotFound, если поток пуст.
+	ChainHead(ctx context.Context, stream string) (*models.ChainLink, error)
+	// ChainEvent возвращает событие потока с номером seq или ErrNotFound.
+	ChainEvent(ctx context.Context, stream string, seq int64) (*models.AuditEvent, error)
+	// ScanChain передает в fn события, принятые в интервале [from, to] (chain.ingested_at),
+	// упорядоченные по потоку и номеру в нем.
+	ScanChain(ctx context.Context, from, to time.Time, fn func(*models.AuditEvent) error) error
+	// ChainPrunes возвращает отметки об удалении событий потока по возрастанию FirstSeq.
+	ChainPrunes(ctx context.Context, stream string) ([]*models.ChainPrune, error)
+}
+
+// Prunes сворачивает звенья удаленных событий в отметки: по одной на каждый
+// непрерывный отрезок номеров потока. Порядок links не важен.
+func Prunes(links []*models.ChainLink, reason string, at time.Time) []*models.ChainPrune {
+	sorted := make([]*models.ChainLink, 0, len(links))
+	for _, link := range links {
+		if link != nil && link.Stream != "" {
+			sorted = append(sorted, link)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Stream != sorted[j].Stream {
+			return sorted[i].Stream < sorted[j].Stream
+		}
+		return sorted[i].Seq < sorted[j].Seq
+	})
+
+	var prunes []*models.ChainPrune
+	var last *models.ChainPrune
+	for _, link := range sorted {
+		if last != nil && last.Stream == link.Stream && link.Seq <= last.LastSeq+1 {
+			last.LastSeq, last.LastHash = link.Seq, link.Hash
+			continue
+		}
+		last = &models.ChainPrune{
+			Stream:   link.Stream,
+			FirstSeq: link.Seq,
+			LastSeq:  link.Seq,
+			LastHash: link.Hash,
+			Reason:   reason,
+			PrunedAt: at.UTC(),
+		}
+		prunes = append(prunes, last)
+	}
+	return prunes
+}
+
+// CheckpointStore хранит подписанные контрольные точки (см. пакет checkpoint).
+type CheckpointStore interface {
+	// SaveCheckpoint создает или перезаписывает контрольную точку.
+	SaveCheckpoint(ctx context.Context, cp *models.Checkpoint) error
+	// LatestCheckpoint возвращает контрольную точку с наибольшим To или ErrNotFound.
+	LatestCheckpoint(ctx context.Context) (*models.Checkpoint, error)
+	// CheckpointAt возвращает контрольную точку, интервал [From, To) которой содержит t, или ErrNotFound.
+	CheckpointAt(ctx context.Context, t time.Time) (*models.Checkpoint, error)
+	// SaveCheckpointLeaves заменяет листья контрольной точки; порядок листьев - порядок дерева.
+	SaveCheckpointLeaves(ctx context.Context, checkpointID string, leaves []models.CheckpointLeaf) error
+	// CheckpointLeaves возвращает листья контрольной точки в порядке дерева
+	// или пустой список, если они не сохранялись.
+	CheckpointLeaves(ctx context.Context, checkpointID string) ([]models.CheckpointLeaf, error)
+}
+
+// Backend объединяет все, что сервер использует от хранилища.
+type Backend interface {
+	EventStore
+	LegalHoldStore
+	Pruner
+	ChainStore
+	CheckpointStore
+	APIKeyStore
+	ExportJobStore
+}
+
+// Query - параметры поиска событий.
+type Query struct {
+	Filter *models.AuditEventFilter
+	Limit  int
+	Offset int
+}
+
+// FacetFields - поля, по которым допускается агрегация.
+var FacetFields = []string{
+	"status",
+	"event_type",
+	"actor.id",
+	"actor.type",
+	"entity.id",
+	"entity.type",
+	"context.source_service",
+	"security.access_level",
+	"signature_status",
+	"tenant",
+}
+
+// ValidateFacetField проверяет, что по полю можно строить агрегацию.
+func ValidateFacetField(field string) error {
+	for _, f := range FacetFields {
+		if f == field {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported facet field %q, expected one of: %s", field, strings.Join(FacetFields, ", "))
+}
+
+// FieldValue возвращает значение поля события по его пути в документе (например, "actor.id").
+func FieldValue(event *models.AuditEvent, field string) string {
+	switch field {
+	case "status":
+		return event.Status
+	case "event_type":
+		return event.EventType
+	case "actor.id":
+		return event.Actor.ID
+	case "actor.type":
+		return event.Actor.Type
+	case "entity.id":
+		return event.Entity.ID
+	case "entity.type":
+		return event.Entity.Type
+	case "context.source_service":
+		return event.Context.SourceService
+	case "security.access_level":
+		if event.Security == nil {
+			return ""
+		}
+		return event.Security.AccessLevel
+	case "signature_status":
+		return event.SignatureStatus
+	case "tenant":
+		return event.Tenant
+	}
+	return ""
+}
+
+// Match проверяет событие на соответствие фильтру. Это эталонная семантика
+// фильтра: точное совпадение полей и интервал времени [from, to] включительно.
+func Match(filter *models.AuditEventFilter, event *models.AuditEvent) bool {
+	if filter == nil {
+		return true
+	}
+	for field, value := range FilterTerms(filter) {
+		if FieldValue(event, field) != value {
+			return false
+		}
+	}
+	if filter.From != nil && event.Timestamp.Before(*filter.From) {
+		return false
+	}
+	if filter.To != nil && event.Timestamp.After(*filter.To) {
+		return false
+	}
+	if filter.Name != nil && !matchName(*filter.Name, event) {
+		return false
+	}
+	return true
+}
+
+// Tokenize разбивает текст на слова в нижнем регистре так же, как стандартный
+// анализатор OpenSearch и токенизатор unicode61 в SQLite FTS5: по всему, что не буква и не цифра.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchName проверяет, что каждое слово запроса есть в actor.name или entity.name.
+func matchName(query string, event *models.AuditEvent) bool {
+	words := make(map[string]bool)
+	for _, w := range Tokenize(event.Actor.Name + " " + event.Entity.Name) {
+		words[w] = true
+	}
+	for _, w := range Tokenize(query) {
+		if !words[w] {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterTerms возвращает условия точного совпадения фильтра в виде "поле документа -> значение".
+func FilterTerms(filter *models.AuditEventFilter) map[string]string {
+	terms := make(map[string]string)
+	if filter == nil {
+		return terms
+	}
+	if filter.Status != nil {
+		terms["status"] = *filter.Status
+	}
+	if filter.EventType != nil {
+		terms["event_type"] = *filter.EventType
+	}
+	if filter.ActorID != nil {
+		terms["actor.id"] = *filter.ActorID
+	}
+	if filter.EntityID != nil {
+		terms["entity.id"] = *filter.EntityID
+	}
+	if filter.SecurityAccessLevel != nil {
+		terms["security.access_level"] = *filter.SecurityAccessLevel
+	}
+	if filter.SignatureStatus != nil {
+		terms["signature_status"] = *filter.SignatureStatus
+	}
+	if filter.Tenant != nil {
+		terms["tenant"] = *filter.Tenant
+	}
+	return terms
+}
+
+// InChainRange проверяет, что событие принято в интервале [from, to] включительно.
+func InChainRange(event *models.AuditEvent, from, to time.Time) bool {
+	if event.Chain == nil {
+		return false
+	}
+	return !event.Chain.IngestedAt.Before(from) && !event.Chain.IngestedAt.After(to)
+}
+
+// ChainLess задает порядок обхода цепочек: по потоку, затем по номеру.
+func ChainLess(a, b *models.AuditEvent) bool {
+	if a.Chain.Stream != b.Chain.Stream {
+		return a.Chain.Stream < b.Chain.Stream
+	}
+	return a.Chain.Seq < b.Chain.Seq
+}
+
+// IngestLess задает порядок приема: по chain.ingested_at, при равенстве - как ChainLess.
+// У обоих событий должно быть звено цепочки.
+func IngestLess(a, b *models.AuditEvent) bool {
+	if !a.Chain.IngestedAt.Equal(b.Chain.IngestedAt) {
+		return a.Chain.IngestedAt.Before(b.Chain.IngestedAt)
+	}
+	return ChainLess(a, b)
+}
+
+// Less задает порядок выдачи: по убыванию времени, при равенстве - по убыванию event_id.
+func Less(a, b *models.AuditEvent) bool {
+	if !a.Timestamp.Equal(b.Timestamp) {
+		return a.Timestamp.After(b.Timestamp)
+	}
+	return a.EventID > b.EventID
+}
@@ -0,0 +1,603 @@
+// Package storagetest - общие проверки реализаций storage.Backend. Все хранилища обязаны
+// одинаково понимать event_id, порядок выдачи, пагинацию, фильтр и удаление по сроку,
+// поэтому тесты каждого хранилища прогоняют один и тот же набор через Run.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+	"witness/models"
+	"witness/storage"
+)
+
+// Open возвращает пустое хранилище для одного подтеста.
+type Open func(t *testing.T) storage.Backend
+
+// t0 - опорное время событий. Время целое до миллисекунд: PostgreSQL хранит микросекунды.
+var t0 = time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+// Run проверяет хранилище, которое создает open.
+func Run(t *testing.T, open Open) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store storage.Backend)
+	}{
+		{"UpsertByEventID", testUpsert},
+		{"Paging", testPaging},
+		{"ScanCursor", testScan},
+		{"Filters", testFilters},
+		{"NameSearch", testNameSearch},
+		{"Aggregate", testAggregate},
+		{"RetentionWithHolds", testRetention},
+		{"Chain", testChain},
+		{"CheckpointLeaves", testCheckpointLeaves},
+		{"ClaimExportJob", testClaimExportJob},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+}
+
+// newEvent создает полное событие; тесты меняют в нем нужные поля.
+func newEvent(id string, ts time.Time) *models.AuditEvent {
+	return &models.AuditEvent{
+		EventID:         id,
+		Timestamp:       ts,
+		Status:          "SUCCESS",
+		EventType:       "LOGIN",
+		Actor:           models.Actor{ID: "alice", Type: "USER", Name: "Alice Smith"},
+		Entity:          models.Entity{ID: "doc-1", Type: "DOCUMENT", Name: "Quarterly report"},
+		Context:         models.Context{SourceService: "auth"},
+		Security:        &models.Security{AccessLevel: "LOW"},
+		Details:         map[string]any{"attempt": "1"},
+		SignatureStatus: models.SignatureUnverified,
+	}
+}
+
+func index(t *testing.T, store storage.Backend, events ...*models.AuditEvent) {
+	t.Helper()
+	if err := store.IndexBatch(context.Background(), events); err != nil {
+		t.Fatalf("IndexBatch: %v", err)
+	}
+}
+
+// search возвращает идентификаторы всех событий по фильтру в порядке выдачи.
+func search(t *testing.T, store storage.Backend, filter *models.AuditEventFilter) []string {
+	t.Helper()
+	events, total, err := store.Search(context.Background(), storage.Query{Filter: filter, Limit: 1000})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if total != int64(len(events)) {
+		t.Fatalf("Search: got %d events, total %d", len(events), total)
+	}
+	return ids(events)
+}
+
+func ids(events []*models.AuditEvent) []string {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.EventID)
+	}
+	return ids
+}
+
+// expected возвращает события из all, подходящие под фильтр по эталонной семантике
+// storage.Match, в порядке выдачи.
+func expected(all []*models.AuditEvent, filter *models.AuditEventFilter) []string {
+	var matched []*models.AuditEvent
+	for _, e := range all {
+		if storage.Match(filter, e) {
+			matched = append(matched, e)
+		}
+	}
+	slices.SortFunc(matched, order)
+	return ids(matched)
+}
+
+func order(a, b *models.AuditEvent) int {
+	if storage.Less(a, b) {
+		return -1
+	}
+	if storage.Less(b, a) {
+		return 1
+	}
+	return 0
+}
+
+func testUpsert(t *testing.T, store storage.Backend) {
+	ctx := context.Background()
+	first := newEvent("e1", t0)
+	index(t, store, first, newEvent("e2", t0))
+
+	// Повтор того же event_id заменяет событие, даже если изменилось время.
+	second := newEvent("e1", t0.Add(40*24*time.Hour))
+	second.Status = "FAILURE"
+	index(t, store, second)
+	// Повтор с тем же временем тоже не создает второе событие.
+	same := newEvent("e2", t0)
+	same.EventType = "LOGOUT"
+	index(t, store, same)
+
+	if got := search(t, store, nil); !slices.Equal(got, []string{"e1", "e2"}) {
+		t.Fatalf("got %v, want [e1 e2]", got)
+	}
+	got, err := store.Get(ctx, "e1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Status != "FAILURE" || !got.Timestamp.Equal(second.Timestamp) {
+		t.Errorf("e1: got status %s at %s, want the second write", got.Status, got.Timestamp)
+	}
+	got, err = store.Get(ctx, "e2")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.EventType != "LOGOUT" {
+		t.Errorf("e2: got event_type %s, want LOGOUT", got.EventType)
+	}
+	if got.Actor != same.Actor || got.Entity != same.Entity || got.Context != same.Context || got.Details["attempt"] != "1" {
+		t.Errorf("e2: stored event differs from the written one: %+v", got)
+	}
+
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get of an unknown event: got %v, want ErrNotFound", err)
+	}
+}
+
+func testPaging(t *testing.T, store storage.Backend) {
+	ctx := context.Background()
+	var all []*models.AuditEvent
+	for i := range 25 {
+		// Пары событий с одним временем: при равенстве порядок - по убыванию event_id.
+		all = append(all, newEvent(fmt.Sprintf("e%02d", i), t0.Add(time.Duration(i/2)*time.Minute)))
+	}
+	index(t, store, all...)
+	want := expected(all, nil)
+
+	var got []string
+	for offset := 0; offset < len(all); offset += 10 {
+		page, total, err := store.Search(ctx, storage.Query{Limit: 10, Offset: offset})
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		if total != int64(len(all)) {
+			t.Fatalf("offset %d: got total %d, want %d", offset, total, len(all))
+		}
+		got = append(got, ids(page)...)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("pages:\ngot  %v\nwant %v", got, want)
+	}
+
+	page, total, err := store.Search(ctx, storage.Query{Limit: 10, Offset: 100})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(page) != 0 || total != int64(len(all)) {
+		t.Errorf("offset past the end: got %d events, total %d", len(page), total)
+	}
+}
+
+func testScan(t *testing.T, store storage.Backend) {
+	ctx := context.Background()
+	// Больше страницы курсора (1000 у SQL-хранилищ и OpenSearch) и с совпадающим временем
+	// на границах страниц, чтобы курсор не терял и не повторял события.
+	var all []*models.AuditEvent
+	for i := range 2100 {
+		e := newEvent(fmt.Sprintf("e%04d", i), t0.Add(time.Duration(i/3)*time.Second))
+		if i%2 == 0 {
+			e.EventType = "LOGOUT"
+		}
+		all = append(all, e)
+	}
+	index(t, store, all...)
+
+	logout := "LOGOUT"
+	for _, filter := range []*models.AuditEventFilter{nil, {EventType: &logout}} {
+		var got []string
+		err := store.Scan(ctx, filter, func(e *models.AuditEvent) error {
+			got = append(got, e.EventID)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		if want := expected(all, filter); !slices.Equal(got, want) {
+			t.Fatalf("Scan returned %d events, want %d in Search order", len(got), len(want))
+		}
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err := store.Scan(ctx, nil, func(*models.AuditEvent) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Scan must stop on the first fn error: got %v after %d calls", err, calls)
+	}
+}
+
+func testFilters(t *testing.T, store storage.Backend) {
+	var all []*models.AuditEvent
+	for i := range 12 {
+		e := newEvent(fmt.Sprintf("e%02d", i), t0.Add(time.Duration(i)*time.Hour))
+		if i%2 == 1 {
+			e.Status = "FAILURE"
+		}
+		if i%3 == 0 {
+			e.EventType = "EXPORT"
+			e.Security.AccessLevel = "HIGH"
+		}
+		if i%4 == 0 {
+			e.Actor.ID = "bob"
+			e.Entity.ID = "doc-2"
+		}
+		if i%5 == 0 {
+			e.SignatureStatus = models.SignatureVerified
+			e.Tenant = "billing"
+		}
+		all = append(all, e)
+	}
+	index(t, store, all...)
+
+	str := func(s string) *string { return &s }
+	at := func(h int) *time.Time {
+		t := t0.Add(time.Duration(h) * time.Hour)
+		return &t
+	}
+	filters := map[string]*models.AuditEventFilter{
+		"status":       {Status: str("FAILURE")},
+		"event type":   {EventType: str("EXPORT")},
+		"actor":        {ActorID: str("bob")},
+		"entity":       {EntityID: str("doc-2")},
+		"access level": {SecurityAccessLevel: str("HIGH")},
+		"signature":    {SignatureStatus: str(models.SignatureVerified)},
+		"tenant":       {Tenant: str("billing")},
+		// Границы интервала включаются.
+		"from":      {From: at(7)},
+		"to":        {To: at(3)},
+		"from to":   {From: at(2), To: at(6)},
+		"combined":  {Status: str("FAILURE"), ActorID: str("alice"), From: at(1), To: at(9)},
+		"no match":  {ActorID: str("nobody")},
+		"empty":     {},
+		"unmatched": {EventType: str("EXPORT"), Tenant: str("hr")},
+	}
+	for name, filter := range filters {
+		t.Run(name, func(t *testing.T) {
+			want := expected(all, filter)
+			if got := search(t, store, filter); !slices.Equal(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func testNameSearch(t *testing.T, store storage.Backend) {
+	a := newEvent("e1", t0)
+	b := newEvent("e2", t0.Add(time.Minute))
+	b.Actor.Name = "Bob Jones"
+	b.Entity.Name = "Payroll-2024 export"
+	c := newEvent("e3", t0.Add(2*time.Minute))
+	c.Actor.Name = ""
+	c.Entity.Name = "Alice's notes"
+	all := []*models.AuditEvent{a, b, c}
+	index(t, store, all...)
+
+	for _, query := range []string{
+		"alice",           // слово из actor.name и из entity.name другого события
+		"SMITH",           // без учета регистра
+		"quarterly smith", // слова из разных полей
+		"payroll 2024",    // разбиение по знакам препинания
+		"bob report",      // не все слова есть в событии
+		"nobody",
+	} {
+		t.Run(query, func(t *testing.T) {
+			filter := &models.AuditEventFilter{Name: &query}
+			want := expected(all, filter)
+			if got := search(t, store, filter); !slices.Equal(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func testAggregate(t *testing.T, store storage.Backend) {
+	ctx := context.Background()
+	var all []*models.AuditEvent
+	for i, status := range []string{"SUCCESS", "FAILURE", "FAILURE", "DENIED", "DENIED", "SUCCESS", "FAILURE"} {
+		e := newEvent(fmt.Sprintf("e%d", i), t0.Add(time.Duration(i)*time.Minute))
+		e.Status = status
+		all = append(all, e)
+	}
+	index(t, store, all...)
+
+	buckets, err := store.Aggregate(ctx, nil, "status", 10)
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	want := []models.FacetBucket{{Key: "FAILURE", Count: 3}, {Key: "DENIED", Count: 2}, {Key: "SUCCESS", Count: 2}}
+	if len(buckets) != len(want) {
+		t.Fatalf("got %d buckets, want %v", len(buckets), want)
+	}
+	for i, b := range buckets {
+		if *b != want[i] {
+			t.Errorf("bucket %d: got %+v, want %+v", i, *b, want[i])
+		}
+	}
+
+	from := t0.Add(3 * time.Minute)
+	buckets, err = store.Aggregate(ctx, &models.AuditEventFilter{From: &from}, "status", 1)
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	if len(buckets) != 1 || buckets[0].Key != "DENIED" || buckets[0].Count != 2 {
+		t.Errorf("filtered aggregate with size 1: got %v", buckets)
+	}
+
+	if _, err := store.Aggregate(ctx, nil, "details.attempt", 10); err == nil {
+		t.Error("aggregation by a field outside FacetFields must fail")
+	}
+}
+
+func testRetention(t *testing.T, store storage.Backend) {
+	ctx := context.Background()
+	cutoff := t0.Add(10 * time.Hour)
+	ingested := t0.Add(20 * time.Hour)
+
+	// Поток s, номера 1..8: события 1-6 старше cutoff, 7-8 новее.
+	var all []*models.AuditEvent
+	for i := 1; i <= 8; i++ {
+		e := newEvent(fmt.Sprintf("e%d", i), t0.Add(time.Duration(i)*time.Hour+time.Duration(i/7)*5*time.Hour))
+		e.Chain = &models.ChainLink{Stream: "s", Seq: int64(i), IngestedAt: ingested, Hash: fmt.Sprintf("h%d", i)}
+		all = append(all, e)
+	}
+	all[2].Actor.ID = "held-actor" // e3
+	all[4].Entity.ID = "held-doc"  // e5
+	index(t, store, all...)
+
+	actor, entity := "held-actor", "held-doc"
+	from, to := t0.Add(5*time.Hour), t0.Add(5*time.Hour)
+	holds := []*models.LegalHold{
+		{ID: "h1", Status: models.LegalHoldActive, ActorID: &actor},
+		// Удержание сущности ограничено интервалом, в который попадает только e5.
+		{ID: "h2", Status: models.LegalHoldActive, EntityID: &entity, From: &from, To: &to},
+		// Снятое удержание ничего не защищает.
+		{ID: "h3", Status: models.LegalHoldReleased},
+	}
+
+	deleted, err := store.DeleteEventsBefore(ctx, cutoff, holds)
+	if err != nil {
+		t.Fatalf("DeleteEventsBefore: %v", err)
+	}
+	if deleted != 4 {
+		t.Errorf("deleted %d events, want 4", deleted)
+	}
+	if got, want := search(t, store, nil), []string{"e8", "e7", "e5", "e3"}; !slices.Equal(got, want) {
+		t.Errorf("remaining events: got %v, want %v", got, want)
+	}
+
+	// Удаленные номера 1-2, 4 и 6 записаны отметками по непрерывным отрезкам.
+	prunes, err := store.ChainPrunes(ctx, "s")
+	if err != nil {
+		t.Fatalf("ChainPrunes: %v", err)
+	}
+	type span struct {
+		first, last int64
+		hash        string
+	}
+	var got []span
+	for _, p := range prunes {
+		if p.Stream != "s" || p.Reason != models.PruneRetention {
+			t.Errorf("unexpected prune %+v", p)
+		}
+		got = append(got, span{p.FirstSeq, p.LastSeq, p.LastHash})
+	}
+	want := []span{{1, 2, "h2"}, {4, 4, "h4"}, {6, 6, "h6"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("prunes: got %v, want %v", got, want)
+	}
+
+	if prunes, err := store.ChainPrunes(ctx, "other"); err != nil || len(prunes) != 0 {
+		t.Errorf("prunes of another stream: got %v, %v", prunes, err)
+	}
+}
+
+func testChain(t *testing.T, store storage.Backend) {
+	ctx := context.Background()
+	var all []*models.AuditEvent
+	add := func(stream string, seq int64, ingested time.Time) {
+		e := newEvent(fmt.Sprintf("%s-%d", stream, seq), t0.Add(-time.Duration(seq)*time.Hour))
+		e.Chain = &models.ChainLink{
+			Stream:     stream,
+			Seq:        seq,
+			IngestedAt: ingested,
+			PrevHash:   fmt.Sprintf("%s-h%d", stream, seq-1),
+			Hash:       fmt.Sprintf("%s-h%d", stream, seq),
+		}
+		all = append(all, e)
+	}
+	// Номера больше 9 проверяют числовой, а не строковый порядок.
+	for seq := int64(1); seq <= 12; seq++ {
+		add("a", seq, t0.Add(time.Duration(seq)*time.Second))
+	}
+	for seq := int64(1); seq <= 3; seq++ {
+		add("b", seq, t0.Add(time.Duration(seq)*time.Second))
+	}
+	unchained := newEvent("plain", t0)
+	index(t, store, append(all, unchained)...)
+
+	head, err := store.ChainHead(ctx, "a")
+	if err != nil {
+		t.Fatalf("ChainHead: %v", err)
+	}
+	if head.Stream != "a" || head.Seq != 12 || head.Hash != "a-h12" || !head.IngestedAt.Equal(t0.Add(12*time.Second)) {
+		t.Errorf("head of a: got %+v", head)
+	}
+	if _, err := store.ChainHead(ctx, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("ChainHead of an empty stream: got %v, want ErrNotFound", err)
+	}
+
+	event, err := store.ChainEvent(ctx, "b", 2)
+	if err != nil {
+		t.Fatalf("ChainEvent: %v", err)
+	}
+	if event.EventID != "b-2" || event.Chain.PrevHash != "b-h1" {
+		t.Errorf("ChainEvent(b, 2): got %s with %+v", event.EventID, event.Chain)
+	}
+	if _, err := store.ChainEvent(ctx, "b", 4); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("ChainEvent past the head: got %v, want ErrNotFound", err)
+	}
+
+	// Интервал приема включает границы; события без цепочки не попадают в обход.
+	from, to := t0.Add(2*time.Second), t0.Add(10*time.Second)
+	var got []string
+	err = store.ScanChain(ctx, from, to, func(e *models.AuditEvent) error {
+		got = append(got, e.EventID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanChain: %v", err)
+	}
+	var want []string
+	for seq := 2; seq <= 10; seq++ {
+		want = append(want, fmt.Sprintf("a-%d", seq))
+	}
+	want = append(want, "b-2", "b-3")
+	if !slices.Equal(got, want) {
+		t.Errorf("ScanChain:\ngot  %v\nwant %v", got, want)
+	}
+}
+
+func testCheckpointLeaves(t *testing.T, store storage.Backend) {
+	ctx := context.Background()
+	leaves := func(prefix string, n int) []models.CheckpointLeaf {
+		list := make([]models.CheckpointLeaf, n)
+		for i := range list {
+			list[i] = models.CheckpointLeaf{EventID: fmt.Sprintf("%s-%d", prefix, i), LeafHash: fmt.Sprintf("%064x", i)}
+		}
+		return list
+	}
+	check := func(id string, want []models.CheckpointLeaf) {
+		t.Helper()
+		got, err := store.CheckpointLeaves(ctx, id)
+		if err != nil {
+			t.Fatalf("CheckpointLeaves(%s): %v", id, err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("CheckpointLeaves(%s): got %d leaves, want %d (first diff at %d)", id, len(got), len(want), firstDiff(got, want))
+		}
+	}
+
+	// Больше страницы поиска OpenSearch; порядок листьев - порядок номеров, а не строк.
+	first := leaves("a", 1100)
+	if err := store.SaveCheckpointLeaves(ctx, "cp-1", first); err != nil {
+		t.Fatalf("SaveCheckpointLeaves: %v", err)
+	}
+	if err := store.SaveCheckpointLeaves(ctx, "cp-2", leaves("b", 2)); err != nil {
+		t.Fatalf("SaveCheckpointLeaves: %v", err)
+	}
+	check("cp-1", first)
+	check("cp-2", leaves("b", 2))
+	check("cp-missing", nil)
+
+	// Повторное построение точки заменяет листья целиком.
+	rebuilt := leaves("c", 3)
+	if err := store.SaveCheckpointLeaves(ctx, "cp-1", rebuilt); err != nil {
+		t.Fatalf("SaveCheckpointLeaves: %v", err)
+	}
+	check("cp-1", rebuilt)
+	check("cp-2", leaves("b", 2))
+}
+
+// firstDiff возвращает первый номер, на котором списки различаются.
+func firstDiff[T comparable](a, b []T) int {
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return min(len(a), len(b))
+}
+
+func testClaimExportJob(t *testing.T, store storage.Backend) {
+	ctx := context.Background()
+	save := func(id, status string) {
+		t.Helper()
+		job := &models.ExportJob{ID: id, Status: status, Format: "csv", CreatedBy: "alice", CreatedAt: t0}
+		if err := store.SaveExportJob(ctx, job); err != nil {
+			t.Fatalf("SaveExportJob: %v", err)
+		}
+	}
+	lease := t0.Add(time.Minute)
+
+	// Ожидающее задание забирает ровно один из одновременных претендентов.
+	save("pending", models.ExportPending)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var winners []string
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			owner := fmt.Sprintf("instance-%d", i)
+			job, err := store.ClaimExportJob(ctx, "pending", owner, t0, lease)
+			switch {
+			case err == nil:
+				if job.Status != models.ExportRunning || job.Owner != owner || !job.LeaseUntil.Equal(lease) {
+					t.Errorf("claimed job: %+v", job)
+				}
+				mu.Lock()
+				winners = append(winners, owner)
+				mu.Unlock()
+			case !errors.Is(err, storage.ErrClaimed):
+				t.Errorf("ClaimExportJob: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(winners) != 1 {
+		t.Fatalf("claimed by %v, want exactly one instance", winners)
+	}
+	stored, err := store.GetExportJob(ctx, "pending")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.ExportRunning || stored.Owner != winners[0] {
+		t.Errorf("stored job after claim: status %s, owner %s", stored.Status, stored.Owner)
+	}
+
+	// Пока аренда действует, задание не забрать; после истечения - можно.
+	if _, err := store.ClaimExportJob(ctx, "pending", "late", lease.Add(-time.Second), lease.Add(time.Hour)); !errors.Is(err, storage.ErrClaimed) {
+		t.Errorf("claim under a live lease: got %v, want ErrClaimed", err)
+	}
+	job, err := store.ClaimExportJob(ctx, "pending", "late", lease, lease.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("claim after the lease expired: %v", err)
+	}
+	if job.Owner != "late" {
+		t.Errorf("owner after takeover: got %s, want late", job.Owner)
+	}
+
+	// Выполняющееся задание без аренды (сохраненное до ее появления) можно забрать.
+	save("legacy", models.ExportRunning)
+	if _, err := store.ClaimExportJob(ctx, "legacy", "a", t0, lease); err != nil {
+		t.Errorf("claim of a running job without lease: %v", err)
+	}
+
+	save("done", models.ExportSucceeded)
+	if _, err := store.ClaimExportJob(ctx, "done", "a", t0, lease); !errors.Is(err, storage.ErrClaimed) {
+		t.Errorf("claim of a finished job: got %v, want ErrClaimed", err)
+	}
+	if _, err := store.ClaimExportJob(ctx, "missing", "a", t0, lease); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("claim of a missing job: got %v, want ErrNotFound", err)
+	}
+}
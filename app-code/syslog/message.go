@@ -0,0 +1,235 @@
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrInvalidMessage - сообщение не удалось разобрать как syslog.
+var ErrInvalidMessage = errors.New("invalid syslog message")
+
+// defaultPriority - facility user и severity notice, как у сообщения без PRI по RFC 3164.
+const defaultPriority = 13
+
+// nilValue - пустое значение поля в RFC 5424.
+const nilValue = "-"
+
+// Message - разобранное сообщение syslog (RFC 5424 или RFC 3164).
+type Message struct {
+	Facility int
+	Severity int
+	// Timestamp - время из сообщения, а если его нет - время приема.
+	Timestamp time.Time
+	Hostname  string
+	AppName   string
+	ProcID    string
+	MsgID     string
+	// StructuredData - параметры структурированных данных RFC 5424 по SD-ID.
+	StructuredData map[string]map[string]string
+	Message        string
+}
+
+// Parse разбирает сообщение. Формат определяется по версии после PRI: "1 " - RFC 5424,
+// иначе RFC 3164 (BSD syslog). received - время приема: оно подставляется, если в сообщении
+// нет времени, и определяет год для времени RFC 3164, где года нет.
+func Parse(data []byte, received time.Time) (*Message, error) {
+	data = bytes.TrimRight(data, "\r\n\x00")
+	if !utf8.Valid(data) {
+		data = bytes.ToValidUTF8(data, []byte("�"))
+	}
+	line := string(data)
+	if line == "" {
+		return nil, fmt.Errorf("%w: empty message", ErrInvalidMessage)
+	}
+
+	pri, rest, err := parsePriority(line)
+	if err != nil {
+		return nil, err
+	}
+	m := &Message{Facility: pri / 8, Severity: pri % 8}
+	if strings.HasPrefix(rest, "1 ") {
+		err = m.parse5424(rest[2:])
+	} else {
+		m.parse3164(rest, received)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.Timestamp.IsZero() {
+		m.Timestamp = received
+	}
+	m.Timestamp = m.Timestamp.UTC()
+	return m, nil
+}
+
+// parsePriority разбирает <PRI>. Сообщение без PRI получает defaultPriority (RFC 3164, 4.3.3).
+func parsePriority(line string) (int, string, error) {
+	if !strings.HasPrefix(line, "<") {
+		return defaultPriority, line, nil
+	}
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return 0, "", fmt.Errorf("%w: malformed priority", ErrInvalidMessage)
+	}
+	pri, err := strconv.Atoi(line[1:end])
+	if err != nil || pri > 191 {
+		return 0, "", fmt.Errorf("%w: malformed priority %q", ErrInvalidMessage, line[1:end])
+	}
+	return pri, line[end+1:], nil
+}
+
+// parse5424 разбирает часть сообщения RFC 5424 после версии:
+// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG].
+func (m *Message) parse5424(rest string) error {
+	fields := make([]string, 5)
+	for i := range fields {
+		field, tail, ok := strings.Cut(rest, " ")
+		if !ok && i < len(fields)-1 {
+			return fmt.Errorf("%w: truncated rfc 5424 header", ErrInvalidMessage)
+		}
+		fields[i], rest = field, tail
+	}
+	if fields[0] != nilValue {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("%w: invalid timestamp %q", ErrInvalidMessage, fields[0])
+		}
+		m.Timestamp = ts
+	}
+	m.Hostname = value5424(fields[1])
+	m.AppName = value5424(fields[2])
+	m.ProcID = value5424(fields[3])
+	m.MsgID = value5424(fields[4])
+
+	sd, msg, err := parseStructuredData(rest)
+	if err != nil {
+		return err
+	}
+	m.StructuredData = sd
+	// MSG в UTF-8 может начинаться с BOM.
+	m.Message = strings.TrimPrefix(msg, "\ufeff")
+	return nil
+}
+
+func value5424(field string) string {
+	if field == nilValue {
+		return ""
+	}
+	return field
+}
+
+// parseStructuredData разбирает STRUCTURED-DATA ("-" или [id param="value" ...]...)
+// и возвращает остаток - MSG.
+func parseStructuredData(s string) (map[string]map[string]string, string, error) {
+	if s == nilValue || strings.HasPrefix(s, nilValue+" ") {
+		return nil, strings.TrimPrefix(s[1:], " "), nil
+	}
+	if s == "" {
+		return nil, "", nil
+	}
+	sd := make(map[string]map[string]string)
+	i := 0
+	for i < len(s) && s[i] == '[' {
+		i++
+		start := i
+		for i < len(s) && s[i] != ' ' && s[i] != ']' {
+			i++
+		}
+		id := s[start:i]
+		params := make(map[string]string)
+		for i < len(s) && s[i] == ' ' {
+			i++
+			eq := strings.IndexByte(s[i:], '=')
+			if eq < 0 || i+eq+1 >= len(s) || s[i+eq+1] != '"' {
+				return nil, "", fmt.Errorf("%w: malformed structured data %q", ErrInvalidMessage, id)
+			}
+			name := s[i : i+eq]
+			i += eq + 2
+			var value strings.Builder
+			for ; i < len(s) && s[i] != '"'; i++ {
+				// Экранируются только '"', '\' и ']'; перед другими символами '\' остается как есть.
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`"\]`, s[i+1]) >= 0 {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, "", fmt.Errorf("%w: unterminated structured data %q", ErrInvalidMessage, id)
+			}
+			i++
+			params[name] = value.String()
+		}
+		if i >= len(s) || s[i] != ']' {
+			return nil, "", fmt.Errorf("%w: unterminated structured data %q", ErrInvalidMessage, id)
+		}
+		i++
+		sd[id] = params
+	}
+	if i == 0 || (i < len(s) && s[i] != ' ') {
+		return nil, "", fmt.Errorf("%w: malformed structured data", ErrInvalidMessage)
+	}
+	return sd, strings.TrimPrefix(s[i:], " "), nil
+}
+
+// parse3164 разбирает часть сообщения RFC 3164 после PRI: TIMESTAMP HOSTNAME TAG: MSG.
+// Формат на практике соблюдается нестрого, поэтому разбор не отвергает сообщения:
+// то, что не удалось распознать, остается в Message. Вместо "Mmm dd hh:mm:ss"
+// допускается время RFC 3339, как у rsyslog.
+func (m *Message) parse3164(rest string, received time.Time) {
+	if len(rest) >= len(time.Stamp) {
+		if ts, err := time.ParseInLocation(time.Stamp, rest[:len(time.Stamp)], received.Location()); err == nil {
+			m.Timestamp = stampYear(ts, received)
+			rest = strings.TrimPrefix(rest[len(time.Stamp):], " ")
+		}
+	}
+	if m.Timestamp.IsZero() {
+		if field, tail, ok := strings.Cut(rest, " "); ok {
+			if ts, err := time.Parse(time.RFC3339Nano, field); err == nil {
+				m.Timestamp, rest = ts, tail
+			}
+		}
+	}
+
+	// HOSTNAME есть, только если первое слово не похоже на TAG ("sshd[42]:", "su:").
+	if field, tail, ok := strings.Cut(rest, " "); ok && !m.Timestamp.IsZero() && !isTag(field) {
+		m.Hostname, rest = field, tail
+	}
+	if field, tail, ok := strings.Cut(rest, " "); ok && isTag(field) {
+		tag := strings.TrimSuffix(field, ":")
+		if open := strings.IndexByte(tag, '['); open > 0 && strings.HasSuffix(tag, "]") {
+			m.ProcID = tag[open+1 : len(tag)-1]
+			tag = tag[:open]
+		}
+		m.AppName, rest = tag, tail
+	}
+	m.Message = rest
+}
+
+// stampYear дополняет годом время RFC 3164, где года нет: берется последний год, в котором
+// такая дата есть (29 февраля - только в високосном) и который не дальше суток от received.
+// Время из будущего относится к прошлому году, сутки - запас на расхождение часов.
+func stampYear(ts, received time.Time) time.Time {
+	for year := received.Year(); ; year-- {
+		t := time.Date(year, ts.Month(), ts.Day(), ts.Hour(), ts.Minute(), ts.Second(), ts.Nanosecond(), ts.Location())
+		if t.Day() == ts.Day() && !t.After(received.Add(24*time.Hour)) {
+			return t
+		}
+	}
+}
+
+// isTag сообщает, что слово - TAG RFC 3164: имя процесса с необязательным [PID] и двоеточием.
+func isTag(field string) bool {
+	if !strings.HasSuffix(field, ":") || len(field) < 2 {
+		return false
+	}
+	name := strings.TrimSuffix(field, ":")
+	if open := strings.IndexByte(name, '['); open > 0 && strings.HasSuffix(name, "]") {
+		name = name[:open]
+	}
+	return name != "" && !strings.ContainsAny(name, "[]: ")
+}
@@ -0,0 +1,363 @@
+package syslog
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(value string) time.Time {
+	ts, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		panic(err)
+	}
+	return ts.UTC()
+}
+
+func TestParse5424(t *testing.T) {
+	received := date("2025-06-15T12:00:00Z")
+	// Примеры из RFC 5424, 6.5.
+	tests := []struct {
+		name string
+		line string
+		want Message
+	}{
+		{
+			name: "no structured data",
+			line: "<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - \ufeff'su root' failed for lonvick on /dev/pts/8",
+			want: Message{
+				Facility: 4, Severity: 2, Timestamp: date("2003-10-11T22:14:15.003Z"),
+				Hostname: "mymachine.example.com", AppName: "su", MsgID: "ID47",
+				Message: "'su root' failed for lonvick on /dev/pts/8",
+			},
+		},
+		{
+			name: "time offset and procid",
+			line: "<165>1 2003-08-24T05:14:15.000003-07:00 192.0.2.1 myproc 8710 - - %% It's time to make the do-nuts.",
+			want: Message{
+				Facility: 20, Severity: 5, Timestamp: date("2003-08-24T12:14:15.000003Z"),
+				Hostname: "192.0.2.1", AppName: "myproc", ProcID: "8710",
+				Message: "%% It's time to make the do-nuts.",
+			},
+		},
+		{
+			name: "structured data and message",
+			line: `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"] ` +
+				"\ufeffAn application event log entry...",
+			want: Message{
+				Facility: 20, Severity: 5, Timestamp: date("2003-10-11T22:14:15.003Z"),
+				Hostname: "mymachine.example.com", AppName: "evntslog", MsgID: "ID47",
+				StructuredData: map[string]map[string]string{
+					"exampleSDID@32473": {"iut": "3", "eventSource": "Application", "eventID": "1011"},
+				},
+				Message: "An application event log entry...",
+			},
+		},
+		{
+			name: "several elements without message",
+			line: `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high"]`,
+			want: Message{
+				Facility: 20, Severity: 5, Timestamp: date("2003-10-11T22:14:15.003Z"),
+				Hostname: "mymachine.example.com", AppName: "evntslog", MsgID: "ID47",
+				StructuredData: map[string]map[string]string{
+					"exampleSDID@32473":     {"iut": "3", "eventSource": "Application", "eventID": "1011"},
+					"examplePriority@32473": {"class": "high"},
+				},
+			},
+		},
+		{
+			name: "all fields nil",
+			line: "<13>1 - - - - -",
+			want: Message{Facility: 1, Severity: 5, Timestamp: received},
+		},
+		{
+			name: "trailing newline",
+			line: "<14>1 2025-06-15T10:00:00+03:00 host app 1 - - done\r\n",
+			want: Message{
+				Facility: 1, Severity: 6, Timestamp: date("2025-06-15T07:00:00Z"),
+				Hostname: "host", AppName: "app", ProcID: "1", Message: "done",
+			},
+		},
+		{
+			name: "invalid utf-8",
+			line: "<14>1 - host app - - - bad \xff byte",
+			want: Message{Facility: 1, Severity: 6, Timestamp: received, Hostname: "host", AppName: "app", Message: "bad \ufffd byte"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.line), received)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for name, line := range map[string]string{
+		"empty":             "\r\n",
+		"empty priority":    "<>1 - - - - - -",
+		"priority too long": "<1234>1 - - - - - -",
+		"priority too big":  "<192>1 - - - - - -",
+		"priority not int":  "<ab>1 - - - - - -",
+		"truncated header":  "<13>1 2003-10-11T22:14:15Z host",
+		"invalid timestamp": "<13>1 yesterday host app - - - msg",
+		"malformed sd":      `<13>1 - host app - - [id p=1] msg`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if m, err := Parse([]byte(line), time.Now()); !errors.Is(err, ErrInvalidMessage) {
+				t.Errorf("got %+v, %v; want ErrInvalidMessage", m, err)
+			}
+		})
+	}
+}
+
+func TestParseStructuredData(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		sd   map[string]map[string]string
+		msg  string
+		err  bool
+	}{
+		{name: "empty", in: ""},
+		{name: "nil", in: "-"},
+		{name: "nil with message", in: "- hello world", msg: "hello world"},
+		{name: "element without params", in: "[origin] msg", sd: map[string]map[string]string{"origin": {}}, msg: "msg"},
+		{
+			name: "escaped characters",
+			in:   `[id a="say \"hi\"" b="C:\\dir" c="[x\]" d="\n stays"] msg`,
+			sd:   map[string]map[string]string{"id": {"a": `say "hi"`, "b": `C:\dir`, "c": "[x]", "d": `\n stays`}},
+			msg:  "msg",
+		},
+		{name: "spaces and brackets in value", in: `[id a="x y ] z"]`, sd: map[string]map[string]string{"id": {"a": "x y ] z"}}},
+		{name: "empty value", in: `[id a=""]`, sd: map[string]map[string]string{"id": {"a": ""}}},
+		{name: "unquoted value", in: `[id a=1]`, err: true},
+		{name: "missing equals", in: `[id a]`, err: true},
+		{name: "unterminated value", in: `[id a="x]`, err: true},
+		{name: "unterminated element", in: `[id a="x"`, err: true},
+		{name: "escaped closing quote", in: `[id a="x\"]`, err: true},
+		{name: "no space before message", in: `[id a="x"]msg`, err: true},
+		{name: "not structured data", in: "-msg", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sd, msg, err := parseStructuredData(tt.in)
+			if tt.err {
+				if !errors.Is(err, ErrInvalidMessage) {
+					t.Errorf("got %v, %q, %v; want ErrInvalidMessage", sd, msg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseStructuredData: %v", err)
+			}
+			if !reflect.DeepEqual(sd, tt.sd) || msg != tt.msg {
+				t.Errorf("got %v, %q; want %v, %q", sd, msg, tt.sd, tt.msg)
+			}
+		})
+	}
+}
+
+func TestParse3164(t *testing.T) {
+	june := date("2025-06-15T12:00:00Z")
+	msk := time.FixedZone("MSK", 3*60*60)
+	tests := []struct {
+		name     string
+		line     string
+		received time.Time
+		want     Message
+	}{
+		{
+			// Пример из RFC 3164, 5.4; время из будущего относится к прошлому году.
+			name:     "rfc example",
+			line:     "<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8",
+			received: june,
+			want: Message{
+				Facility: 4, Severity: 2, Timestamp: date("2024-10-11T22:14:15Z"),
+				Hostname: "mymachine", AppName: "su", Message: "'su root' failed for lonvick on /dev/pts/8",
+			},
+		},
+		{
+			name:     "no tag",
+			line:     "<13>Feb  5 17:32:18 10.0.0.99 Use the BFG!",
+			received: june,
+			want:     Message{Facility: 1, Severity: 5, Timestamp: date("2025-02-05T17:32:18Z"), Hostname: "10.0.0.99", Message: "Use the BFG!"},
+		},
+		{
+			name:     "pid",
+			line:     "<38>Jun 15 11:59:00 bastion sshd[4242]: Accepted publickey for alice",
+			received: june,
+			want: Message{
+				Facility: 4, Severity: 6, Timestamp: date("2025-06-15T11:59:00Z"),
+				Hostname: "bastion", AppName: "sshd", ProcID: "4242", Message: "Accepted publickey for alice",
+			},
+		},
+		{
+			name:     "no hostname",
+			line:     "<38>Jun 15 11:59:00 sshd[4242]: Accepted publickey for alice",
+			received: june,
+			want: Message{
+				Facility: 4, Severity: 6, Timestamp: date("2025-06-15T11:59:00Z"),
+				AppName: "sshd", ProcID: "4242", Message: "Accepted publickey for alice",
+			},
+		},
+		{
+			// Без времени первое слово считается TAG или текстом, но не HOSTNAME.
+			name:     "no timestamp",
+			line:     "<13>host su: hello",
+			received: june,
+			want:     Message{Facility: 1, Severity: 5, Timestamp: june, Message: "host su: hello"},
+		},
+		{
+			name:     "no timestamp with tag",
+			line:     "<13>su: hello",
+			received: june,
+			want:     Message{Facility: 1, Severity: 5, Timestamp: june, AppName: "su", Message: "hello"},
+		},
+		{
+			name:     "no priority",
+			line:     "Jun 15 11:59:00 host app: msg",
+			received: june,
+			want:     Message{Facility: 1, Severity: 5, Timestamp: date("2025-06-15T11:59:00Z"), Hostname: "host", AppName: "app", Message: "msg"},
+		},
+		{
+			name:     "rfc 3339 timestamp",
+			line:     "<13>2025-06-15T11:00:00.5+02:00 host app: msg",
+			received: june,
+			want:     Message{Facility: 1, Severity: 5, Timestamp: date("2025-06-15T09:00:00.5Z"), Hostname: "host", AppName: "app", Message: "msg"},
+		},
+		{
+			name:     "local time of receiver",
+			line:     "<13>Jun 15 12:00:00 host app: msg",
+			received: june.In(msk),
+			want:     Message{Facility: 1, Severity: 5, Timestamp: date("2025-06-15T09:00:00Z"), Hostname: "host", AppName: "app", Message: "msg"},
+		},
+		{
+			// Часы отправителя спешат: время до суток вперед остается в году приема.
+			name:     "clock skew",
+			line:     "<13>Jun 15 20:00:00 host app: msg",
+			received: june,
+			want:     Message{Facility: 1, Severity: 5, Timestamp: date("2025-06-15T20:00:00Z"), Hostname: "host", AppName: "app", Message: "msg"},
+		},
+		{
+			name:     "year rollover",
+			line:     "<13>Dec 31 23:59:50 host app: msg",
+			received: date("2025-01-01T00:00:30Z"),
+			want:     Message{Facility: 1, Severity: 5, Timestamp: date("2024-12-31T23:59:50Z"), Hostname: "host", AppName: "app", Message: "msg"},
+		},
+		{
+			name:     "feb 29 in leap year",
+			line:     "<13>Feb 29 10:00:00 host app: msg",
+			received: date("2024-02-29T10:00:05Z"),
+			want:     Message{Facility: 1, Severity: 5, Timestamp: date("2024-02-29T10:00:00Z"), Hostname: "host", AppName: "app", Message: "msg"},
+		},
+		{
+			name:     "feb 29 received in next year",
+			line:     "<13>Feb 29 10:00:00 host app: msg",
+			received: date("2025-01-10T00:00:00Z"),
+			want:     Message{Facility: 1, Severity: 5, Timestamp: date("2024-02-29T10:00:00Z"), Hostname: "host", AppName: "app", Message: "msg"},
+		},
+		{
+			name:     "feb 29 received after march 1",
+			line:     "<13>Feb 29 23:59:59 host app: msg",
+			received: date("2025-03-01T00:00:01Z"),
+			want:     Message{Facility: 1, Severity: 5, Timestamp: date("2024-02-29T23:59:59Z"), Hostname: "host", AppName: "app", Message: "msg"},
+		},
+		{
+			name:     "malformed timestamp",
+			line:     "<13>Jun 45 11:59:00 host app: msg",
+			received: june,
+			want:     Message{Facility: 1, Severity: 5, Timestamp: june, Message: "Jun 45 11:59:00 host app: msg"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.line), tt.received)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTag(t *testing.T) {
+	for field, want := range map[string]bool{
+		"su:":          true,
+		"sshd[4242]:":  true,
+		"CRON[1]:":     true,
+		"app[]:":       true,
+		"host":         false,
+		":":            false,
+		"[4242]:":      false,
+		"sshd[4242]":   false,
+		"a:b:":         false,
+		"sshd[42:":     false,
+		"10.0.0.99":    false,
+		"sshd]4242[:":  false,
+		"su:extra:":    false,
+		"mymachine.su": false,
+	} {
+		if got := isTag(field); got != want {
+			t.Errorf("isTag(%q) = %v, want %v", field, got, want)
+		}
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	long := strings.Repeat("x", 5000)
+	tests := []struct {
+		name   string
+		in     string
+		frames []string
+		err    bool
+	}{
+		{name: "octet counting", in: "5 hello11 hello world", frames: []string{"hello", "hello world"}},
+		{name: "octet counting keeps newlines", in: "6 a\nb\nc\n3 end", frames: []string{"a\nb\nc\n", "end"}},
+		{name: "newline delimited", in: "<13>first\n<13>second\n", frames: []string{"<13>first\n", "<13>second\n"}},
+		{name: "last message without newline", in: "<13>first\n<13>last", frames: []string{"<13>first\n", "<13>last"}},
+		{name: "mixed framing", in: "3 abc<13>x\n", frames: []string{"abc", "<13>x\n"}},
+		{name: "line longer than reader buffer", in: long + "\n", frames: []string{long + "\n"}},
+		{name: "truncated octet frame", in: "10 short", err: true},
+		{name: "octet count overflow", in: "99999999999999999999 x", err: true},
+		{name: "octet count too big", in: "99999999 x", err: true},
+		{name: "octet count not a number", in: "1x2 abc", err: true},
+		{name: "line too long", in: strings.Repeat("x", maxMessageSize+1) + "\n", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReaderSize(strings.NewReader(tt.in), 4096)
+			var frames []string
+			for {
+				frame, err := readFrame(r)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					if !tt.err {
+						t.Fatalf("readFrame after %q: %v", frames, err)
+					}
+					return
+				}
+				frames = append(frames, string(frame))
+			}
+			if tt.err {
+				t.Fatalf("readFrame must fail, got frames %q", frames)
+			}
+			if !reflect.DeepEqual(frames, tt.frames) {
+				t.Errorf("got %q, want %q", frames, tt.frames)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+// Package syslog принимает сообщения syslog (RFC 5424 и RFC 3164) по UDP, TCP и TLS
+// и по правилам сопоставления превращает их в события, которые проходят обычный конвейер приема.
+//
+// Правила задаются JSON-файлом:
+//
+//	{"rules": [
+//	  {"name": "ssh-login", "app_name": "sshd",
+//	   "pattern": "^Accepted (?P<method>\\S+) for (?P<user>\\S+) from (?P<ip>\\S+)",
+//	   "event_type": "SSH_LOGIN",
+//	   "actor": {"id": "${user}", "type": "USER", "ip_address": "${ip}"},
+//	   "entity": {"id": "${hostname}", "type": "HOST"},
+//	   "details": {"method": "${method}"}}
+//	]}
+//
+// Сообщение сопоставляется с правилами по порядку, применяется первое подошедшее;
+// сообщения, не подошедшие ни одному правилу, пропускаются. app_name (точное совпадение)
+// и hostname (регулярное выражение) ограничивают правило источником, pattern - регулярное
+// выражение для текста сообщения. В строковых полях события подставляются ${name}:
+// именованные группы pattern, поля сообщения (hostname, app_name, proc_id, msg_id,
+// facility, severity, message) и параметры структурированных данных ${sd.<SD-ID>.<имя>}.
+// Необязательные status (по умолчанию SUCCESS), source_service (по умолчанию ${app_name})
+// и access_level тоже поддерживают подстановки.
+package syslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"witness/models"
+
+	"github.com/google/uuid"
+)
+
+// Имена severity по RFC 5424, подставляемые в ${severity}.
+var severities = []string{"emergency", "alert", "critical", "error", "warning", "notice", "info", "debug"}
+
+// Rule - правило в файле конфигурации.
+type Rule struct {
+	Name     string `json:"name"`
+	AppName  string `json:"app_name"`
+	Hostname string `json:"hostname"`
+	Pattern  string `json:"pattern"`
+
+	EventType     string            `json:"event_type"`
+	Status        string            `json:"status"`
+	Actor         models.Actor      `json:"actor"`
+	Entity        models.Entity     `json:"entity"`
+	SourceService string            `json:"source_service"`
+	AccessLevel   string            `json:"access_level"`
+	Details       map[string]string `json:"details"`
+}
+
+type rule struct {
+	Rule
+	hostname *regexp.Regexp
+	pattern  *regexp.Regexp
+}
+
+// Rules сопоставляет сообщения с правилами. Без правил ни одно сообщение не становится событием.
+type Rules struct {
+	rules []*rule
+}
+
+// LoadRules читает правила из файла. Пустой путь дает Rules без правил.
+func LoadRules(path string) (*Rules, error) {
+	r := &Rules{}
+	if path == "" {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read syslog rules: %w", err)
+	}
+	var cfg struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse syslog rules: %w", err)
+	}
+	for i, rc := range cfg.Rules {
+		compiled, err := compile(rc)
+		if err != nil {
+			return nil, fmt.Errorf("syslog rule %d (%s): %w", i, rc.Name, err)
+		}
+		r.rules = append(r.rules, compiled)
+	}
+	return r, nil
+}
+
+// Len возвращает число правил.
+func (r *Rules) Len() int {
+	return len(r.rules)
+}
+
+func compile(rc Rule) (*rule, error) {
+	if rc.Pattern == "" {
+		return nil, fmt.Errorf("pattern is required")
+	}
+	if rc.EventType == "" {
+		return nil, fmt.Errorf("event_type is required")
+	}
+	c := &rule{Rule: rc}
+	var err error
+	if c.pattern, err = regexp.Compile(rc.Pattern); err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	if rc.Hostname != "" {
+		if c.hostname, err = regexp.Compile(rc.Hostname); err != nil {
+			return nil, fmt.Errorf("invalid hostname pattern: %w", err)
+		}
+	}
+	if c.Status == "" {
+		c.Status = "SUCCESS"
+	}
+	if c.SourceService == "" {
+		c.SourceService = "${app_name}"
+	}
+	return c, nil
+}
+
+// Map переводит сообщение в событие по первому подошедшему правилу и возвращает его имя.
+// ok = false, если ни одно правило не подошло. Проверку обязательных полей выполняет конвейер приема.
+func (r *Rules) Map(msg *Message) (event *models.AuditEvent, name string, ok bool) {
+	for _, c := range r.rules {
+		if c.AppName != "" && c.AppName != msg.AppName {
+			continue
+		}
+		if c.hostname != nil && !c.hostname.MatchString(msg.Hostname) {
+			continue
+		}
+		match := c.pattern.FindStringSubmatch(msg.Message)
+		if match == nil {
+			continue
+		}
+		return c.event(msg, variables(msg, c.pattern, match)), c.Name, true
+	}
+	return nil, "", false
+}
+
+// event строит событие по правилу. Исходное сообщение и узел всегда попадают в details.
+func (c *rule) event(msg *Message, vars map[string]string) *models.AuditEvent {
+	expand := func(s string) string {
+		return os.Expand(s, func(name string) string { return vars[name] })
+	}
+	event := &models.AuditEvent{
+		EventID:   uuid.NewString(),
+		EventType: expand(c.EventType),
+		Timestamp: msg.Timestamp,
+		Status:    expand(c.Status),
+		Actor: models.Actor{
+			ID:        expand(c.Actor.ID),
+			Type:      expand(c.Actor.Type),
+			Name:      expand(c.Actor.Name),
+			IPAddress: expand(c.Actor.IPAddress),
+		},
+		Entity: models.Entity{
+			ID:   expand(c.Entity.ID),
+			Type: expand(c.Entity.Type),
+			Name: expand(c.Entity.Name),
+		},
+		Context: models.Context{SourceService: expand(c.SourceService)},
+	}
+	if event.Actor.Type == "" && event.Actor.ID != "" {
+		event.Actor.Type = "USER"
+	}
+	if c.AccessLevel != "" {
+		event.Security = &models.Security{AccessLevel: expand(c.AccessLevel)}
+	}
+
+	details := map[string]any{"message": msg.Message}
+	if msg.Hostname != "" {
+		details["hostname"] = msg.Hostname
+	}
+	for key, value := range c.Details {
+		details[key] = expand(value)
+	}
+	event.Details = details
+	return event
+}
+
+// variables собирает значения для подстановки: поля сообщения, структурированные данные
+// и именованные группы pattern (они главнее полей сообщения с тем же именем).
+func variables(msg *Message, pattern *regexp.Regexp, match []string) map[string]string {
+	vars := map[string]string{
+		"hostname": msg.Hostname,
+		"app_name": msg.AppName,
+		"proc_id":  msg.ProcID,
+		"msg_id":   msg.MsgID,
+		"facility": strconv.Itoa(msg.Facility),
+		"severity": severities[msg.Severity],
+		"message":  msg.Message,
+	}
+	for id, params := range msg.StructuredData {
+		for name, value := range params {
+			vars["sd."+id+"."+name] = value
+		}
+	}
+	for i, name := range pattern.SubexpNames() {
+		if name != "" {
+			vars[name] = match[i]
+		}
+	}
+	return vars
+}
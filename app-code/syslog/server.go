@@ -0,0 +1,249 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+	"witness/ingest"
+)
+
+// maxMessageSize - наибольший размер сообщения: больше не помещается в датаграмму UDP.
+const maxMessageSize = 64 << 10
+
+// Server принимает сообщения syslog и проводит события, полученные по правилам,
+// через конвейер приема. У syslog нет ответа отправителю, поэтому отклоненные
+// и пропущенные сообщения только записываются в лог.
+type Server struct {
+	rules    *Rules
+	pipeline *ingest.Pipeline
+	buffer   *ingest.Buffer
+	// stream - поток цепочки хешей для событий, принятых этим экземпляром по syslog.
+	stream string
+	tenant string
+
+	mu        sync.Mutex
+	closed    bool
+	listeners map[io.Closer]struct{}
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+}
+
+// NewServer создает сервер. События сохраняются через buffer в цепочке потока stream
+// (своего у каждого экземпляра Witness) с арендатором tenant.
+func NewServer(rules *Rules, pipeline *ingest.Pipeline, buffer *ingest.Buffer, stream, tenant string) *Server {
+	return &Server{
+		rules:     rules,
+		pipeline:  pipeline,
+		buffer:    buffer,
+		stream:    stream,
+		tenant:    tenant,
+		listeners: make(map[io.Closer]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// ServePacket принимает по сообщению в датаграмме (UDP, RFC 5426), пока conn не закрыт Close.
+func (s *Server) ServePacket(conn net.PacketConn) error {
+	if !s.track(conn) {
+		return net.ErrClosed
+	}
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			return err
+		}
+		if !s.begin() {
+			return nil
+		}
+		s.handle(buf[:n], addr)
+		s.wg.Done()
+	}
+}
+
+// Serve принимает соединения TCP или TLS (RFC 6587, RFC 5425), пока l не закрыт Close.
+func (s *Server) Serve(l net.Listener) error {
+	if !s.track(l) {
+		return net.ErrClosed
+	}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// Close закрывает слушателей и соединения и ждет, пока принятые сообщения пройдут конвейер.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) track(l io.Closer) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.listeners[l] = struct{}{}
+	return true
+}
+
+// begin учитывает начатую обработку датаграммы, которую дождется Close; после Close возвращает false.
+func (s *Server) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+		s.wg.Done()
+	}()
+	r := bufio.NewReaderSize(conn, 4096)
+	for {
+		frame, err := readFrame(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !s.isClosed() {
+				slog.Warn("closing syslog connection", "remote", conn.RemoteAddr().String(), "error", err)
+			}
+			return
+		}
+		if len(frame) > 0 {
+			s.handle(frame, conn.RemoteAddr())
+		}
+	}
+}
+
+// readFrame читает одно сообщение потока. Отправитель выбирает разбиение сам (RFC 6587):
+// octet counting ("<длина> <сообщение>") или non-transparent framing (сообщения через LF).
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] >= '1' && first[0] <= '9' {
+		prefix, err := r.ReadSlice(' ')
+		if err != nil {
+			return nil, fmt.Errorf("malformed octet count: %w", err)
+		}
+		size, err := strconv.Atoi(string(prefix[:len(prefix)-1]))
+		if err != nil || size > maxMessageSize {
+			return nil, fmt.Errorf("invalid octet count %q", prefix[:len(prefix)-1])
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return nil, err
+		}
+		return frame, nil
+	}
+
+	var frame []byte
+	for {
+		line, err := r.ReadSlice('\n')
+		frame = append(frame, line...)
+		if len(frame) > maxMessageSize {
+			return nil, fmt.Errorf("message exceeds %d bytes", maxMessageSize)
+		}
+		switch {
+		case err == nil:
+			return frame, nil
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case errors.Is(err, io.EOF) && len(frame) > 0:
+			// Последнее сообщение перед закрытием соединения может быть без LF.
+			return frame, nil
+		default:
+			return nil, err
+		}
+	}
+}
+
+// handle разбирает сообщение, сопоставляет его с правилами и проводит событие через конвейер.
+func (s *Server) handle(data []byte, from net.Addr) {
+	log := slog.With("remote", from.String(), "stream", s.stream)
+	msg, err := Parse(data, time.Now())
+	if err != nil {
+		log.Warn("skipping malformed syslog message", "error", err)
+		return
+	}
+	event, rule, ok := s.rules.Map(msg)
+	if !ok {
+		log.Debug("syslog message matches no rule", "app_name", msg.AppName, "hostname", msg.Hostname)
+		return
+	}
+	value, err := json.Marshal(event)
+	if err != nil {
+		log.Error("failed to encode syslog event", "rule", rule, "error", err)
+		return
+	}
+
+	// Отправителю syslog нечего ответить, а ожидание задержало бы Close: при переполненном
+	// буфере сообщение отбрасывается.
+	if err := s.buffer.Admit(); err != nil {
+		log.Error("dropping syslog event", "rule", rule, "error", err)
+		return
+	}
+	ctx := context.Background()
+	event, err = s.pipeline.Process(ctx, s.stream, value, ingest.Meta{Tenant: s.tenant})
+	switch {
+	case err == nil:
+		s.buffer.Add(ctx, event)
+	case errors.Is(err, ingest.ErrMalformed):
+		log.Warn("syslog rule produced an invalid event", "rule", rule, "error", err)
+	default:
+		log.Error("failed to process syslog event", "rule", rule, "error", err)
+	}
+}
@@ -0,0 +1,141 @@
+// Package tenant разделяет события между арендаторами (бизнес-подразделениями)
+// одного экземпляра Witness: при приеме определяет арендатора события,
+// а при чтении ограничивает запросы арендатором субъекта.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"witness/auth"
+	"witness/models"
+	"witness/storage"
+)
+
+// Header - заголовок сообщения Kafka с арендатором события.
+const Header = "witness-tenant"
+
+// ErrNoTenant - у субъекта запроса нет арендатора, а Witness работает в режиме нескольких арендаторов.
+var ErrNoTenant = errors.New("forbidden: caller has no tenant")
+
+// Resolver определяет арендатора принятого сообщения.
+type Resolver struct {
+	topics   map[string]string
+	fallback string
+}
+
+// NewResolver создает определитель арендатора. topics сопоставляет топики
+// арендаторам; fallback назначается, если арендатора не удалось определить.
+func NewResolver(topics map[string]string, fallback string) *Resolver {
+	return &Resolver{topics: topics, fallback: fallback}
+}
+
+// Resolve возвращает арендатора сообщения из топика topic с заголовком header.
+// Сопоставление топика главнее заголовка: топик задает администратор Witness,
+// а заголовок - продюсер, которому разрешена запись в топик.
+func (r *Resolver) Resolve(topic, header string) string {
+	if t, ok := r.topics[topic]; ok {
+		return t
+	}
+	if header != "" {
+		return header
+	}
+	return r.fallback
+}
+
+// ResolveCaller возвращает арендатора событий, которые субъект запроса из ctx отправил
+// напрямую (HTTP, gRPC, OTLP): арендатор субъекта, иначе заголовок header, иначе
+// арендатор по умолчанию. Арендатор субъекта главнее заголовка: заголовок задает
+// продюсер, а ключ API выдает администратор Witness.
+func (r *Resolver) ResolveCaller(ctx context.Context, header string) string {
+	if p := auth.FromContext(ctx); p != nil && p.Tenant != "" {
+		return p.Tenant
+	}
+	if header != "" {
+		return header
+	}
+	return r.fallback
+}
+
+// Store - хранилище событий для API, принудительно ограниченное арендатором
+// субъекта запроса. Без режима нескольких арендаторов запросы проходят без изменений.
+type Store struct {
+	storage.EventStore
+	enabled bool
+}
+
+// NewStore оборачивает хранилище событий. enabled включает режим нескольких арендаторов.
+func NewStore(store storage.EventStore, enabled bool) *Store {
+	return &Store{EventStore: store, enabled: enabled}
+}
+
+// Scope возвращает фильтр, ограниченный арендатором субъекта из ctx.
+// Исходный фильтр не меняется; арендатор из фильтра вызывающего игнорируется.
+func (s *Store) Scope(ctx context.Context, filter *models.AuditEventFilter) (*models.AuditEventFilter, error) {
+	if !s.enabled {
+		return filter, nil
+	}
+	t, err := FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scoped := models.AuditEventFilter{}
+	if filter != nil {
+		scoped = *filter
+	}
+	scoped.Tenant = &t
+	return &scoped, nil
+}
+
+func (s *Store) Search(ctx context.Context, q storage.Query) ([]*models.AuditEvent, int64, error) {
+	filter, err := s.Scope(ctx, q.Filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	q.Filter = filter
+	return s.EventStore.Search(ctx, q)
+}
+
+func (s *Store) Aggregate(ctx context.Context, filter *models.AuditEventFilter, field string, size int) ([]*models.FacetBucket, error) {
+	filter, err := s.Scope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.EventStore.Aggregate(ctx, filter, field, size)
+}
+
+func (s *Store) Scan(ctx context.Context, filter *models.AuditEventFilter, fn func(*models.AuditEvent) error) error {
+	filter, err := s.Scope(ctx, filter)
+	if err != nil {
+		return err
+	}
+	return s.EventStore.Scan(ctx, filter, fn)
+}
+
+// Get возвращает событие, только если оно принадлежит арендатору субъекта;
+// чужое событие неотличимо от отсутствующего.
+func (s *Store) Get(ctx context.Context, eventID string) (*models.AuditEvent, error) {
+	if !s.enabled {
+		return s.EventStore.Get(ctx, eventID)
+	}
+	t, err := FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	event, err := s.EventStore.Get(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Tenant != t {
+		return nil, storage.ErrNotFound
+	}
+	return event, nil
+}
+
+// FromContext возвращает арендатора субъекта запроса или ErrNoTenant.
+func FromContext(ctx context.Context) (string, error) {
+	p := auth.FromContext(ctx)
+	if p == nil || p.Tenant == "" {
+		return "", ErrNoTenant
+	}
+	return p.Tenant, nil
+}